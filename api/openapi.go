@@ -0,0 +1,377 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version REST API 的版本号，写入 OpenAPI 文档
+const Version = "1.0.0"
+
+// Parameter 查询参数
+type Parameter struct {
+	Name        string
+	Description string
+	Type        string // string、integer、boolean
+	Array       bool   // 可重复
+}
+
+// Operation 一个 REST 接口，Path 使用 OpenAPI 格式（如 /projects/{id}），相对于 BasePath
+type Operation struct {
+	ID          string
+	Method      string
+	Path        string
+	Tag         string
+	Summary     string
+	Role        string      // 启用认证时所需的最低角色，为空表示无需认证
+	Query       []Parameter // 查询参数
+	Request     interface{} // 请求体类型的零值，nil 表示没有 JSON 请求体
+	Multipart   []Parameter // multipart/form-data 请求体字段（上传文件的字段类型为 file）
+	Status      int         // 成功时的状态码
+	Response    interface{} // 成功响应类型的零值，nil 表示没有 JSON 响应体
+	ContentType string      // 非 JSON 响应的内容类型
+	Confirm     bool        // 受保护环境需要确认（可能返回 428）
+}
+
+// Operations 全部 REST 接口
+var Operations = []Operation{
+	{ID: "listProjects", Method: http.MethodGet, Path: "/projects", Tag: "projects", Summary: "获取所有项目", Role: "viewer",
+		Status: http.StatusOK, Response: ProjectList{}},
+	{ID: "createProject", Method: http.MethodPost, Path: "/projects", Tag: "projects", Summary: "创建项目", Role: "admin",
+		Request: CreateProjectRequest{}, Status: http.StatusCreated, Response: Project{}},
+	{ID: "importProject", Method: http.MethodPost, Path: "/projects/import", Tag: "projects", Summary: "导入项目打包文件", Role: "admin",
+		Multipart: []Parameter{
+			{Name: "bundle", Description: "项目打包文件（tar.gz）", Type: "file"},
+			{Name: "name", Description: "导入后的项目名称", Type: "string"},
+			{Name: "remap_target", Description: "目标路径映射 old=new", Type: "string", Array: true},
+		},
+		Status: http.StatusCreated, Response: Project{}},
+	{ID: "getProject", Method: http.MethodGet, Path: "/projects/{id}", Tag: "projects", Summary: "获取项目详情", Role: "viewer",
+		Status: http.StatusOK, Response: Project{}},
+	{ID: "updateProject", Method: http.MethodPut, Path: "/projects/{id}", Tag: "projects", Summary: "更新项目", Role: "admin",
+		Request: UpdateProjectRequest{}, Status: http.StatusOK, Response: Project{}},
+	{ID: "deleteProject", Method: http.MethodDelete, Path: "/projects/{id}", Tag: "projects", Summary: "删除项目，项目中的每个受保护环境都需要确认", Role: "admin",
+		Status: http.StatusOK, Response: MessageResponse{}, Confirm: true},
+	{ID: "exportProject", Method: http.MethodGet, Path: "/projects/{id}/export", Tag: "projects", Summary: "导出项目打包文件", Role: "viewer",
+		Status: http.StatusOK, ContentType: "application/gzip"},
+	{ID: "listEnvironments", Method: http.MethodGet, Path: "/projects/{id}/environments", Tag: "environments", Summary: "获取项目下的所有环境", Role: "viewer",
+		Status: http.StatusOK, Response: EnvironmentList{}},
+	{ID: "createEnvironment", Method: http.MethodPost, Path: "/projects/{id}/environments", Tag: "environments", Summary: "创建环境", Role: "admin",
+		Request: CreateEnvironmentRequest{}, Status: http.StatusCreated, Response: Environment{}},
+	{ID: "getEnvironment", Method: http.MethodGet, Path: "/environments/{id}", Tag: "environments", Summary: "获取环境详情", Role: "viewer",
+		Status: http.StatusOK, Response: Environment{}},
+	{ID: "updateEnvironment", Method: http.MethodPut, Path: "/environments/{id}", Tag: "environments", Summary: "更新环境，取消受保护环境的保护需要确认", Role: "admin",
+		Request: UpdateEnvironmentRequest{}, Status: http.StatusOK, Response: Environment{}, Confirm: true},
+	{ID: "deleteEnvironment", Method: http.MethodDelete, Path: "/environments/{id}", Tag: "environments", Summary: "删除环境", Role: "admin",
+		Status: http.StatusOK, Response: MessageResponse{}, Confirm: true},
+	{ID: "getEnvironmentPlan", Method: http.MethodGet, Path: "/environments/{id}/plan", Tag: "environments", Summary: "获取环境文件在服务端解析后的路径，preview=true 时包含切换预览", Role: "viewer",
+		Query: []Parameter{
+			{Name: "preview", Description: "是否计算各目标文件的变更预览", Type: "boolean"},
+		},
+		Status: http.StatusOK, Response: EnvironmentPlan{}},
+	{ID: "cloneEnvironment", Method: http.MethodPost, Path: "/environments/{id}/clone", Tag: "environments", Summary: "克隆环境", Role: "admin",
+		Request: CloneEnvironmentRequest{}, Status: http.StatusCreated, Response: Environment{}},
+	{ID: "addFile", Method: http.MethodPost, Path: "/environments/{id}/files", Tag: "files", Summary: "添加文件配置", Role: "admin",
+		Request: AddFileRequest{}, Status: http.StatusCreated, Response: FileResponse{}},
+	{ID: "updateFile", Method: http.MethodPut, Path: "/files/{id}", Tag: "files", Summary: "更新文件配置，文件配置 ID 保持不变", Role: "admin",
+		Request: UpdateFileRequest{}, Status: http.StatusOK, Response: FileResponse{}, Confirm: true},
+	{ID: "getFileContent", Method: http.MethodGet, Path: "/files/{id}/content", Tag: "files", Summary: "获取源文件内容，二进制文件不返回内容", Role: "viewer",
+		Status: http.StatusOK, Response: FileContent{}},
+	{ID: "updateFileContent", Method: http.MethodPut, Path: "/files/{id}/content", Tag: "files", Summary: "保存源文件内容，外部源文件保存到托管目录", Role: "admin",
+		Request: UpdateFileContentRequest{}, Status: http.StatusOK, Response: FileResponse{}, Confirm: true},
+	{ID: "getFileTarget", Method: http.MethodGet, Path: "/files/{id}/target", Tag: "files", Summary: "获取目标文件当前的内容", Role: "viewer",
+		Status: http.StatusOK, Response: FileContent{}},
+	{ID: "deleteFile", Method: http.MethodDelete, Path: "/files/{id}", Tag: "files", Summary: "移除文件配置", Role: "admin",
+		Status: http.StatusOK, Response: MessageResponse{}, Confirm: true},
+	{ID: "switchEnvironment", Method: http.MethodPost, Path: "/switch", Tag: "switch", Summary: "切换环境", Role: "operator",
+		Request: SwitchRequest{}, Status: http.StatusOK, Response: SwitchResponse{}, Confirm: true},
+	{ID: "rollback", Method: http.MethodPost, Path: "/rollback", Tag: "switch", Summary: "从备份回滚", Role: "operator",
+		Request: RollbackRequest{}, Status: http.StatusOK, Response: RollbackResponse{}},
+	{ID: "getStatus", Method: http.MethodGet, Path: "/status", Tag: "switch", Summary: "获取当前状态，wait 参数用于长轮询", Role: "viewer",
+		Query: []Parameter{
+			{Name: "wait", Description: "状态版本号，版本号未变化时等待状态变化", Type: "integer"},
+			{Name: "timeout", Description: "长轮询的最长等待秒数（默认 30，最长 120）", Type: "integer"},
+		},
+		Status: http.StatusOK, Response: Status{}},
+	{ID: "streamEvents", Method: http.MethodGet, Path: "/events", Tag: "events", Summary: "Server-Sent Events 实时事件流，data 为 Event JSON", Role: "viewer",
+		Query: []Parameter{
+			{Name: "project", Description: "只接收指定项目（ID 或名称）的事件", Type: "string", Array: true},
+			{Name: "last_event_id", Description: "断线续传的最后事件序号，也可使用 Last-Event-ID 请求头", Type: "integer"},
+		},
+		Status: http.StatusOK, Response: Event{}, ContentType: "text/event-stream"},
+	{ID: "getOpenAPI", Method: http.MethodGet, Path: "/openapi.json", Tag: "meta", Summary: "获取 OpenAPI 文档",
+		Status: http.StatusOK, Response: map[string]interface{}{}},
+}
+
+// Spec 生成 OpenAPI 3 文档
+func Spec() map[string]interface{} {
+	gen := &schemaGenerator{schemas: make(map[string]interface{})}
+	paths := make(map[string]map[string]interface{})
+
+	for _, op := range Operations {
+		item, ok := paths[BasePath+op.Path]
+		if !ok {
+			item = make(map[string]interface{})
+			paths[BasePath+op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = gen.operation(op)
+	}
+
+	gen.schemas["ErrorResponse"] = gen.schema(reflect.TypeOf(ErrorResponse{}))
+	gen.schemas["ConfirmationRequired"] = gen.schema(reflect.TypeOf(ConfirmationRequired{}))
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":       "envswitch API",
+			"version":     Version,
+			"description": "envswitch Web 服务的 REST API。配置了 API 令牌时需要 Authorization: Bearer <token> 请求头。",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": gen.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+	}
+}
+
+// schemaGenerator 通过反射从 Go 类型生成 JSON Schema，结构体放入 components/schemas
+type schemaGenerator struct {
+	schemas map[string]interface{}
+}
+
+// operation 生成一个接口的 Operation 对象
+func (g *schemaGenerator) operation(op Operation) map[string]interface{} {
+	result := map[string]interface{}{
+		"operationId": op.ID,
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+	}
+
+	var parameters []interface{}
+	if strings.Contains(op.Path, "{id}") {
+		parameters = append(parameters, map[string]interface{}{
+			"name": "id", "in": "path", "required": true,
+			"description": "ID（项目也可使用名称）",
+			"schema":      map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, param := range op.Query {
+		parameters = append(parameters, map[string]interface{}{
+			"name": param.Name, "in": "query", "description": param.Description,
+			"schema": paramSchema(param),
+		})
+	}
+	if op.Confirm {
+		parameters = append(parameters, map[string]interface{}{
+			"name": ConfirmTokenHeader, "in": "header",
+			"description": "受保护环境的确认令牌（来自 428 响应），涉及多个受保护环境时以逗号分隔",
+			"schema":      map[string]interface{}{"type": "string"},
+		})
+	}
+	if len(parameters) > 0 {
+		result["parameters"] = parameters
+	}
+
+	if op.Request != nil {
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.Request))},
+			},
+		}
+	} else if len(op.Multipart) > 0 {
+		properties := make(map[string]interface{})
+		var required []string
+		for _, field := range op.Multipart {
+			properties[field.Name] = paramSchema(field)
+			if field.Type == "file" {
+				required = append(required, field.Name)
+			}
+		}
+		result["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"multipart/form-data": map[string]interface{}{
+					"schema": map[string]interface{}{"type": "object", "properties": properties, "required": required},
+				},
+			},
+		}
+	}
+
+	success := map[string]interface{}{"description": http.StatusText(op.Status)}
+	switch {
+	case op.ContentType == "text/event-stream":
+		success["content"] = map[string]interface{}{
+			op.ContentType: map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.Response))},
+		}
+	case op.ContentType != "":
+		success["content"] = map[string]interface{}{
+			op.ContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}},
+		}
+	case op.Response != nil:
+		success["content"] = jsonContent(g.schema(reflect.TypeOf(op.Response)))
+	}
+
+	responses := map[string]interface{}{strconv.Itoa(op.Status): success}
+	errorResponse := func(status int) {
+		if _, ok := responses[strconv.Itoa(status)]; !ok {
+			responses[strconv.Itoa(status)] = map[string]interface{}{
+				"description": http.StatusText(status),
+				"content":     jsonContent(ref("ErrorResponse")),
+			}
+		}
+	}
+	if op.Request != nil || len(op.Multipart) > 0 || len(op.Query) > 0 {
+		errorResponse(http.StatusBadRequest)
+	}
+	if strings.Contains(op.Path, "{id}") {
+		errorResponse(http.StatusNotFound)
+	}
+	if op.Method != http.MethodGet && (op.Request != nil || len(op.Multipart) > 0) {
+		errorResponse(http.StatusConflict)
+	}
+	if op.Role != "" {
+		errorResponse(http.StatusUnauthorized)
+		errorResponse(http.StatusForbidden)
+		result["security"] = []interface{}{map[string]interface{}{"bearerAuth": []string{}}}
+		result["x-required-role"] = op.Role
+	}
+	if op.Confirm {
+		responses[strconv.Itoa(http.StatusPreconditionRequired)] = map[string]interface{}{
+			"description": "受保护环境需要确认",
+			"content":     jsonContent(ref("ConfirmationRequired")),
+		}
+	}
+	result["responses"] = responses
+
+	return result
+}
+
+// schema 生成类型的 Schema，命名结构体返回引用
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		return g.schema(t.Elem())
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			// 先占位，避免递归类型无限展开
+			g.schemas[name] = nil
+			g.schemas[name] = g.object(t)
+		}
+		return ref(name)
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		if t.Elem().Kind() == reflect.Interface {
+			return map[string]interface{}{"type": "object"}
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+// object 生成结构体的 object Schema，匿名嵌入字段的属性提升到外层
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, options, _ := strings.Cut(tag, ",")
+
+			if field.Anonymous && name == "" {
+				embedded := field.Type
+				if embedded.Kind() == reflect.Ptr {
+					embedded = embedded.Elem()
+				}
+				if embedded.Kind() == reflect.Struct {
+					collect(embedded)
+					continue
+				}
+			}
+
+			if name == "" {
+				name = field.Name
+			}
+			schema := g.schema(field.Type)
+			if field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() != reflect.Struct {
+				schema = withNullable(schema)
+			}
+			properties[name] = schema
+
+			if strings.Contains(field.Tag.Get("binding"), "required") || (!strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr && field.Type.Kind() != reflect.Map && field.Type.Kind() != reflect.Slice) {
+				required = append(required, name)
+			}
+		}
+	}
+	collect(t)
+
+	sort.Strings(required)
+	result := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		result["required"] = required
+	}
+	return result
+}
+
+// paramSchema 查询参数或表单字段的 Schema
+func paramSchema(param Parameter) map[string]interface{} {
+	schema := map[string]interface{}{"type": param.Type}
+	if param.Type == "file" {
+		schema = map[string]interface{}{"type": "string", "format": "binary"}
+	}
+	if param.Array {
+		return map[string]interface{}{"type": "array", "items": schema}
+	}
+	return schema
+}
+
+// withNullable 复制 Schema 并标记为可为 null
+func withNullable(schema map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(schema)+1)
+	for key, value := range schema {
+		result[key] = value
+	}
+	result["nullable"] = true
+	return result
+}
+
+// ref 引用 components/schemas 中的 Schema
+func ref(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// jsonContent JSON 响应内容
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
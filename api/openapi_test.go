@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestOperationsAreUnique(t *testing.T) {
+	ids := make(map[string]bool)
+	routes := make(map[string]bool)
+	for _, op := range Operations {
+		if ids[op.ID] {
+			t.Errorf("Duplicate operation ID %s", op.ID)
+		}
+		ids[op.ID] = true
+
+		key := op.Method + " " + op.Path
+		if routes[key] {
+			t.Errorf("Duplicate operation %s", key)
+		}
+		routes[key] = true
+
+		if op.Status == 0 {
+			t.Errorf("Operation %s has no success status", op.ID)
+		}
+	}
+}
+
+func TestSpecReferencesResolve(t *testing.T) {
+	data, err := json.Marshal(Spec())
+	if err != nil {
+		t.Fatalf("Failed to marshal spec: %v", err)
+	}
+
+	var spec struct {
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatalf("Failed to parse spec: %v", err)
+	}
+
+	refs := regexp.MustCompile(`"\$ref":"#/components/schemas/([^"]+)"`).FindAllStringSubmatch(string(data), -1)
+	if len(refs) == 0 {
+		t.Fatal("Expected schema references in spec")
+	}
+	for _, ref := range refs {
+		if _, ok := spec.Components.Schemas[ref[1]]; !ok {
+			t.Errorf("Unresolved schema reference %s", ref[1])
+		}
+	}
+
+	for _, op := range Operations {
+		item, ok := spec.Paths[BasePath+op.Path]
+		if !ok {
+			t.Errorf("Missing path %s", BasePath+op.Path)
+			continue
+		}
+		if _, ok := item[strings.ToLower(op.Method)]; !ok {
+			t.Errorf("Missing %s %s", op.Method, op.Path)
+		}
+	}
+}
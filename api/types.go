@@ -0,0 +1,189 @@
+package api
+
+import (
+	"time"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/events"
+	"github.com/zoyopei/envswitch/internal/file"
+)
+
+// BasePath REST API 的路径前缀
+const BasePath = "/api/v1"
+
+// ConfirmTokenHeader 携带受保护环境确认令牌的请求头
+const ConfirmTokenHeader = "X-Confirm-Token"
+
+// 数据模型，与命令行和数据文件使用相同的结构
+type (
+	Project        = internal.Project
+	Environment    = internal.Environment
+	FileConfig     = internal.FileConfig
+	FileMapping    = internal.FileMapping
+	AppState       = internal.AppState
+	SwitchResult   = internal.SwitchResult
+	RollbackResult = internal.RollbackResult
+	Event          = events.Event
+	FilePreview    = file.FilePreview
+	FileContent    = file.FileContent
+)
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// MessageResponse 操作成功的提示
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+// ConfirmationRequired 操作受保护环境时的 428 响应，
+// 携带 confirm_token 通过 X-Confirm-Token 请求头重新发送请求即可完成操作
+type ConfirmationRequired struct {
+	Error        string    `json:"error"`
+	Protected    bool      `json:"protected"`
+	Action       string    `json:"action"`
+	Environment  string    `json:"environment"`
+	Reason       string    `json:"reason"`
+	ConfirmToken string    `json:"confirm_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// ProjectList 项目列表
+type ProjectList struct {
+	Projects []Project `json:"projects"`
+}
+
+// CreateProjectRequest 创建项目
+type CreateProjectRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+}
+
+// UpdateProjectRequest 更新项目，未提供的字段保持不变
+// path_variables 按名称合并，值为空时删除该变量
+type UpdateProjectRequest struct {
+	Name          string            `json:"name"`
+	Description   *string           `json:"description"`
+	Root          *string           `json:"root"`
+	PathVariables map[string]string `json:"path_variables"`
+}
+
+// EnvironmentList 环境列表
+type EnvironmentList struct {
+	Environments []Environment `json:"environments"`
+}
+
+// CreateEnvironmentRequest 创建环境
+type CreateEnvironmentRequest struct {
+	Name        string            `json:"name" binding:"required"`
+	Description string            `json:"description"`
+	Tags        []string          `json:"tags"`
+	Variables   map[string]string `json:"variables"`
+	DotEnvPath  string            `json:"dotenv_path"`
+	Protected   bool              `json:"protected"`
+}
+
+// UpdateEnvironmentRequest 更新环境，未提供的字段保持不变
+type UpdateEnvironmentRequest struct {
+	Name        string            `json:"name"`
+	Description *string           `json:"description"`
+	Tags        []string          `json:"tags"`
+	Variables   map[string]string `json:"variables"`
+	DotEnvPath  *string           `json:"dotenv_path"`
+	Protected   *bool             `json:"protected"`
+}
+
+// CloneEnvironmentRequest 克隆环境，project_id 为空时克隆到同一项目
+type CloneEnvironmentRequest struct {
+	Name        string `json:"name" binding:"required"`
+	ProjectID   string `json:"project_id"`
+	CopySources bool   `json:"copy_sources"`
+}
+
+// AddFileRequest 向环境添加文件配置，import 为 true 时将源文件导入为托管源文件
+type AddFileRequest struct {
+	SourcePath  string `json:"source_path" binding:"required"`
+	TargetPath  string `json:"target_path" binding:"required"`
+	Description string `json:"description"`
+	Import      bool   `json:"import"`
+	AllowEmpty  bool   `json:"allow_empty"`
+}
+
+// UpdateFileRequest 更新文件配置，省略的字段保持不变
+// managed 为 true 时将源文件导入为托管源文件，为 false 时改为引用 source_path 指定的外部文件
+type UpdateFileRequest struct {
+	SourcePath  *string `json:"source_path"`
+	TargetPath  *string `json:"target_path"`
+	Description *string `json:"description"`
+	Managed     *bool   `json:"managed"`
+	AllowEmpty  *bool   `json:"allow_empty"`
+}
+
+// UpdateFileContentRequest 保存源文件内容，内容必须是文本，JSON 和 YAML 文件保存前检查语法
+// 引用外部文件的配置保存后改为托管源文件
+type UpdateFileContentRequest struct {
+	Content string `json:"content"`
+}
+
+// FileResponse 添加或更新文件配置的结果
+type FileResponse struct {
+	Message string `json:"message"`
+	FileConfig
+}
+
+// SwitchRequest 切换环境，project_id 和 environment_id 也可以是名称
+type SwitchRequest struct {
+	ProjectID     string `json:"project_id" binding:"required"`
+	EnvironmentID string `json:"environment_id" binding:"required"`
+}
+
+// SwitchResponse 切换环境的结果
+type SwitchResponse struct {
+	Message string `json:"message"`
+	SwitchResult
+}
+
+// RollbackRequest 回滚，backup_id 为空时回滚最近一次切换
+type RollbackRequest struct {
+	BackupID string `json:"backup_id"`
+}
+
+// RollbackResponse 回滚的结果
+type RollbackResponse struct {
+	Message string `json:"message"`
+	RollbackResult
+}
+
+// Status 当前状态和状态版本号，版本号在每次切换或回滚后递增
+type Status struct {
+	AppState
+	Rev uint64 `json:"rev"`
+}
+
+// EnvironmentPlan 环境的文件配置在服务端机器上解析后的路径，preview 为切换到该环境时各目标文件的变更预览
+type EnvironmentPlan struct {
+	Files        []PlannedFile `json:"files"`
+	DotEnvPath   string        `json:"dotenv_path,omitempty"`
+	DotEnvError  string        `json:"dotenv_error,omitempty"`
+	SourcesDir   string        `json:"sources_dir"`
+	Preview      []FilePreview `json:"preview,omitempty"`
+	PreviewError string        `json:"preview_error,omitempty"`
+}
+
+// PlannedFile 单个文件配置解析后的路径，通配符配置的 mappings 为全部匹配文件
+type PlannedFile struct {
+	FileID       string        `json:"file_id"`
+	SourcePath   string        `json:"source_path"`
+	TargetPath   string        `json:"target_path"`
+	ResolveError string        `json:"resolve_error,omitempty"`
+	Mappings     []FileMapping `json:"mappings"`
+	ExpandError  string        `json:"expand_error,omitempty"`
+}
+
+// Health /healthz 和 /readyz 的响应，checks 为未通过的检查及其错误
+type Health struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
@@ -0,0 +1,422 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zoyopei/envswitch/api"
+)
+
+// Client envswitch Web 服务的 REST API 客户端
+type Client struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// Option 客户端选项
+type Option func(*Client)
+
+// WithToken 使用 API 令牌认证
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithHTTPClient 使用自定义的 http.Client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New 创建客户端，baseURL 为服务地址（如 http://localhost:8080）
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Error 服务返回的错误，操作受保护环境需要确认时 Confirmation 不为空
+type Error struct {
+	StatusCode   int
+	Message      string
+	Confirmation *api.ConfirmationRequired
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("envswitch api: %s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// confirmKey 确认令牌在 context 中的键
+type confirmKey struct{}
+
+// WithConfirmation 返回携带确认令牌的 context，用于确认受保护环境上的操作
+// 令牌来自上一次请求返回的 Error.Confirmation.ConfirmToken；ctx 中已有令牌时追加，
+// 用于删除项目等涉及多个受保护环境、需要依次确认的操作
+func WithConfirmation(ctx context.Context, confirmToken string) context.Context {
+	if previous, ok := ctx.Value(confirmKey{}).(string); ok && previous != "" {
+		confirmToken = previous + "," + confirmToken
+	}
+	return context.WithValue(ctx, confirmKey{}, confirmToken)
+}
+
+// 项目
+
+// ListProjects 获取所有项目
+func (c *Client) ListProjects(ctx context.Context) ([]api.Project, error) {
+	var result api.ProjectList
+	if err := c.do(ctx, http.MethodGet, "/projects", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Projects, nil
+}
+
+// CreateProject 创建项目
+func (c *Client) CreateProject(ctx context.Context, request api.CreateProjectRequest) (*api.Project, error) {
+	var result api.Project
+	if err := c.do(ctx, http.MethodPost, "/projects", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ImportOptions 导入项目的选项
+type ImportOptions struct {
+	Name        string            // 导入后的项目名称，为空时使用打包文件中的名称
+	RemapTarget map[string]string // 目标路径映射，旧路径 -> 新路径
+}
+
+// ImportProject 导入项目打包文件
+func (c *Client) ImportProject(ctx context.Context, bundle io.Reader, opts ImportOptions) (*api.Project, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("bundle", "bundle.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(part, bundle); err != nil {
+		return nil, err
+	}
+	if opts.Name != "" {
+		if err := writer.WriteField("name", opts.Name); err != nil {
+			return nil, err
+		}
+	}
+	for oldPath, newPath := range opts.RemapTarget {
+		if err := writer.WriteField("remap_target", oldPath+"="+newPath); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	resp, err := c.send(ctx, http.MethodPost, "/projects/import", writer.FormDataContentType(), &body)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var result api.Project
+	if err := decodeResponse(resp, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetProject 获取项目详情，id 也可以是项目名称
+func (c *Client) GetProject(ctx context.Context, id string) (*api.Project, error) {
+	var result api.Project
+	if err := c.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateProject 更新项目
+func (c *Client) UpdateProject(ctx context.Context, id string, request api.UpdateProjectRequest) (*api.Project, error) {
+	var result api.Project
+	if err := c.do(ctx, http.MethodPut, "/projects/"+url.PathEscape(id), request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteProject 删除项目
+func (c *Client) DeleteProject(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/projects/"+url.PathEscape(id), nil, nil)
+}
+
+// ExportProject 导出项目打包文件（tar.gz）并写入 w
+func (c *Client) ExportProject(ctx context.Context, id string, w io.Writer) error {
+	resp, err := c.send(ctx, http.MethodGet, "/projects/"+url.PathEscape(id)+"/export", "", nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return decodeResponse(resp, nil)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// 环境
+
+// ListEnvironments 获取项目下的所有环境
+func (c *Client) ListEnvironments(ctx context.Context, projectID string) ([]api.Environment, error) {
+	var result api.EnvironmentList
+	if err := c.do(ctx, http.MethodGet, "/projects/"+url.PathEscape(projectID)+"/environments", nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Environments, nil
+}
+
+// CreateEnvironment 在项目下创建环境
+func (c *Client) CreateEnvironment(ctx context.Context, projectID string, request api.CreateEnvironmentRequest) (*api.Environment, error) {
+	var result api.Environment
+	if err := c.do(ctx, http.MethodPost, "/projects/"+url.PathEscape(projectID)+"/environments", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetEnvironment 获取环境详情
+func (c *Client) GetEnvironment(ctx context.Context, id string) (*api.Environment, error) {
+	var result api.Environment
+	if err := c.do(ctx, http.MethodGet, "/environments/"+url.PathEscape(id), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateEnvironment 更新环境
+func (c *Client) UpdateEnvironment(ctx context.Context, id string, request api.UpdateEnvironmentRequest) (*api.Environment, error) {
+	var result api.Environment
+	if err := c.do(ctx, http.MethodPut, "/environments/"+url.PathEscape(id), request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteEnvironment 删除环境，受保护的环境需要确认
+func (c *Client) DeleteEnvironment(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/environments/"+url.PathEscape(id), nil, nil)
+}
+
+// CloneEnvironment 克隆环境
+func (c *Client) CloneEnvironment(ctx context.Context, id string, request api.CloneEnvironmentRequest) (*api.Environment, error) {
+	var result api.Environment
+	if err := c.do(ctx, http.MethodPost, "/environments/"+url.PathEscape(id)+"/clone", request, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// EnvironmentPlan 获取环境文件在服务端解析后的路径，preview 为 true 时包含切换预览
+func (c *Client) EnvironmentPlan(ctx context.Context, id string, preview bool) (*api.EnvironmentPlan, error) {
+	path := "/environments/" + url.PathEscape(id) + "/plan"
+	if preview {
+		path += "?preview=true"
+	}
+
+	var result api.EnvironmentPlan
+	if err := c.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// 文件配置
+
+// AddFile 向环境添加文件配置
+func (c *Client) AddFile(ctx context.Context, envID string, request api.AddFileRequest) (*api.FileConfig, error) {
+	var result api.FileResponse
+	if err := c.do(ctx, http.MethodPost, "/environments/"+url.PathEscape(envID)+"/files", request, &result); err != nil {
+		return nil, err
+	}
+	return &result.FileConfig, nil
+}
+
+// UpdateFile 更新文件配置，受保护的环境需要确认
+func (c *Client) UpdateFile(ctx context.Context, id string, request api.UpdateFileRequest) (*api.FileConfig, error) {
+	var result api.FileResponse
+	if err := c.do(ctx, http.MethodPut, "/files/"+url.PathEscape(id), request, &result); err != nil {
+		return nil, err
+	}
+	return &result.FileConfig, nil
+}
+
+// GetFileContent 获取文件配置的源文件内容
+func (c *Client) GetFileContent(ctx context.Context, id string) (*api.FileContent, error) {
+	var content api.FileContent
+	if err := c.do(ctx, http.MethodGet, "/files/"+url.PathEscape(id)+"/content", nil, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// UpdateFileContent 保存源文件内容，受保护的环境需要确认
+func (c *Client) UpdateFileContent(ctx context.Context, id, content string) (*api.FileConfig, error) {
+	var result api.FileResponse
+	request := api.UpdateFileContentRequest{Content: content}
+	if err := c.do(ctx, http.MethodPut, "/files/"+url.PathEscape(id)+"/content", request, &result); err != nil {
+		return nil, err
+	}
+	return &result.FileConfig, nil
+}
+
+// GetFileTarget 获取文件配置的目标文件当前的内容
+func (c *Client) GetFileTarget(ctx context.Context, id string) (*api.FileContent, error) {
+	var content api.FileContent
+	if err := c.do(ctx, http.MethodGet, "/files/"+url.PathEscape(id)+"/target", nil, &content); err != nil {
+		return nil, err
+	}
+	return &content, nil
+}
+
+// DeleteFile 移除文件配置，受保护的环境需要确认
+func (c *Client) DeleteFile(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/files/"+url.PathEscape(id), nil, nil)
+}
+
+// 切换
+
+// Switch 切换环境，项目和环境也可以使用名称；受保护的环境需要确认
+func (c *Client) Switch(ctx context.Context, projectID, environmentID string) (*api.SwitchResult, error) {
+	var result api.SwitchResponse
+	request := api.SwitchRequest{ProjectID: projectID, EnvironmentID: environmentID}
+	if err := c.do(ctx, http.MethodPost, "/switch", request, &result); err != nil {
+		return nil, err
+	}
+	return &result.SwitchResult, nil
+}
+
+// Rollback 从备份回滚，backupID 为空时回滚最近一次切换
+func (c *Client) Rollback(ctx context.Context, backupID string) (*api.RollbackResult, error) {
+	var result api.RollbackResponse
+	if err := c.do(ctx, http.MethodPost, "/rollback", api.RollbackRequest{BackupID: backupID}, &result); err != nil {
+		return nil, err
+	}
+	return &result.RollbackResult, nil
+}
+
+// Status 获取当前状态
+func (c *Client) Status(ctx context.Context) (*api.Status, error) {
+	var result api.Status
+	if err := c.do(ctx, http.MethodGet, "/status", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// WaitStatus 长轮询：状态版本号等于 rev 时等待状态变化，最长等待 timeout（按秒取整，服务端上限 2 分钟）
+func (c *Client) WaitStatus(ctx context.Context, rev uint64, timeout time.Duration) (*api.Status, error) {
+	query := url.Values{}
+	query.Set("wait", strconv.FormatUint(rev, 10))
+	query.Set("timeout", strconv.Itoa(max(int(timeout/time.Second), 1)))
+
+	var result api.Status
+	if err := c.do(ctx, http.MethodGet, "/status?"+query.Encode(), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// OpenAPI 获取服务端的 OpenAPI 文档
+func (c *Client) OpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	if err := c.do(ctx, http.MethodGet, "/openapi.json", nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// do 发送 JSON 请求并解析响应，out 为 nil 时忽略响应体
+func (c *Client) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	contentType := ""
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	resp, err := c.send(ctx, method, path, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	return decodeResponse(resp, out)
+}
+
+// send 发送请求，path 相对于 api.BasePath
+func (c *Client) send(ctx context.Context, method, path, contentType string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+api.BasePath+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if confirmToken, ok := ctx.Value(confirmKey{}).(string); ok && confirmToken != "" {
+		req.Header.Set(api.ConfirmTokenHeader, confirmToken)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// decodeResponse 解析响应，错误状态码转换为 *Error
+func decodeResponse(resp *http.Response, out interface{}) error {
+	if resp.StatusCode >= http.StatusBadRequest {
+		data, _ := io.ReadAll(resp.Body)
+		apiErr := &Error{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+
+		if resp.StatusCode == http.StatusPreconditionRequired {
+			var confirmation api.ConfirmationRequired
+			if json.Unmarshal(data, &confirmation) == nil && confirmation.ConfirmToken != "" {
+				apiErr.Confirmation = &confirmation
+			}
+		}
+
+		var errResp api.ErrorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Error != "" {
+			apiErr.Message = errResp.Error
+		}
+		return apiErr
+	}
+
+	if out == nil {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/zoyopei/envswitch/api"
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/events"
+	"github.com/zoyopei/envswitch/internal/web"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupTest(t *testing.T) (*Client, string) {
+	tempDir := t.TempDir()
+	originalDir, _ := os.Getwd()
+	t.Cleanup(func() { _ = os.Chdir(originalDir) })
+	_ = os.Chdir(tempDir)
+
+	// 当前目录下的 config.json 优先，避免写入用户目录
+	testConfig := &internal.Config{
+		DataDir:   filepath.Join(tempDir, "data"),
+		BackupDir: filepath.Join(tempDir, "backups"),
+		WebPort:   8080,
+	}
+	if err := os.WriteFile(config.DefaultConfigFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save test config: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	server := httptest.NewServer(web.NewServer().SetupRoutes())
+	t.Cleanup(server.Close)
+
+	return New(server.URL), tempDir
+}
+
+func TestClient(t *testing.T) {
+	c, tempDir := setupTest(t)
+	ctx := context.Background()
+
+	source := filepath.Join(tempDir, "dev.json")
+	target := filepath.Join(tempDir, "app", "config.json")
+	if err := os.WriteFile(source, []byte(`{"env":"dev"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, []byte(`{"env":"original"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	project, err := c.CreateProject(ctx, api.CreateProjectRequest{Name: "web-app", Description: "demo"})
+	if err != nil {
+		t.Fatalf("CreateProject() error = %v", err)
+	}
+	env, err := c.CreateEnvironment(ctx, project.ID, api.CreateEnvironmentRequest{Name: "dev"})
+	if err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	file, err := c.AddFile(ctx, env.ID, api.AddFileRequest{SourcePath: source, TargetPath: target})
+	if err != nil {
+		t.Fatalf("AddFile() error = %v", err)
+	}
+	if file.ID == "" || file.TargetPath != target {
+		t.Errorf("Unexpected file config %+v", file)
+	}
+	description := "App config"
+	updated, err := c.UpdateFile(ctx, file.ID, api.UpdateFileRequest{Description: &description})
+	if err != nil {
+		t.Fatalf("UpdateFile() error = %v", err)
+	}
+	if updated.ID != file.ID || updated.Description != description || updated.SourcePath != source {
+		t.Errorf("Unexpected updated file config %+v", updated)
+	}
+
+	projects, err := c.ListProjects(ctx)
+	if err != nil || len(projects) != 1 || projects[0].Name != "web-app" {
+		t.Fatalf("ListProjects() = %+v, %v", projects, err)
+	}
+	environments, err := c.ListEnvironments(ctx, "web-app")
+	if err != nil || len(environments) != 1 || len(environments[0].Files) != 1 {
+		t.Fatalf("ListEnvironments() = %+v, %v", environments, err)
+	}
+
+	status, err := c.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+
+	result, err := c.Switch(ctx, "web-app", "dev")
+	if err != nil {
+		t.Fatalf("Switch() error = %v", err)
+	}
+	if result.Project != "web-app" || result.Environment != "dev" || result.BackupID == "" || len(result.Files) != 1 {
+		t.Errorf("Unexpected switch result %+v", result)
+	}
+	if data, _ := os.ReadFile(target); string(data) != `{"env":"dev"}` {
+		t.Errorf("Expected target to be switched, got %s", data)
+	}
+
+	// 切换后状态版本号递增，长轮询立即返回
+	waited, err := c.WaitStatus(ctx, status.Rev, 5*time.Second)
+	if err != nil {
+		t.Fatalf("WaitStatus() error = %v", err)
+	}
+	if waited.Rev <= status.Rev || waited.CurrentEnvironment != env.ID {
+		t.Errorf("Expected new revision for the switch, got %+v (was %d)", waited, status.Rev)
+	}
+
+	rollback, err := c.Rollback(ctx, "")
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if rollback.BackupID != result.BackupID || len(rollback.RestoredFiles) != 1 || rollback.RestoredFiles[0] != target {
+		t.Errorf("Unexpected rollback result %+v", rollback)
+	}
+	if data, _ := os.ReadFile(target); string(data) != `{"env":"original"}` {
+		t.Errorf("Expected target to be restored, got %s", data)
+	}
+
+	// 源文件和目标文件内容；保存外部源文件时写入托管目录，原文件不变
+	live, err := c.GetFileTarget(ctx, file.ID)
+	if err != nil || live.Content != `{"env":"original"}` || live.Binary {
+		t.Fatalf("GetFileTarget() = %+v, %v", live, err)
+	}
+	var apiErr *Error
+	if _, err := c.UpdateFileContent(ctx, file.ID, `{"env":`); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 error for invalid JSON, got %v", err)
+	}
+	saved, err := c.UpdateFileContent(ctx, file.ID, `{"env":"edited"}`)
+	if err != nil || !saved.Managed || saved.Version != 1 {
+		t.Fatalf("UpdateFileContent() = %+v, %v", saved, err)
+	}
+	content, err := c.GetFileContent(ctx, file.ID)
+	if err != nil || content.Content != `{"env":"edited"}` {
+		t.Fatalf("GetFileContent() = %+v, %v", content, err)
+	}
+	if data, _ := os.ReadFile(source); string(data) != `{"env":"dev"}` {
+		t.Errorf("Expected external source to be unchanged, got %s", data)
+	}
+
+	var bundle bytes.Buffer
+	if err := c.ExportProject(ctx, project.ID, &bundle); err != nil || bundle.Len() == 0 {
+		t.Fatalf("ExportProject() error = %v, %d bytes", err, bundle.Len())
+	}
+	imported, err := c.ImportProject(ctx, &bundle, ImportOptions{Name: "web-app-copy"})
+	if err != nil || imported.Name != "web-app-copy" {
+		t.Fatalf("ImportProject() = %+v, %v", imported, err)
+	}
+
+	spec, err := c.OpenAPI(ctx)
+	if err != nil || spec["openapi"] == nil {
+		t.Fatalf("OpenAPI() = %v, %v", spec, err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	c, _ := setupTest(t)
+	ctx := context.Background()
+
+	_, err := c.GetProject(ctx, "missing")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Project not found" {
+		t.Fatalf("Expected 404 error, got %v", err)
+	}
+
+	if _, err := c.CreateProject(ctx, api.CreateProjectRequest{}); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected 400 error for missing name, got %v", err)
+	}
+
+	// 受保护的环境需要使用返回的确认令牌重新发送请求
+	project, err := c.CreateProject(ctx, api.CreateProjectRequest{Name: "web-app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := c.CreateEnvironment(ctx, project.ID, api.CreateEnvironmentRequest{Name: "prod", Protected: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.DeleteEnvironment(ctx, env.ID)
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusPreconditionRequired || apiErr.Confirmation == nil {
+		t.Fatalf("Expected confirmation required, got %v", err)
+	}
+	if err := c.DeleteEnvironment(WithConfirmation(ctx, apiErr.Confirmation.ConfirmToken), env.ID); err != nil {
+		t.Fatalf("DeleteEnvironment() with confirmation error = %v", err)
+	}
+	if _, err := c.GetEnvironment(ctx, env.ID); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected environment to be deleted, got %v", err)
+	}
+
+	// 取消保护需要确认，修改其他字段不需要
+	staging, err := c.CreateEnvironment(ctx, project.ID, api.CreateEnvironmentRequest{Name: "staging", Protected: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	description := "Staging"
+	if _, err := c.UpdateEnvironment(ctx, staging.ID, api.UpdateEnvironmentRequest{Description: &description}); err != nil {
+		t.Fatalf("UpdateEnvironment() error = %v", err)
+	}
+	unprotect := false
+	_, err = c.UpdateEnvironment(ctx, staging.ID, api.UpdateEnvironmentRequest{Protected: &unprotect})
+	if !errors.As(err, &apiErr) || apiErr.Confirmation == nil || apiErr.Confirmation.Action != "unprotect" {
+		t.Fatalf("Expected confirmation required to remove protection, got %v", err)
+	}
+	updated, err := c.UpdateEnvironment(WithConfirmation(ctx, apiErr.Confirmation.ConfirmToken), staging.ID, api.UpdateEnvironmentRequest{Protected: &unprotect})
+	if err != nil || updated.Protected {
+		t.Fatalf("UpdateEnvironment() with confirmation = %+v, %v", updated, err)
+	}
+	protect := true
+	if _, err := c.UpdateEnvironment(ctx, staging.ID, api.UpdateEnvironmentRequest{Protected: &protect}); err != nil {
+		t.Fatalf("UpdateEnvironment() error = %v", err)
+	}
+
+	// 删除项目时每个受保护的环境依次确认，重试时携带已获得的全部令牌
+	if _, err := c.CreateEnvironment(ctx, project.ID, api.CreateEnvironmentRequest{Name: "prod", Protected: true}); err != nil {
+		t.Fatal(err)
+	}
+	confirmCtx := ctx
+	var confirmed []string
+	for {
+		err = c.DeleteProject(confirmCtx, project.ID)
+		if !errors.As(err, &apiErr) || apiErr.Confirmation == nil {
+			break
+		}
+		confirmed = append(confirmed, apiErr.Confirmation.Environment)
+		confirmCtx = WithConfirmation(confirmCtx, apiErr.Confirmation.ConfirmToken)
+	}
+	if err != nil {
+		t.Fatalf("DeleteProject() with confirmation error = %v", err)
+	}
+	if len(confirmed) != 2 || confirmed[0] != "staging" || confirmed[1] != "prod" {
+		t.Errorf("Expected staging and prod to be confirmed, got %v", confirmed)
+	}
+	if _, err := c.GetProject(ctx, project.ID); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected project to be deleted, got %v", err)
+	}
+}
+
+func TestClientEvents(t *testing.T) {
+	c, _ := setupTest(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stream, err := c.Events(ctx, 0, "web-app")
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+
+	if _, err := c.CreateProject(ctx, api.CreateProjectRequest{Name: "other"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateProject(ctx, api.CreateProjectRequest{Name: "web-app"}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event, ok := <-stream:
+		if !ok {
+			t.Fatal("Event stream closed unexpectedly")
+		}
+		if event.Type != events.ProjectCreated || event.ProjectName != "web-app" {
+			t.Errorf("Expected project.created for web-app, got %+v", event)
+		}
+	case <-ctx.Done():
+		t.Fatal("Timed out waiting for event")
+	}
+}
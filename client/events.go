@@ -0,0 +1,80 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/zoyopei/envswitch/api"
+)
+
+// Events 订阅 Server-Sent Events 实时事件流，projects 为空时接收全部项目的事件
+// lastEventID 不为 0 时先补发之后的事件（仅限服务端缓冲区中的事件），用于断线续传
+// 连接断开或 ctx 取消时关闭返回的通道，调用方可使用最后收到的事件 ID 重新订阅
+func (c *Client) Events(ctx context.Context, lastEventID uint64, projects ...string) (<-chan api.Event, error) {
+	query := url.Values{}
+	for _, project := range projects {
+		query.Add("project", project)
+	}
+	if lastEventID != 0 {
+		query.Set("last_event_id", strconv.FormatUint(lastEventID, 10))
+	}
+
+	path := "/events"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.send(ctx, http.MethodGet, path, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer func() { _ = resp.Body.Close() }()
+		return nil, decodeResponse(resp, nil)
+	}
+
+	ch := make(chan api.Event)
+	go func() {
+		defer close(ch)
+		defer func() { _ = resp.Body.Close() }()
+
+		scanner := bufio.NewScanner(resp.Body)
+		var data strings.Builder
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			// 空行表示一条事件结束；只需要 data 字段，id 和 event 已包含在 JSON 中
+			if line == "" {
+				if data.Len() == 0 {
+					continue
+				}
+				var event api.Event
+				err := json.Unmarshal([]byte(data.String()), &event)
+				data.Reset()
+				if err != nil {
+					continue
+				}
+				select {
+				case ch <- event:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+
+			if value, ok := strings.CutPrefix(line, "data:"); ok {
+				if data.Len() > 0 {
+					data.WriteByte('\n')
+				}
+				data.WriteString(strings.TrimPrefix(value, " "))
+			}
+		}
+	}()
+
+	return ch, nil
+}
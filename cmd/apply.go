@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/zoyopei/envswitch/internal/manifest"
+
+	"github.com/spf13/cobra"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Apply a project manifest",
+	Long: `Reconcile a declarative project manifest (envswitch.yaml) into storage.
+The project, its environments, tags and file mappings are created or updated to match the manifest.
+Environments and files that exist in storage but not in the manifest are only removed with --prune.
+Changes that remove protection from a protected environment, or update or remove its file mappings,
+must be confirmed with --confirm=<env>.`,
+	Example: `  envswitch apply -f envswitch.yaml
+  envswitch apply -f envswitch.yaml --dry-run
+  envswitch apply -f envswitch.yaml --prune
+  envswitch apply -f envswitch.yaml --confirm prod`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		manifestFile, _ := cmd.Flags().GetString("file")
+		prune, _ := cmd.Flags().GetBool("prune")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		m, err := manifest.Load(manifestFile)
+		checkError(err)
+
+		reconciler := manifest.NewReconciler()
+		plan, err := reconciler.Plan(m, prune)
+		checkError(err)
+
+		if plan.Changes == nil {
+			plan.Changes = []manifest.Change{}
+		}
+		result := struct {
+			*manifest.Plan
+			Applied bool `json:"applied"`
+		}{Plan: plan}
+
+		if !structuredOutput() {
+			printPlan(plan)
+		}
+
+		if plan.HasChanges() && !dryRun {
+			// 涉及受保护环境的变更需要逐个确认
+			for _, env := range plan.ProtectedEnvironments() {
+				confirmProtected(cmd, "apply changes to", env)
+			}
+			checkError(reconciler.Apply(plan))
+			result.Applied = true
+		}
+
+		printResult(result, func() {
+			if result.Applied {
+				fmt.Printf("\nProject '%s' applied successfully\n", plan.Project)
+			}
+		})
+	},
+}
+
+// printPlan 输出变更计划
+func printPlan(plan *manifest.Plan) {
+	if !plan.HasChanges() {
+		fmt.Printf("Project '%s' is up to date. No changes.\n", plan.Project)
+	} else {
+		fmt.Printf("Project '%s':\n", plan.Project)
+		for _, change := range plan.Changes {
+			fmt.Printf("  %s\n", change.String())
+		}
+		add, change, destroy := plan.Summary()
+		fmt.Printf("\nPlan: %d to add, %d to change, %d to destroy.\n", add, change, destroy)
+	}
+
+	if len(plan.Unmanaged) > 0 {
+		fmt.Println("\nNot in manifest (use --prune to remove):")
+		for _, item := range plan.Unmanaged {
+			fmt.Printf("  %s\n", item)
+		}
+	}
+}
+
+func init() {
+	applyCmd.Flags().StringP("file", "f", manifest.DefaultFile, "Manifest file (YAML or JSON)")
+	applyCmd.Flags().Bool("prune", false, "Remove environments and files not present in the manifest")
+	applyCmd.Flags().Bool("dry-run", false, "Show the plan without applying it")
+	applyCmd.Flags().StringArray("confirm", nil, "Confirm changes to a protected environment by repeating its name (repeatable)")
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"sort"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
+
+	"github.com/spf13/cobra"
+)
+
+// backend project、env、switch、status 和 rollback 命令使用的数据来源：
+// 本地存储，或通过 --remote / ENVSWITCH_REMOTE / 档案中的远程地址访问的 envswitch 服务
+// 路径解析类方法返回数据所在机器上的路径
+type backend interface {
+	ListProjects() ([]internal.Project, error)
+	GetProject(identifier string) (*internal.Project, error)
+	CreateProject(name, description string) (*internal.Project, error)
+	UpdateProject(identifier string, updates map[string]interface{}) (*internal.Project, error)
+	DeleteProject(identifier string) error
+	ExportProject(identifier string, w io.Writer) error
+	ImportProject(r io.Reader, opts project.ImportOptions) (*internal.Project, error)
+
+	ListEnvironments(projectIdentifier string) ([]internal.Environment, error)
+	GetEnvironment(projectIdentifier, envIdentifier string) (*internal.Environment, error)
+	AddEnvironment(projectIdentifier string, env *internal.Environment) error
+	UpdateEnvironment(projectIdentifier, envIdentifier string, updates map[string]interface{}) (*internal.Environment, error)
+	RemoveEnvironment(projectIdentifier, envIdentifier string) error
+	CloneEnvironment(srcProjectIdentifier, srcEnvIdentifier, dstProjectIdentifier, newName string, copySources bool) (*internal.Environment, error)
+
+	GetFileConfig(projectID, environmentID, fileID string) (*internal.FileConfig, error)
+	CreateFileConfig(projectID, environmentID string, fileConfig *internal.FileConfig, importSource bool) (*internal.FileConfig, error)
+	UpdateFileConfig(projectID, environmentID, fileID string, updates map[string]interface{}) (*internal.FileConfig, error)
+	RemoveFileConfig(projectID, environmentID, fileID string) error
+
+	GetCurrentState() (*internal.AppState, error)
+	SwitchEnvironment(projectID, environmentID string) error
+	Rollback(backupID string) (*internal.RollbackResult, error)
+
+	ResolveSourcePath(fileConfig *internal.FileConfig) string
+	ResolvePaths(project *internal.Project, fileConfig *internal.FileConfig) (string, string, error)
+	ExpandFileConfig(project *internal.Project, fileConfig *internal.FileConfig) ([]internal.FileMapping, error)
+	ResolveDotEnvPath(project *internal.Project, env *internal.Environment) (string, error)
+	PreviewEnvironment(project *internal.Project, env *internal.Environment) ([]file.FilePreview, error)
+	SourcesDir(projectID, envID string) string
+}
+
+// currentBackend 当前命令使用的数据来源，在 PersistentPreRunE 中根据远程设置创建
+var currentBackend backend
+
+// getBackend 获取当前命令使用的数据来源，未设置远程地址时使用本地存储
+func getBackend() backend {
+	if currentBackend == nil {
+		currentBackend = newLocalBackend()
+	}
+	return currentBackend
+}
+
+// setupBackend 根据 --remote 标志、ENVSWITCH_REMOTE 环境变量或当前档案的远程地址选择数据来源
+func setupBackend(cmd *cobra.Command) error {
+	address, _ := cmd.Flags().GetString("remote")
+	if address == "" {
+		address = os.Getenv(remoteEnvVar)
+	}
+	profileAddress, token := config.GetRemote()
+	if address == "" {
+		address = profileAddress
+	}
+	if address == "" {
+		currentBackend = nil
+		return nil
+	}
+
+	address, err := config.ValidateRemote(address)
+	if err != nil {
+		return usageErrorf("%v", err)
+	}
+	if envToken := os.Getenv(remoteTokenEnvVar); envToken != "" {
+		token = envToken
+	}
+
+	currentBackend = newRemoteBackend(cmd, address, token)
+	return nil
+}
+
+// isRemote 当前命令是否通过远程服务执行
+func isRemote() bool {
+	_, ok := getBackend().(*remoteBackend)
+	return ok
+}
+
+// 两个管理器类型同名，通过别名嵌入以区分字段名
+type (
+	projectStore = project.Manager
+	fileStore    = file.Manager
+)
+
+// localBackend 使用本地存储的数据来源，项目和文件管理器的方法没有重名，嵌入后直接提供 backend 的大部分方法
+type localBackend struct {
+	*projectStore
+	*fileStore
+}
+
+// newLocalBackend 创建本地数据来源
+func newLocalBackend() *localBackend {
+	return &localBackend{
+		projectStore: project.NewManager(),
+		fileStore:    file.NewManager(),
+	}
+}
+
+// Rollback 从备份回滚，返回恢复的文件和备份所属的项目、环境
+func (b *localBackend) Rollback(backupID string) (*internal.RollbackResult, error) {
+	// 回滚前读取备份信息，用于输出结果
+	backup, err := b.GetStorage().LoadBackupInfo(backupID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &internal.RollbackResult{
+		BackupID:      backupID,
+		RestoredFiles: make([]string, 0, len(backup.Files)),
+	}
+	for targetPath := range backup.Files {
+		result.RestoredFiles = append(result.RestoredFiles, targetPath)
+	}
+	sort.Strings(result.RestoredFiles)
+	if proj, err := b.GetProject(backup.ProjectID); err == nil {
+		result.Project = proj.Name
+		for _, env := range proj.Environments {
+			if env.ID == backup.EnvID {
+				result.Environment = env.Name
+			}
+		}
+	}
+
+	if err := b.RollbackFromBackup(backupID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SourcesDir 环境托管源文件所在的目录
+func (b *localBackend) SourcesDir(projectID, envID string) string {
+	return b.GetStorage().SourcesDir(projectID, envID)
+}
@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/project"
+	"github.com/zoyopei/envswitch/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Generate shell completion script",
+	Long: `Generate a shell completion script for envswitch.
+Project, environment, file and backup names are completed dynamically from the active profile's data directory.`,
+	Example: `  # bash (current shell / permanently)
+  source <(envswitch completion bash)
+  envswitch completion bash > /etc/bash_completion.d/envswitch
+
+  # zsh
+  envswitch completion zsh > "${fpath[1]}/_envswitch"
+
+  # fish
+  envswitch completion fish > ~/.config/fish/completions/envswitch.fish
+
+  # PowerShell
+  envswitch completion powershell | Out-String | Invoke-Expression`,
+	Args:                  cobra.ExactArgs(1),
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	DisableFlagsInUseLine: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		noDescriptions, _ := cmd.Flags().GetBool("no-descriptions")
+
+		var err error
+		switch args[0] {
+		case "bash":
+			err = rootCmd.GenBashCompletionV2(os.Stdout, !noDescriptions)
+		case "zsh":
+			if noDescriptions {
+				err = rootCmd.GenZshCompletionNoDesc(os.Stdout)
+			} else {
+				err = rootCmd.GenZshCompletion(os.Stdout)
+			}
+		case "fish":
+			err = rootCmd.GenFishCompletion(os.Stdout, !noDescriptions)
+		case "powershell":
+			if noDescriptions {
+				err = rootCmd.GenPowerShellCompletion(os.Stdout)
+			} else {
+				err = rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+			}
+		default:
+			err = fmt.Errorf("unsupported shell '%s', expected one of: bash, zsh, fish, powershell", args[0])
+		}
+		checkError(err)
+	},
+}
+
+// applyCompletionProfile 补全时不会执行 PersistentPreRunE，需要单独应用 --profile
+func applyCompletionProfile(cmd *cobra.Command) {
+	if profile, _ := cmd.Flags().GetString("profile"); profile != "" {
+		_ = config.SetSessionProfile(profile)
+	}
+}
+
+// projectNames 补全项目名称，描述为项目说明
+func projectNames(toComplete string) []string {
+	projects, err := project.NewManager().ListProjects()
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, p := range projects {
+		if strings.HasPrefix(p.Name, toComplete) {
+			names = append(names, completionItem(p.Name, p.Description))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// environmentNames 补全项目下的环境名称，描述为环境说明
+func environmentNames(projectName, toComplete string) []string {
+	if projectName == "" {
+		return nil
+	}
+
+	proj, err := project.NewManager().GetProject(projectName)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, env := range proj.Environments {
+		if strings.HasPrefix(env.Name, toComplete) {
+			names = append(names, completionItem(env.Name, env.Description))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completionItem 生成带描述的补全项
+func completionItem(value, description string) string {
+	description = strings.TrimSpace(strings.ReplaceAll(description, "\n", " "))
+	if description == "" {
+		return value
+	}
+	return value + "\t" + description
+}
+
+// completeProjects 补全第一个参数为项目名称的命令
+func completeProjects(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	applyCompletionProfile(cmd)
+	return projectNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProjectEnv 补全 <project> <env-name> 形式的参数
+func completeProjectEnv(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	applyCompletionProfile(cmd)
+	switch len(args) {
+	case 0:
+		return projectNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		return environmentNames(args[0], toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeOptionalProjectEnv 补全 [project] <env-name> 形式的参数
+// 第一个参数既可以是识别到的项目中的环境，也可以是项目名称
+func completeOptionalProjectEnv(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	applyCompletionProfile(cmd)
+	switch len(args) {
+	case 0:
+		var items []string
+		if detected, _, err := config.DetectProject(); err == nil {
+			items = append(items, environmentNames(detected, toComplete)...)
+		}
+		items = append(items, projectNames(toComplete)...)
+		return items, cobra.ShellCompDirectiveNoFileComp
+	case 1:
+		return environmentNames(args[0], toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeFileIDs 补全 <project> <env-name> <file-id> 形式的参数，文件 ID 的描述为目标路径
+func completeFileIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) < 2 {
+		return completeProjectEnv(cmd, args, toComplete)
+	}
+	if len(args) > 2 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	manager := project.NewManager()
+	proj, err := manager.GetProject(args[0])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	env, err := manager.GetEnvironment(proj.ID, args[1])
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var ids []string
+	for _, fileConfig := range env.Files {
+		if strings.HasPrefix(fileConfig.ID, toComplete) {
+			ids = append(ids, completionItem(fileConfig.ID, fileConfig.TargetPath))
+		}
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeAddFile 补全 add-file 的参数，源路径和目标路径使用文件补全
+func completeAddFile(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) < 2 {
+		return completeProjectEnv(cmd, args, toComplete)
+	}
+	if len(args) < 4 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return nil, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeExec 补全 exec 的参数，-- 之后交给 shell 默认补全
+func completeExec(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if cmd.ArgsLenAtDash() >= 0 {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	return completeOptionalProjectEnv(cmd, args, toComplete)
+}
+
+// completeBackupIDs 补全备份 ID，描述为备份时间及对应的项目和环境
+func completeBackupIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	applyCompletionProfile(cmd)
+
+	store := storage.NewStorage()
+	backups, err := store.ListBackups()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	// 最新的备份排在最前面
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Timestamp.After(backups[j].Timestamp)
+	})
+
+	projectsByID := make(map[string]string)
+	envNames := make(map[string]string)
+	if projects, err := store.ListProjects(); err == nil {
+		for _, p := range projects {
+			projectsByID[p.ID] = p.Name
+			for _, env := range p.Environments {
+				envNames[env.ID] = env.Name
+			}
+		}
+	}
+
+	var ids []string
+	for _, backup := range backups {
+		if !strings.HasPrefix(backup.ID, toComplete) {
+			continue
+		}
+		description := backup.Timestamp.Format("2006-01-02 15:04:05")
+		if name := projectsByID[backup.ProjectID]; name != "" {
+			description += fmt.Sprintf(" %s/%s", name, envNames[backup.EnvID])
+		}
+		ids = append(ids, completionItem(backup.ID, description))
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeProfiles 补全配置档案名称
+func completeProfiles(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, p := range config.ListProfiles() {
+		if strings.HasPrefix(p.Name, toComplete) {
+			names = append(names, p.Name)
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProfileFlag 补全 --profile 标志
+func completeProfileFlag(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return completeProfiles(cmd, nil, toComplete)
+}
+
+// completeTokens 补全 API 令牌名称
+func completeTokens(_ *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var names []string
+	for _, token := range config.ListTokens() {
+		if strings.HasPrefix(token.Name, toComplete) {
+			names = append(names, completionItem(token.Name, token.Role))
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProjectFlag 补全值为项目名称的标志
+func completeProjectFlag(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	applyCompletionProfile(cmd)
+	return projectNames(toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+func init() {
+	completionCmd.Flags().Bool("no-descriptions", false, "Disable completion descriptions")
+
+	// 使用自定义的 completion 命令
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+}
@@ -22,23 +22,46 @@ var configShowCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		cfg := config.GetConfig()
 
-		fmt.Println("📋 当前配置:")
-		fmt.Printf("  数据目录:     %s\n", cfg.DataDir)
-		fmt.Printf("  备份目录:     %s\n", cfg.BackupDir)
-		fmt.Printf("  Web端口:      %d\n", cfg.WebPort)
-		fmt.Printf("  默认项目:     %s\n", cfg.DefaultProject)
-		fmt.Printf("  数据目录检查: %t\n", cfg.EnableDataDirCheck)
-
-		if cfg.OriginalDataDir != "" {
-			fmt.Printf("  原始数据目录: %s\n", cfg.OriginalDataDir)
-		}
+		printResult(config.RedactConfig(cfg), func() {
+			fmt.Println("📋 当前配置:")
+			fmt.Printf("  当前档案:     %s\n", config.GetActiveProfileName())
+			fmt.Printf("  数据目录:     %s\n", config.GetDataDir())
+			fmt.Printf("  备份目录:     %s\n", config.GetBackupDir())
+			fmt.Printf("  Web端口:      %d\n", config.GetWebPort())
+			fmt.Printf("  Web监听地址:  %s\n", config.GetWebBind())
+			if cfg.WebUnixSocket != "" {
+				fmt.Printf("  Unix套接字:   %s (%04o)\n", cfg.WebUnixSocket, config.GetUnixSocketMode())
+			}
+			if cfg.WebTLSCert != "" {
+				fmt.Printf("  HTTPS证书:    %s\n", cfg.WebTLSCert)
+				fmt.Printf("  HTTPS私钥:    %s\n", cfg.WebTLSKey)
+			} else if cfg.WebTLSSelfSigned {
+				fmt.Printf("  HTTPS证书:    自签名\n")
+			}
+			fmt.Printf("  默认项目:     %s\n", config.GetDefaultProject())
+			fmt.Printf("  数据目录检查: %t\n", cfg.EnableDataDirCheck)
+			if len(cfg.ProtectedTags) > 0 {
+				fmt.Printf("  受保护标签:   %s\n", strings.Join(cfg.ProtectedTags, ", "))
+			}
 
-		if len(cfg.DataDirHistory) > 0 {
-			fmt.Printf("  历史数据目录:\n")
-			for i, dir := range cfg.DataDirHistory {
-				fmt.Printf("    %d. %s\n", i+1, dir)
+			if len(cfg.PathVariables) > 0 {
+				fmt.Printf("  路径变量:\n")
+				for _, name := range config.SortedVariableNames(cfg.PathVariables) {
+					fmt.Printf("    %s=%s\n", name, cfg.PathVariables[name])
+				}
 			}
-		}
+
+			if cfg.OriginalDataDir != "" {
+				fmt.Printf("  原始数据目录: %s\n", cfg.OriginalDataDir)
+			}
+
+			if len(cfg.DataDirHistory) > 0 {
+				fmt.Printf("  历史数据目录:\n")
+				for i, dir := range cfg.DataDirHistory {
+					fmt.Printf("    %d. %s\n", i+1, dir)
+				}
+			}
+		})
 	},
 }
 
@@ -52,12 +75,32 @@ var configSetCmd = &cobra.Command{
   backup_dir      - 备份目录路径  
   web_port        - Web服务端口
   default_project - 默认项目名称
-  enable_data_dir_check - 是否启用数据目录检查 (true/false)`,
+  enable_data_dir_check - 是否启用数据目录检查 (true/false)
+  protected_tags  - 受保护环境的标签，逗号分隔，带有这些标签的环境切换和删除前需要确认（值为空时清除）
+  path_var.<NAME> - 本机路径变量，可在文件路径中以 ${NAME} 引用，覆盖项目中的同名变量（值为空时删除）
+  web_bind        - Web服务监听的地址（默认 127.0.0.1，0.0.0.0 表示所有网卡）
+  web_tls_cert    - HTTPS 证书文件，需要同时设置 web_tls_key
+  web_tls_key     - HTTPS 私钥文件
+  web_tls_self_signed - 使用自动生成的自签名证书启用 HTTPS (true/false)
+  web_unix_socket - 监听的 Unix 套接字路径，设置后不再监听 TCP 端口（值为空时清除）
+  web_unix_socket_mode - Unix 套接字文件的权限，八进制（默认 0660）`,
 	Args: cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		key := args[0]
 		value := args[1]
 
+		// 本机路径变量
+		if name, ok := strings.CutPrefix(key, "path_var."); ok {
+			if err := config.SetPathVariable(name, value); err != nil {
+				fmt.Printf("❌ 更新配置失败: %v\n", err)
+				os.Exit(ExitCode(err))
+			}
+			printResult(config.RedactConfig(config.GetConfig()), func() {
+				fmt.Printf("✅ 路径变量 '%s' 已更新为 '%s'\n", name, value)
+			})
+			return
+		}
+
 		updates := make(map[string]interface{})
 
 		switch key {
@@ -69,7 +112,7 @@ var configSetCmd = &cobra.Command{
 			var port int
 			if _, err := fmt.Sscanf(value, "%d", &port); err != nil {
 				fmt.Printf("❌ 错误: web_port 必须是数字\n")
-				return
+				os.Exit(exitUsage)
 			}
 			updates["web_port"] = port
 		case "default_project":
@@ -77,18 +120,32 @@ var configSetCmd = &cobra.Command{
 		case "enable_data_dir_check":
 			enable := strings.ToLower(value) == "true"
 			updates["enable_data_dir_check"] = enable
+		case "web_bind", "web_tls_cert", "web_tls_key", "web_unix_socket", "web_unix_socket_mode":
+			updates[key] = value
+		case "web_tls_self_signed":
+			updates[key] = strings.ToLower(value) == "true"
+		case "protected_tags":
+			var tags []string
+			for _, tag := range strings.Split(value, ",") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					tags = append(tags, tag)
+				}
+			}
+			updates["protected_tags"] = tags
 		default:
 			fmt.Printf("❌ 错误: 不支持的配置项 '%s'\n", key)
-			fmt.Printf("支持的配置项: data_dir, backup_dir, web_port, default_project, enable_data_dir_check\n")
-			return
+			fmt.Printf("支持的配置项: data_dir, backup_dir, web_port, default_project, enable_data_dir_check, protected_tags, path_var.<NAME>, web_bind, web_tls_cert, web_tls_key, web_tls_self_signed, web_unix_socket, web_unix_socket_mode\n")
+			os.Exit(exitUsage)
 		}
 
 		if err := config.UpdateConfig(updates); err != nil {
 			fmt.Printf("❌ 更新配置失败: %v\n", err)
-			return
+			os.Exit(ExitCode(err))
 		}
 
-		fmt.Printf("✅ 配置项 '%s' 已更新为 '%s'\n", key, value)
+		printResult(config.RedactConfig(config.GetConfig()), func() {
+			fmt.Printf("✅ 配置项 '%s' 已更新为 '%s'\n", key, value)
+		})
 	},
 }
 
@@ -106,7 +163,7 @@ var configDataDirMigrateCmd = &cobra.Command{
 
 		if err := config.UpdateConfig(updates); err != nil {
 			fmt.Printf("❌ 数据目录迁移失败: %v\n", err)
-			os.Exit(1)
+			os.Exit(exitError)
 		}
 	},
 }
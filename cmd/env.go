@@ -1,15 +1,18 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/zoyopei/envswitch/internal"
 	"github.com/zoyopei/envswitch/internal/config"
 	"github.com/zoyopei/envswitch/internal/file"
-	"github.com/zoyopei/envswitch/internal/project"
 
 	"github.com/spf13/cobra"
 )
@@ -21,14 +24,16 @@ var envCmd = &cobra.Command{
 }
 
 var envCreateCmd = &cobra.Command{
-	Use:   "create <project> <env-name>",
-	Short: "Create a new environment",
-	Args:  cobra.ExactArgs(2),
+	Use:               "create <project> <env-name>",
+	Short:             "Create a new environment",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProjects,
 	Run: func(cmd *cobra.Command, args []string) {
 		projectName := args[0]
 		envName := args[1]
 		description, _ := cmd.Flags().GetString("description")
 		tagsStr, _ := cmd.Flags().GetString("tags")
+		protected, _ := cmd.Flags().GetBool("protected")
 
 		var tags []string
 		if tagsStr != "" {
@@ -38,152 +43,213 @@ var envCreateCmd = &cobra.Command{
 			}
 		}
 
-		manager := project.NewManager()
+		manager := getBackend()
 
 		env := &internal.Environment{
 			Name:        envName,
 			Description: description,
 			Tags:        tags,
 			Files:       []internal.FileConfig{},
+			Protected:   protected,
 		}
 
 		err := manager.AddEnvironment(projectName, env)
 		checkError(err)
 
-		fmt.Printf("Environment '%s' created in project '%s'\n", envName, projectName)
+		printResult(env, func() {
+			fmt.Printf("Environment '%s' created in project '%s'\n", envName, projectName)
+		})
 	},
 }
 
 var envListCmd = &cobra.Command{
-	Use:   "list [project]",
-	Short: "List environments",
-	Args:  cobra.MaximumNArgs(1),
+	Use:               "list [project]",
+	Short:             "List environments",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeProjects,
 	Run: func(cmd *cobra.Command, args []string) {
-		manager := project.NewManager()
+		manager := getBackend()
 
 		var projectName string
 		if len(args) > 0 {
 			projectName = args[0]
 		} else {
-			// 使用默认项目
-			projectName = config.GetDefaultProject()
+			// 使用目录标记或默认项目
+			projectName = detectProject()
 			if projectName == "" {
-				fmt.Println("No project specified and no default project set")
-				fmt.Println("Usage: envswitch env list <project>")
-				return
+				checkError(usageErrorf("no project specified, no %s marker found and no default project set; usage: envswitch env list <project>", config.MarkerFile))
 			}
 		}
 
 		environments, err := manager.ListEnvironments(projectName)
 		checkError(err)
 
-		if len(environments) == 0 {
-			fmt.Printf("No environments found in project '%s'\n", projectName)
-			return
+		if environments == nil {
+			environments = []internal.Environment{}
 		}
 
-		// 获取当前应用状态
-		storage := manager.GetStorage()
-		appState, err := storage.LoadAppState()
-		if err != nil {
-			fmt.Printf("Warning: failed to load app state: %v\n", err)
-			appState = &internal.AppState{}
-		}
+		printResult(environments, func() {
+			if len(environments) == 0 {
+				fmt.Printf("No environments found in project '%s'\n", projectName)
+				return
+			}
 
-		// 获取项目信息以检查当前项目
-		project, err := manager.GetProject(projectName)
-		if err != nil {
-			fmt.Printf("Warning: failed to get project info: %v\n", err)
-		}
+			// 获取当前应用状态
+			appState, err := manager.GetCurrentState()
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Warning: failed to load app state: %v\n", err)
+				appState = &internal.AppState{}
+			}
 
-		fmt.Printf("Environments in project '%s':\n\n", projectName)
+			// 获取项目信息以检查当前项目
+			project, err := manager.GetProject(projectName)
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Warning: failed to get project info: %v\n", err)
+			}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		_, _ = fmt.Fprintln(w, "NAME\tDESCRIPTION\tTAGS\tFILES\tCREATED\tLAST SWITCH")
+			fmt.Printf("Environments in project '%s':\n\n", projectName)
 
-		for _, env := range environments {
-			tagsStr := strings.Join(env.Tags, ", ")
-			if len(tagsStr) > 20 {
-				tagsStr = tagsStr[:17] + "..."
-			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tDESCRIPTION\tTAGS\tFILES\tCREATED\tLAST SWITCH")
 
-			lastSwitch := "Never"
-			if env.LastSwitchAt != nil {
-				lastSwitch = env.LastSwitchAt.Format("2006-01-02 15:04")
-			}
+			for _, env := range environments {
+				tagsStr := strings.Join(env.Tags, ", ")
+				if len(tagsStr) > 20 {
+					tagsStr = tagsStr[:17] + "..."
+				}
 
-			// 检查是否为当前环境
-			marker := ""
-			if project != nil && (appState.CurrentProject == project.Name || appState.CurrentProject == project.ID) &&
-				(appState.CurrentEnvironment == env.Name || appState.CurrentEnvironment == env.ID) {
-				marker = "*"
-			}
+				lastSwitch := "Never"
+				if env.LastSwitchAt != nil {
+					lastSwitch = env.LastSwitchAt.Format("2006-01-02 15:04")
+				}
 
-			_, _ = fmt.Fprintf(w, "%s%s\t%s\t%s\t%d\t%s\t%s\n",
-				marker,
-				env.Name,
-				truncateString(env.Description, 25),
-				tagsStr,
-				len(env.Files),
-				env.CreatedAt.Format("2006-01-02 15:04"),
-				lastSwitch,
-			)
-		}
-		_ = w.Flush()
+				// 检查是否为当前环境
+				marker := ""
+				if project != nil && (appState.CurrentProject == project.Name || appState.CurrentProject == project.ID) &&
+					(appState.CurrentEnvironment == env.Name || appState.CurrentEnvironment == env.ID) {
+					marker = "*"
+				}
+
+				_, _ = fmt.Fprintf(w, "%s%s\t%s\t%s\t%d\t%s\t%s\n",
+					marker,
+					env.Name,
+					truncateString(env.Description, 25),
+					tagsStr,
+					len(env.Files),
+					env.CreatedAt.Format("2006-01-02 15:04"),
+					lastSwitch,
+				)
+			}
+			_ = w.Flush()
 		
-		// 显示图例
-		fmt.Println("\n* = Current environment")
+			// 显示图例
+			fmt.Println("\n* = Current environment")
+		})
 	},
 }
 
 var envShowCmd = &cobra.Command{
-	Use:   "show <project> <env-name>",
-	Short: "Show environment details",
-	Args:  cobra.ExactArgs(2),
+	Use:               "show <project> <env-name>",
+	Short:             "Show environment details",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProjectEnv,
 	Run: func(_ *cobra.Command, args []string) {
 		projectName := args[0]
 		envName := args[1]
 
-		manager := project.NewManager()
-		env, err := manager.GetEnvironment(projectName, envName)
+		manager := getBackend()
+		proj, err := manager.GetProject(projectName)
 		checkError(err)
 
-		fmt.Printf("Environment: %s\n", env.Name)
-		fmt.Printf("ID: %s\n", env.ID)
-		fmt.Printf("Description: %s\n", env.Description)
-		fmt.Printf("Tags: %s\n", strings.Join(env.Tags, ", "))
-		fmt.Printf("Created: %s\n", env.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Updated: %s\n", env.UpdatedAt.Format("2006-01-02 15:04:05"))
+		env, err := manager.GetEnvironment(proj.ID, envName)
+		checkError(err)
 
-		if env.LastSwitchAt != nil {
-			fmt.Printf("Last Switch: %s\n", env.LastSwitchAt.Format("2006-01-02 15:04:05"))
-		} else {
-			fmt.Printf("Last Switch: Never\n")
-		}
+		printResult(env, func() {
+			fmt.Printf("Environment: %s\n", env.Name)
+			fmt.Printf("ID: %s\n", env.ID)
+			fmt.Printf("Description: %s\n", env.Description)
+			fmt.Printf("Tags: %s\n", strings.Join(env.Tags, ", "))
+			if reason := config.ProtectionReason(env); reason != "" {
+				fmt.Printf("Protected: yes (%s)\n", reason)
+			}
+			fmt.Printf("Created: %s\n", env.CreatedAt.Format("2006-01-02 15:04:05"))
+			fmt.Printf("Updated: %s\n", env.UpdatedAt.Format("2006-01-02 15:04:05"))
 
-		fmt.Printf("Files: %d\n", len(env.Files))
+			if env.LastSwitchAt != nil {
+				fmt.Printf("Last Switch: %s\n", env.LastSwitchAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("Last Switch: Never\n")
+			}
 
-		if len(env.Files) > 0 {
-			fmt.Println("\nFile Configurations:")
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			_, _ = fmt.Fprintln(w, "  SOURCE\tTARGET\tDESCRIPTION")
+			if env.DotEnvPath != "" {
+				dotEnv := env.DotEnvPath
+				if resolved, err := manager.ResolveDotEnvPath(proj, env); err != nil {
+					dotEnv = fmt.Sprintf("%s (error: %v)", dotEnv, err)
+				} else if resolved != env.DotEnvPath {
+					dotEnv = fmt.Sprintf("%s -> %s", dotEnv, resolved)
+				}
+				fmt.Printf("DotEnv: %s\n", dotEnv)
+			}
 
-			for _, file := range env.Files {
-				_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\n",
-					file.SourcePath,
-					file.TargetPath,
-					file.Description,
-				)
+			if len(env.Variables) > 0 {
+				fmt.Println("Variables:")
+				for _, name := range config.SortedVariableNames(env.Variables) {
+					fmt.Printf("  %s=%s\n", name, env.Variables[name])
+				}
 			}
-			_ = w.Flush()
-		}
+
+			fmt.Printf("Files: %d\n", len(env.Files))
+
+			if len(env.Files) > 0 {
+				fmt.Println("\nFile Configurations:")
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				_, _ = fmt.Fprintln(w, "  ID\tSOURCE\tTARGET\tDESCRIPTION")
+
+				for _, fileConfig := range env.Files {
+					source := fileConfig.SourcePath
+					if fileConfig.Managed {
+						source = fmt.Sprintf("%s (managed v%d)", fileConfig.SourcePath, fileConfig.Version)
+					}
+					target := fileConfig.TargetPath
+
+					// 存储路径与解析后的路径不同时一并显示
+					resolvedSource, resolvedTarget, err := manager.ResolvePaths(proj, &fileConfig)
+					if err != nil {
+						target = fmt.Sprintf("%s (error: %v)", target, err)
+					} else {
+						if !fileConfig.Managed && resolvedSource != fileConfig.SourcePath {
+							source = fmt.Sprintf("%s -> %s", source, resolvedSource)
+						}
+						if resolvedTarget != fileConfig.TargetPath {
+							target = fmt.Sprintf("%s -> %s", target, resolvedTarget)
+						}
+					}
+					if isGlobFileConfig(&fileConfig) {
+						if mappings, err := manager.ExpandFileConfig(proj, &fileConfig); err == nil {
+							source = fmt.Sprintf("%s [%d files]", source, len(mappings))
+						} else {
+							source = fmt.Sprintf("%s [error: %v]", source, err)
+						}
+					}
+
+					_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\n",
+						fileConfig.ID,
+						source,
+						target,
+						fileConfig.Description,
+					)
+				}
+				_ = w.Flush()
+			}
+		})
 	},
 }
 
 var envUpdateCmd = &cobra.Command{
-	Use:   "update <project> <env-name>",
-	Short: "Update environment",
-	Args:  cobra.ExactArgs(2),
+	Use:               "update <project> <env-name>",
+	Short:             "Update environment",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProjectEnv,
 	Run: func(cmd *cobra.Command, args []string) {
 		projectName := args[0]
 		envName := args[1]
@@ -207,64 +273,119 @@ var envUpdateCmd = &cobra.Command{
 			updates["tags"] = tags
 		}
 
+		if cmd.Flags().Changed("dotenv") {
+			dotEnvPath, _ := cmd.Flags().GetString("dotenv")
+			updates["dotenv_path"] = dotEnvPath
+		}
+
+		if cmd.Flags().Changed("protected") {
+			protected, _ := cmd.Flags().GetBool("protected")
+			updates["protected"] = protected
+		}
+
+		manager := getBackend()
+
+		// 环境变量在现有变量基础上合并
+		setVars, _ := cmd.Flags().GetStringArray("var")
+		unsetVars, _ := cmd.Flags().GetStringArray("unset-var")
+		if len(setVars) > 0 || len(unsetVars) > 0 {
+			proj, err := manager.GetProject(projectName)
+			checkError(err)
+			current, err := manager.GetEnvironment(proj.ID, envName)
+			checkError(err)
+
+			vars := make(map[string]string)
+			for name, value := range current.Variables {
+				vars[name] = value
+			}
+			for _, v := range setVars {
+				parts := strings.SplitN(v, "=", 2)
+				if len(parts) != 2 || parts[0] == "" {
+					checkError(usageErrorf("invalid --var '%s', expected NAME=value", v))
+				}
+				vars[parts[0]] = parts[1]
+			}
+			for _, name := range unsetVars {
+				delete(vars, name)
+			}
+			updates["variables"] = vars
+		}
+
 		if len(updates) == 0 {
-			fmt.Println("No updates specified")
-			return
+			checkError(usageErrorf("no updates specified"))
+		}
+
+		// 取消受保护环境的保护（清除标记或移除受保护标签）需要确认
+		if cmd.Flags().Changed("protected") || cmd.Flags().Changed("tags") {
+			current, err := manager.GetEnvironment(projectName, envName)
+			checkError(err)
+			if config.RemovesProtection(current, updates) {
+				confirmProtected(cmd, "remove protection from", current)
+			}
 		}
 
-		manager := project.NewManager()
 		env, err := manager.UpdateEnvironment(projectName, envName, updates)
 		checkError(err)
 
-		fmt.Printf("Environment '%s' updated successfully\n", env.Name)
+		printResult(env, func() {
+			fmt.Printf("Environment '%s' updated successfully\n", env.Name)
+		})
 	},
 }
 
 var envDeleteCmd = &cobra.Command{
-	Use:   "delete <project> <env-name>",
-	Short: "Delete an environment",
-	Args:  cobra.ExactArgs(2),
+	Use:               "delete <project> <env-name>",
+	Short:             "Delete an environment",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: completeProjectEnv,
 	Run: func(cmd *cobra.Command, args []string) {
 		projectName := args[0]
 		envName := args[1]
 		force, _ := cmd.Flags().GetBool("force")
 
-		manager := project.NewManager()
+		manager := getBackend()
 
 		// 获取环境信息用于确认
 		env, err := manager.GetEnvironment(projectName, envName)
 		checkError(err)
 
-		if !force {
-			fmt.Printf("Are you sure you want to delete environment '%s' from project '%s'?\n", env.Name, projectName)
-			fmt.Print("Type 'yes' to confirm: ")
-			var confirmation string
-			_, _ = fmt.Scanln(&confirmation)
-			if confirmation != "yes" {
-				fmt.Println("Operation cancelled")
-				return
-			}
+		// 受保护的环境总是需要输入环境名称确认，--force 无效
+		if config.IsProtected(env) {
+			confirmProtected(cmd, "delete", env)
+		} else if !force {
+			confirm(fmt.Sprintf("Are you sure you want to delete environment '%s' from project '%s'?", env.Name, projectName))
 		}
 
 		err = manager.RemoveEnvironment(projectName, envName)
 		checkError(err)
 
-		fmt.Printf("Environment '%s' deleted from project '%s'\n", env.Name, projectName)
+		printResult(env, func() {
+			fmt.Printf("Environment '%s' deleted from project '%s'\n", env.Name, projectName)
+		})
 	},
 }
 
 var envAddFileCmd = &cobra.Command{
 	Use:   "add-file <project> <env-name> <source> <target>",
 	Short: "Add file configuration to environment",
-	Args:  cobra.ExactArgs(4),
+	Long: `Add a file configuration to an environment.
+The source may be a glob pattern (*, ?, [...] and ** for any number of directories); the target is then
+a destination directory and matched files keep their path relative to the non-glob part of the pattern.
+Quote glob patterns to keep the shell from expanding them.`,
+	Example: `  envswitch env add-file myapp dev config/dev.yaml app/config.yaml
+  envswitch env add-file myapp dev 'conf/dev/**/*.properties' app/conf/`,
+	Args:              cobra.ExactArgs(4),
+	ValidArgsFunction: completeAddFile,
 	Run: func(cmd *cobra.Command, args []string) {
 		projectName := args[0]
 		envName := args[1]
 		sourcePath := args[2]
 		targetPath := args[3]
 		description, _ := cmd.Flags().GetString("description")
+		importSource, _ := cmd.Flags().GetBool("import")
+		allowEmpty, _ := cmd.Flags().GetBool("allow-empty")
 
-		manager := project.NewManager()
+		manager := getBackend()
 
 		// 获取项目和环境ID
 		proj, err := manager.GetProject(projectName)
@@ -273,26 +394,173 @@ var envAddFileCmd = &cobra.Command{
 		env, err := manager.GetEnvironment(projectName, envName)
 		checkError(err)
 
-		fileManager := file.NewManager()
-		err = fileManager.AddFileConfig(proj.ID, env.ID, sourcePath, targetPath, description)
+		// 命令行中的相对路径以当前目录为基准，保存为相对项目根目录的路径或绝对路径
+		// 远程模式下路径属于服务端所在的机器，原样发送
+		if !isRemote() {
+			sourcePath = portablePath(proj, sourcePath)
+			targetPath = portablePath(proj, targetPath)
+		}
+
+		fileConfig, err := manager.CreateFileConfig(proj.ID, env.ID, &internal.FileConfig{
+			SourcePath:  sourcePath,
+			TargetPath:  targetPath,
+			Description: description,
+			AllowEmpty:  allowEmpty,
+		}, importSource)
 		checkError(err)
 
-		fmt.Printf("File configuration added to environment '%s'\n", envName)
-		fmt.Printf("Source: %s\n", sourcePath)
-		fmt.Printf("Target: %s\n", targetPath)
+		printResult(fileConfig, func() {
+			fmt.Printf("File configuration added to environment '%s'\n", envName)
+			if importSource {
+				fmt.Printf("Source: %s (imported from %s)\n", manager.ResolveSourcePath(fileConfig), sourcePath)
+			} else {
+				fmt.Printf("Source: %s\n", sourcePath)
+			}
+			fmt.Printf("Target: %s\n", targetPath)
+
+			// 通配符配置显示当前匹配的文件
+			if mappings, err := manager.ExpandFileConfig(proj, fileConfig); err == nil && isGlobFileConfig(fileConfig) {
+				fmt.Printf("Matched %d files\n", len(mappings))
+			}
+		})
 	},
 }
 
 var envRemoveFileCmd = &cobra.Command{
-	Use:   "remove-file <project> <env-name> <file-id>",
-	Short: "Remove file configuration from environment",
-	Args:  cobra.ExactArgs(3),
+	Use:               "remove-file <project> <env-name> <file-id>",
+	Short:             "Remove file configuration from environment",
+	Args:              cobra.ExactArgs(3),
+	ValidArgsFunction: completeFileIDs,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectName := args[0]
+		envName := args[1]
+		fileID := args[2]
+
+		manager := getBackend()
+
+		// 获取项目和环境ID
+		proj, err := manager.GetProject(projectName)
+		checkError(err)
+
+		env, err := manager.GetEnvironment(projectName, envName)
+		checkError(err)
+
+		fileConfig, err := manager.GetFileConfig(proj.ID, env.ID, fileID)
+		checkError(err)
+
+		confirmProtected(cmd, "remove a file from", env)
+
+		err = manager.RemoveFileConfig(proj.ID, env.ID, fileID)
+		checkError(err)
+
+		printResult(fileConfig, func() {
+			fmt.Printf("File configuration removed from environment '%s'\n", envName)
+		})
+	},
+}
+
+var envUpdateFileCmd = &cobra.Command{
+	Use:   "update-file <project> <env-name> <file-id>",
+	Short: "Update a file configuration in place",
+	Long: `Change the source, target, description or mode of a file configuration while keeping its ID.
+--managed copies the source into the managed sources area; --managed=false switches back to an
+external source file and requires --source. Setting --source on a managed file imports the new file.`,
+	Example: `  envswitch env update-file myapp dev <file-id> --target app/config.local.json
+  envswitch env update-file myapp dev <file-id> --managed
+  envswitch env update-file myapp dev <file-id> --managed=false --source config/dev.yaml`,
+	Args:              cobra.ExactArgs(3),
+	ValidArgsFunction: completeFileIDs,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectName := args[0]
+		envName := args[1]
+		fileID := args[2]
+
+		if !cmd.Flags().Changed("source") && !cmd.Flags().Changed("target") && !cmd.Flags().Changed("description") &&
+			!cmd.Flags().Changed("managed") && !cmd.Flags().Changed("allow-empty") {
+			checkError(usageErrorf("at least one of --source, --target, --description, --managed or --allow-empty must be provided"))
+		}
+
+		if managed, _ := cmd.Flags().GetBool("managed"); cmd.Flags().Changed("managed") && !managed && !cmd.Flags().Changed("source") {
+			checkError(usageErrorf("--managed=false requires --source"))
+		}
+
+		manager := getBackend()
+
+		// 获取项目和环境ID
+		proj, err := manager.GetProject(projectName)
+		checkError(err)
+
+		env, err := manager.GetEnvironment(projectName, envName)
+		checkError(err)
+
+		_, err = manager.GetFileConfig(proj.ID, env.ID, fileID)
+		checkError(err)
+
+		// 命令行中的相对路径与 add-file 相同处理，远程模式下原样发送
+		updates := make(map[string]interface{})
+		if cmd.Flags().Changed("source") {
+			sourcePath, _ := cmd.Flags().GetString("source")
+			if !isRemote() {
+				sourcePath = portablePath(proj, sourcePath)
+			}
+			updates["source_path"] = sourcePath
+		}
+		if cmd.Flags().Changed("target") {
+			targetPath, _ := cmd.Flags().GetString("target")
+			if !isRemote() {
+				targetPath = portablePath(proj, targetPath)
+			}
+			updates["target_path"] = targetPath
+		}
+		if cmd.Flags().Changed("description") {
+			description, _ := cmd.Flags().GetString("description")
+			updates["description"] = description
+		}
+		if cmd.Flags().Changed("managed") {
+			managed, _ := cmd.Flags().GetBool("managed")
+			updates["managed"] = managed
+		}
+		if cmd.Flags().Changed("allow-empty") {
+			allowEmpty, _ := cmd.Flags().GetBool("allow-empty")
+			updates["allow_empty"] = allowEmpty
+		}
+
+		confirmProtected(cmd, "update a file in", env)
+
+		fileConfig, err := manager.UpdateFileConfig(proj.ID, env.ID, fileID, updates)
+		checkError(err)
+
+		printResult(fileConfig, func() {
+			fmt.Printf("File configuration updated in environment '%s'\n", envName)
+			if fileConfig.Managed {
+				fmt.Printf("Source: %s (managed v%d)\n", manager.ResolveSourcePath(fileConfig), fileConfig.Version)
+			} else {
+				fmt.Printf("Source: %s\n", fileConfig.SourcePath)
+			}
+			fmt.Printf("Target: %s\n", fileConfig.TargetPath)
+		})
+	},
+}
+
+var envEditFileCmd = &cobra.Command{
+	Use:   "edit-file <project> <env-name> <file-id>",
+	Short: "Edit a managed source file in $EDITOR",
+	Long: `Open the managed copy of a source file in $VISUAL or $EDITOR. When the editor exits
+the file is re-validated and saved as a new version; the previous content is kept in the source history.
+Only files added with 'env add-file --import' can be edited.`,
+	Args:              cobra.ExactArgs(3),
+	ValidArgsFunction: completeFileIDs,
 	Run: func(_ *cobra.Command, args []string) {
 		projectName := args[0]
 		envName := args[1]
 		fileID := args[2]
 
-		manager := project.NewManager()
+		// 托管源文件位于服务端的数据目录中，只能在本地编辑
+		if isRemote() {
+			checkError(usageErrorf("edit-file is not supported in remote mode"))
+		}
+
+		manager := getBackend()
 
 		// 获取项目和环境ID
 		proj, err := manager.GetProject(projectName)
@@ -302,10 +570,78 @@ var envRemoveFileCmd = &cobra.Command{
 		checkError(err)
 
 		fileManager := file.NewManager()
-		err = fileManager.RemoveFileConfig(proj.ID, env.ID, fileID)
+		fileConfig, err := fileManager.GetFileConfig(proj.ID, env.ID, fileID)
+		checkError(err)
+
+		if !fileConfig.Managed {
+			checkError(fmt.Errorf("file config %s is not managed, re-add it with 'env add-file --import' to edit its source", fileID))
+		}
+
+		original, err := os.ReadFile(fileManager.ResolveSourcePath(fileConfig))
+		checkError(err)
+
+		// 在临时副本上编辑，保留扩展名以便编辑器识别语法
+		tmpFile, err := os.CreateTemp("", "envswitch-*"+filepath.Ext(fileConfig.SourcePath))
+		checkError(err)
+		defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+		_, err = tmpFile.Write(original)
+		checkError(err)
+		checkError(tmpFile.Close())
+
+		checkError(runEditor(tmpFile.Name()))
+
+		edited, err := os.ReadFile(tmpFile.Name())
+		checkError(err)
+
+		if bytes.Equal(original, edited) {
+			printResult(fileConfig, func() {
+				fmt.Println("No changes made")
+			})
+			return
+		}
+
+		updated, err := fileManager.UpdateManagedSource(proj.ID, env.ID, fileID, edited)
+		checkError(err)
+
+		printResult(updated, func() {
+			fmt.Printf("Source file updated to version %d\n", updated.Version)
+		})
+	},
+}
+
+var envCloneCmd = &cobra.Command{
+	Use:   "clone <project> <src-env> <new-env>",
+	Short: "Clone an environment",
+	Long: `Clone an environment, copying its tags and file configurations.
+Use --to-project to create the clone in another project, and --copy-sources to copy
+the source files into a folder owned by the new environment so it can diverge from the original.`,
+	Args:              cobra.ExactArgs(3),
+	ValidArgsFunction: completeProjectEnv,
+	Run: func(cmd *cobra.Command, args []string) {
+		projectName := args[0]
+		srcEnvName := args[1]
+		newEnvName := args[2]
+		toProject, _ := cmd.Flags().GetString("to-project")
+		copySources, _ := cmd.Flags().GetBool("copy-sources")
+
+		if toProject == "" {
+			toProject = projectName
+		}
+
+		manager := getBackend()
+		env, err := manager.CloneEnvironment(projectName, srcEnvName, toProject, newEnvName, copySources)
 		checkError(err)
 
-		fmt.Printf("File configuration removed from environment '%s'\n", envName)
+		printResult(env, func() {
+			fmt.Printf("Environment '%s' cloned to '%s' in project '%s'\n", srcEnvName, env.Name, toProject)
+			fmt.Printf("Copied %d file configurations\n", len(env.Files))
+			if copySources {
+				proj, err := manager.GetProject(toProject)
+				checkError(err)
+				fmt.Printf("Source files imported to: %s\n", manager.SourcesDir(proj.ID, env.ID))
+			}
+		})
 	},
 }
 
@@ -313,17 +649,44 @@ func init() {
 	// env create
 	envCreateCmd.Flags().StringP("description", "d", "", "Environment description")
 	envCreateCmd.Flags().StringP("tags", "t", "", "Comma-separated tags")
+	envCreateCmd.Flags().Bool("protected", false, "Require typed confirmation to switch to or delete this environment")
 
 	// env update
 	envUpdateCmd.Flags().StringP("description", "d", "", "New description")
 	envUpdateCmd.Flags().StringP("tags", "t", "", "New comma-separated tags")
+	envUpdateCmd.Flags().StringArray("var", nil, "Set an environment variable, e.g. --var API_URL=http://localhost:8080 (repeatable)")
+	envUpdateCmd.Flags().StringArray("unset-var", nil, "Remove an environment variable (repeatable)")
+	envUpdateCmd.Flags().String("dotenv", "", "Write the variables to this .env file on switch (empty to disable)")
+	envUpdateCmd.Flags().Bool("protected", false, "Require typed confirmation to switch to or delete this environment (--protected=false to clear)")
+	envUpdateCmd.Flags().String("confirm", "", "Confirm removing protection from a protected environment by repeating its name")
 
 	// env delete
-	envDeleteCmd.Flags().BoolP("force", "f", false, "Force delete without confirmation")
+	envDeleteCmd.Flags().BoolP("force", "f", false, "Force delete without confirmation (protected environments still require --confirm)")
+	envDeleteCmd.Flags().String("confirm", "", "Confirm deleting a protected environment by repeating its name")
+
+	// env remove-file
+	envRemoveFileCmd.Flags().String("confirm", "", "Confirm changing a protected environment by repeating its name")
 
 	// env add-file
 	envAddFileCmd.Flags().StringP("description", "d", "", "File configuration description")
 
+	// env update-file
+	envUpdateFileCmd.Flags().String("source", "", "New source path or glob pattern")
+	envUpdateFileCmd.Flags().String("target", "", "New target path")
+	envUpdateFileCmd.Flags().StringP("description", "d", "", "New description")
+	envUpdateFileCmd.Flags().Bool("managed", false, "Copy the source into the managed sources area (--managed=false to use an external source)")
+	envUpdateFileCmd.Flags().Bool("allow-empty", false, "Allow a glob source pattern to match no files (--allow-empty=false to clear)")
+	envUpdateFileCmd.Flags().String("confirm", "", "Confirm changing a protected environment by repeating its name")
+
+	// env add-file
+	envAddFileCmd.Flags().Bool("import", false, "Copy the source file into the managed sources area of the data directory")
+	envAddFileCmd.Flags().Bool("allow-empty", false, "Allow a glob source pattern to match no files")
+
+	// env clone
+	envCloneCmd.Flags().String("to-project", "", "Target project for the clone (default is the source project)")
+	_ = envCloneCmd.RegisterFlagCompletionFunc("to-project", completeProjectFlag)
+	envCloneCmd.Flags().Bool("copy-sources", false, "Copy source files into a new per-environment folder")
+
 	// 添加子命令
 	envCmd.AddCommand(envCreateCmd)
 	envCmd.AddCommand(envListCmd)
@@ -331,5 +694,60 @@ func init() {
 	envCmd.AddCommand(envUpdateCmd)
 	envCmd.AddCommand(envDeleteCmd)
 	envCmd.AddCommand(envAddFileCmd)
+	envCmd.AddCommand(envUpdateFileCmd)
 	envCmd.AddCommand(envRemoveFileCmd)
+	envCmd.AddCommand(envCloneCmd)
+	envCmd.AddCommand(envEditFileCmd)
+}
+
+// runEditor 使用 $VISUAL 或 $EDITOR 打开文件
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+		if runtime.GOOS == "windows" {
+			editor = "notepad"
+		}
+	}
+
+	parts := strings.Fields(editor)
+	editorCmd := exec.Command(parts[0], append(parts[1:], path)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	if err := editorCmd.Run(); err != nil {
+		return fmt.Errorf("editor exited with error: %w", err)
+	}
+
+	return nil
+}
+
+// portablePath 将命令行输入的相对路径转换为不依赖当前目录的路径
+// 位于项目根目录下时保存为相对根目录的路径，否则保存为绝对路径；包含 ~ 或变量的路径保持原样
+func portablePath(proj *internal.Project, path string) string {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "~") || strings.Contains(path, "$") {
+		return path
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	if root, ok := config.PathVariables(proj)[config.ProjectRootVariable]; ok {
+		if rel, err := filepath.Rel(root, absPath); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return rel
+		}
+	}
+
+	return absPath
+}
+
+// isGlobFileConfig 判断文件配置的源路径是否为通配符模式
+func isGlobFileConfig(fileConfig *internal.FileConfig) bool {
+	return file.NewManager().IsGlobFileConfig(fileConfig)
 }
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
+
+	"github.com/spf13/cobra"
+)
+
+var execCmd = &cobra.Command{
+	Use:   "exec [project] <env-name> -- <command> [args...]",
+	Short: "Run a command under an environment without switching globally",
+	Long: `Run a single command with an environment's variables injected, without replacing any files for other users.
+With --overlay, the project root is copied to a temporary directory, the environment's file mappings are applied to the copy
+and the command runs inside it with PROJECT_ROOT pointing at the overlay. The overlay is removed when the command exits.
+The overlay copies the project's root directory, or the directory given with --root; one of them is required.
+If the project is omitted, it is detected from the nearest .envswitch marker or the default project.
+The command's exit code is passed through.`,
+	Example: `  envswitch exec myproject staging -- go test ./...
+  envswitch exec staging --overlay -- ./run-integration.sh
+  envswitch exec myproject prod --overlay --keep-overlay -- cat app.yaml`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		if dash < 0 || dash == len(args) {
+			return fmt.Errorf("missing command, usage: %s", cmd.Use)
+		}
+		if dash < 1 || dash > 2 {
+			return fmt.Errorf("expected [project] <env-name> before --, got %d arguments", dash)
+		}
+		return nil
+	},
+	ValidArgsFunction: completeExec,
+	Run: func(cmd *cobra.Command, args []string) {
+		dash := cmd.ArgsLenAtDash()
+		var projectName, envName string
+		if dash == 2 {
+			projectName, envName = args[0], args[1]
+		} else {
+			projectName, envName = detectProject(), args[0]
+		}
+		command := args[dash:]
+
+		useOverlay, _ := cmd.Flags().GetBool("overlay")
+		keepOverlay, _ := cmd.Flags().GetBool("keep-overlay")
+
+		manager := project.NewManager()
+		proj, err := manager.GetProject(projectName)
+		checkError(err)
+
+		env, err := manager.GetEnvironment(proj.ID, envName)
+		checkError(err)
+
+		c := exec.Command(command[0], command[1:]...)
+		c.Stdin = os.Stdin
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		c.Env = os.Environ()
+		for _, name := range config.SortedVariableNames(env.Variables) {
+			c.Env = append(c.Env, name+"="+env.Variables[name])
+		}
+		c.Env = append(c.Env, "ENVSWITCH_PROJECT="+proj.Name, "ENVSWITCH_ENVIRONMENT="+env.Name)
+
+		var overlay *file.Overlay
+		if useOverlay {
+			// 不回退到当前目录，以免在主目录等位置运行时复制整个目录树
+			root, _ := cmd.Flags().GetString("root")
+			if root == "" {
+				root = proj.Root
+			}
+			if root == "" {
+				checkError(usageErrorf("project '%s' has no root directory; set one with 'envswitch project update %s --root <dir>' or pass --root", proj.Name, proj.Name))
+			}
+
+			overlay, err = file.NewManager().CreateOverlay(proj, env, root)
+			checkError(err)
+
+			// 在覆盖目录中对应的位置运行命令
+			wd, err := os.Getwd()
+			if err != nil {
+				overlay.Remove()
+				checkError(err)
+			}
+			c.Dir = overlay.MapPath(wd)
+			c.Env = append(c.Env, config.ProjectRootVariable+"="+overlay.Dir, "ENVSWITCH_OVERLAY="+overlay.Dir)
+
+			if keepOverlay {
+				fmt.Fprintf(os.Stderr, "Overlay: %s\n", overlay.Dir)
+			}
+		}
+
+		// 中断信号由子进程处理，当前进程等待其退出后清理
+		signal.Ignore(os.Interrupt)
+
+		err = c.Run()
+
+		if overlay != nil && !keepOverlay {
+			overlay.Remove()
+		}
+
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr):
+			os.Exit(exitErr.ExitCode())
+		case err != nil:
+			// 错误写入 stderr，不与命令的输出混在一起
+			err = &notRunError{msg: fmt.Sprintf("failed to run %s: %v", filepath.Base(command[0]), err)}
+			printErrorTo(os.Stderr, err)
+			os.Exit(ExitCode(err))
+		}
+	},
+}
+
+func init() {
+	execCmd.Flags().Bool("overlay", false, "Apply the file mappings to a temporary copy of the project root and run the command there")
+	execCmd.Flags().Bool("keep-overlay", false, "Keep the overlay directory after the command exits (prints its path)")
+	execCmd.Flags().String("root", "", "Directory to copy into the overlay (default the project root)")
+}
@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/zoyopei/envswitch/internal"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// 输出格式
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// 退出码
+const (
+	exitOK        = 0
+	exitError     = 1   // 其他错误
+	exitUsage     = 2   // 参数或标志错误
+	exitNotFound  = 3   // 项目、环境、文件配置、备份或档案不存在
+	exitConflict  = 4   // 名称或目标路径冲突
+	exitCancelled = 5   // 用户取消确认
+	exitProtected = 6   // 受保护环境需要确认，但未提供 --confirm 且无法交互确认
+	exitNotRun    = 127 // exec 无法运行命令，与 shell 一致
+)
+
+// legacyFileFlag 命令注解，值为输出文件标志的名称：这些命令在旧版本中用 -o 指定输出文件，
+// -o 的值不是输出格式且带有文件扩展名时（如 -o bundle.tar.gz）作为该标志的值，保持兼容
+const legacyFileFlag = "legacy-output-file"
+
+// outputFormat 当前命令的输出格式
+var outputFormat = outputTable
+
+// usageError 参数或标志错误
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// usageErrorf 创建参数错误
+func usageErrorf(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// protectedError 受保护环境未确认
+type protectedError struct {
+	msg string
+}
+
+func (e *protectedError) Error() string {
+	return e.msg
+}
+
+// notRunError exec 无法运行命令（如命令不存在）
+type notRunError struct {
+	msg string
+}
+
+func (e *notRunError) Error() string {
+	return e.msg
+}
+
+// ExitCode 根据错误类别返回进程退出码
+func ExitCode(err error) int {
+	var usageErr *usageError
+	var protectedErr *protectedError
+	var notRunErr *notRunError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usageErr):
+		return exitUsage
+	case errors.As(err, &protectedErr):
+		return exitProtected
+	case errors.As(err, &notRunErr):
+		return exitNotRun
+	case errors.Is(err, internal.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, internal.ErrAlreadyExists):
+		return exitConflict
+	}
+	return exitError
+}
+
+// structuredOutput 是否以 JSON/YAML 输出
+func structuredOutput() bool {
+	return outputFormat == outputJSON || outputFormat == outputYAML
+}
+
+// validateOutputFormat 校验 --output 标志
+func validateOutputFormat(cmd *cobra.Command) error {
+	format, _ := cmd.Flags().GetString("output")
+	switch format {
+	case "", outputTable:
+		outputFormat = outputTable
+	case outputJSON, outputYAML:
+		outputFormat = format
+	default:
+		name := cmd.Annotations[legacyFileFlag]
+		if name == "" || filepath.Ext(format) == "" {
+			return usageErrorf("invalid output format '%s', expected one of: table, json, yaml", format)
+		}
+		if cmd.Flags().Changed(name) {
+			return usageErrorf("cannot use -o %s together with --%s", format, name)
+		}
+		if err := cmd.Flags().Set(name, format); err != nil {
+			return err
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "Warning: -o <file> is deprecated for '%s', use --%s %s\n", cmd.CommandPath(), name, format)
+		outputFormat = outputTable
+	}
+	return nil
+}
+
+// printResult 按 --output 输出结果：JSON/YAML 直接序列化 v，表格格式调用 table 输出文本
+func printResult(v interface{}, table func()) {
+	switch outputFormat {
+	case outputJSON:
+		data, err := json.MarshalIndent(v, "", "  ")
+		checkError(err)
+		fmt.Println(string(data))
+	case outputYAML:
+		data, err := marshalYAML(v)
+		checkError(err)
+		fmt.Print(string(data))
+	default:
+		table()
+	}
+}
+
+// marshalYAML 以 JSON 字段名和字段顺序输出 YAML，保证与 JSON 输出的字段名一致
+func marshalYAML(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON 是合法的 YAML，解析为节点后可保留字段顺序
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	resetYAMLStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// resetYAMLStyle 清除从 JSON 继承的引号和流式风格
+func resetYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		resetYAMLStyle(child)
+	}
+}
+
+// printError 输出错误信息，JSON/YAML 格式时以结构化形式写入 stderr
+func printError(err error) {
+	printErrorTo(os.Stdout, err)
+}
+
+// printErrorTo 同 printError，表格模式下写入 w
+func printErrorTo(w io.Writer, err error) {
+	if !structuredOutput() {
+		_, _ = fmt.Fprintf(w, "Error: %v\n", err)
+		return
+	}
+
+	result := struct {
+		Error    string `json:"error"`
+		ExitCode int    `json:"exit_code"`
+	}{err.Error(), ExitCode(err)}
+
+	var data []byte
+	if outputFormat == outputJSON {
+		data, _ = json.Marshal(result)
+		data = append(data, '\n')
+	} else {
+		data, _ = marshalYAML(result)
+	}
+	_, _ = os.Stderr.Write(data)
+}
+
+// checkError 出错时输出错误并以对应的退出码退出
+func checkError(err error) {
+	if err != nil {
+		printError(err)
+		os.Exit(ExitCode(err))
+	}
+}
+
+// confirm 提示用户输入 yes 确认，未确认时以取消退出码退出
+// JSON/YAML 格式时提示写入 stderr，避免混入结构化输出
+func confirm(prompt string) {
+	out := os.Stdout
+	if structuredOutput() {
+		out = os.Stderr
+	}
+	_, _ = fmt.Fprintln(out, prompt)
+	_, _ = fmt.Fprint(out, "Type 'yes' to confirm: ")
+	var confirmation string
+	_, _ = fmt.Scanln(&confirmation)
+	if confirmation != "yes" {
+		_, _ = fmt.Fprintln(out, "Operation cancelled")
+		os.Exit(exitCancelled)
+	}
+}
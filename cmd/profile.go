@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage configuration profiles",
+	Long: `Manage named configuration profiles. Each profile has its own data directory,
+backup directory, default project and web port, similar to kubectl contexts.
+A profile with a remote URL runs project, env, switch, status and rollback against that envswitch server.`,
+}
+
+var profileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all profiles",
+	Run: func(_ *cobra.Command, _ []string) {
+		active := config.GetActiveProfileName()
+
+		printResult(config.RedactProfiles(config.ListProfiles()), func() {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tDATA DIR\tBACKUP DIR\tDEFAULT PROJECT\tPORT\tREMOTE")
+
+			for _, p := range config.ListProfiles() {
+				marker := ""
+				if p.Name == active {
+					marker = "*"
+				}
+
+				port := "-"
+				if p.WebPort != 0 {
+					port = fmt.Sprintf("%d", p.WebPort)
+				}
+
+				remote := "-"
+				if p.Remote != "" {
+					remote = p.Remote
+				}
+
+				_, _ = fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\t%s\n",
+					marker,
+					p.Name,
+					p.DataDir,
+					p.BackupDir,
+					p.DefaultProject,
+					port,
+					remote,
+				)
+			}
+			_ = w.Flush()
+
+			// 显示图例
+			fmt.Println("\n* = Active profile")
+		})
+	},
+}
+
+var profileUseCmd = &cobra.Command{
+	Use:               "use <name>",
+	Short:             "Set the active profile",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfiles,
+	Run: func(_ *cobra.Command, args []string) {
+		name := args[0]
+
+		err := config.UseProfile(name)
+		checkError(err)
+
+		profile, err := config.GetProfile(name)
+		checkError(err)
+
+		printResult(config.RedactProfile(profile), func() {
+			fmt.Printf("Switched to profile '%s'\n", name)
+		})
+	},
+}
+
+var profileCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		dataDir, _ := cmd.Flags().GetString("data-dir")
+		backupDir, _ := cmd.Flags().GetString("backup-dir")
+		defaultProject, _ := cmd.Flags().GetString("default-project")
+		port, _ := cmd.Flags().GetInt("port")
+		remote, _ := cmd.Flags().GetString("remote-url")
+		token, _ := cmd.Flags().GetString("token")
+		use, _ := cmd.Flags().GetBool("use")
+
+		if remote != "" {
+			var err error
+			remote, err = config.ValidateRemote(remote)
+			if err != nil {
+				checkError(usageErrorf("%v", err))
+			}
+		} else if token != "" {
+			checkError(usageErrorf("--token requires --remote-url"))
+		}
+
+		profile := internal.Profile{
+			Name:           args[0],
+			DataDir:        dataDir,
+			BackupDir:      backupDir,
+			WebPort:        port,
+			DefaultProject: defaultProject,
+			Remote:         remote,
+			RemoteToken:    token,
+		}
+
+		err := config.CreateProfile(profile)
+		checkError(err)
+
+		created, err := config.GetProfile(profile.Name)
+		checkError(err)
+
+		if use {
+			err = config.UseProfile(created.Name)
+			checkError(err)
+		}
+
+		printResult(config.RedactProfile(created), func() {
+			fmt.Printf("Profile '%s' created\n", created.Name)
+			fmt.Printf("Data dir: %s\n", created.DataDir)
+			fmt.Printf("Backup dir: %s\n", created.BackupDir)
+			if created.Remote != "" {
+				fmt.Printf("Remote: %s\n", created.Remote)
+			}
+			if use {
+				fmt.Printf("Switched to profile '%s'\n", created.Name)
+			}
+		})
+	},
+}
+
+var profileDeleteCmd = &cobra.Command{
+	Use:               "delete <name>",
+	Short:             "Delete a profile",
+	Long:              "Delete a profile from the configuration. The profile's data directory is left untouched.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProfiles,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		force, _ := cmd.Flags().GetBool("force")
+
+		profile, err := config.GetProfile(name)
+		checkError(err)
+
+		if !force {
+			confirm(fmt.Sprintf("Are you sure you want to delete profile '%s'?", name))
+		}
+
+		err = config.DeleteProfile(name)
+		checkError(err)
+
+		printResult(config.RedactProfile(profile), func() {
+			fmt.Printf("Profile '%s' deleted\n", name)
+		})
+	},
+}
+
+var profileSetRemoteCmd = &cobra.Command{
+	Use:   "set-remote <name> [url]",
+	Short: "Set or clear the remote server of a profile",
+	Long: `Set the URL of a running envswitch server for a profile. While the profile is active,
+project, env, switch, status and rollback operate on that server through its REST API.
+The API token is stored in the profile; omit the URL to clear the remote and the token.`,
+	Example: `  envswitch profile set-remote staging https://envswitch.example.com --token <token>
+  envswitch profile set-remote staging`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeProfiles,
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		token, _ := cmd.Flags().GetString("token")
+
+		var address string
+		if len(args) > 1 {
+			address = args[1]
+			if _, err := config.ValidateRemote(address); err != nil {
+				checkError(usageErrorf("%v", err))
+			}
+		} else if token != "" {
+			checkError(usageErrorf("--token requires a remote URL"))
+		}
+
+		err := config.SetRemote(name, address, token)
+		checkError(err)
+
+		profile, err := config.GetProfile(name)
+		checkError(err)
+
+		printResult(config.RedactProfile(profile), func() {
+			if profile.Remote == "" {
+				fmt.Printf("Remote cleared for profile '%s'\n", profile.Name)
+				return
+			}
+			fmt.Printf("Profile '%s' now uses remote %s\n", profile.Name, profile.Remote)
+		})
+	},
+}
+
+func init() {
+	// profile create
+	profileCreateCmd.Flags().String("data-dir", "", "Data directory (default ~/.envswitch/profiles/<name>/data)")
+	profileCreateCmd.Flags().String("backup-dir", "", "Backup directory (default ~/.envswitch/profiles/<name>/backups)")
+	profileCreateCmd.Flags().String("default-project", "", "Default project for this profile")
+	profileCreateCmd.Flags().IntP("port", "p", 0, "Web server port for this profile")
+	profileCreateCmd.Flags().String("remote-url", "", "URL of an envswitch server this profile operates on")
+	profileCreateCmd.Flags().String("token", "", "API token for the remote server")
+	profileCreateCmd.Flags().Bool("use", false, "Switch to the new profile after creating it")
+
+	// profile set-remote
+	profileSetRemoteCmd.Flags().String("token", "", "API token for the remote server")
+
+	// profile delete
+	profileDeleteCmd.Flags().BoolP("force", "f", false, "Force delete without confirmation")
+
+	// 添加子命令
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+	profileCmd.AddCommand(profileSetRemoteCmd)
+}
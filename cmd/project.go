@@ -3,10 +3,13 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/zoyopei/envswitch/internal"
 	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/manifest"
 	"github.com/zoyopei/envswitch/internal/project"
 
 	"github.com/spf13/cobra"
@@ -25,12 +28,20 @@ var projectCreateCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
 		description, _ := cmd.Flags().GetString("description")
+		root, _ := cmd.Flags().GetString("root")
 
-		manager := project.NewManager()
+		manager := getBackend()
 		proj, err := manager.CreateProject(name, description)
 		checkError(err)
 
-		fmt.Printf("Project '%s' created successfully (ID: %s)\n", proj.Name, proj.ID)
+		if root != "" {
+			proj, err = manager.UpdateProject(proj.ID, map[string]interface{}{"root": projectRootPath(root)})
+			checkError(err)
+		}
+
+		printResult(proj, func() {
+			fmt.Printf("Project '%s' created successfully (ID: %s)\n", proj.Name, proj.ID)
+		})
 	},
 }
 
@@ -38,138 +49,159 @@ var projectListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all projects",
 	Run: func(cmd *cobra.Command, _ []string) {
-		manager := project.NewManager()
+		manager := getBackend()
 		projects, err := manager.ListProjects()
 		checkError(err)
 
-		if len(projects) == 0 {
-			fmt.Println("No projects found")
-			return
-		}
-
-		// 获取当前应用状态
-		storage := manager.GetStorage()
-		appState, err := storage.LoadAppState()
-		if err != nil {
-			fmt.Printf("Warning: failed to load app state: %v\n", err)
-			appState = &internal.AppState{}
+		if projects == nil {
+			projects = []internal.Project{}
 		}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		_, _ = fmt.Fprintln(w, "NAME\tDESCRIPTION\tENVIRONMENTS\tCREATED\tUPDATED")
+		printResult(projects, func() {
+			if len(projects) == 0 {
+				fmt.Println("No projects found")
+				return
+			}
 
-		for _, p := range projects {
-			// 检查是否为当前项目
-			marker := ""
-			if appState.CurrentProject == p.Name || appState.CurrentProject == p.ID {
-				marker = "*"
+			// 获取当前应用状态
+			appState, err := manager.GetCurrentState()
+			if err != nil {
+				_, _ = fmt.Fprintf(os.Stderr, "Warning: failed to load app state: %v\n", err)
+				appState = &internal.AppState{}
 			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tDESCRIPTION\tENVIRONMENTS\tCREATED\tUPDATED")
+
+			for _, p := range projects {
+				// 检查是否为当前项目
+				marker := ""
+				if appState.CurrentProject == p.Name || appState.CurrentProject == p.ID {
+					marker = "*"
+				}
 			
-			_, _ = fmt.Fprintf(w, "%s%s\t%s\t%d\t%s\t%s\n",
-				marker,
-				p.Name,
-				truncateString(p.Description, 30),
-				len(p.Environments),
-				p.CreatedAt.Format("2006-01-02 15:04"),
-				p.UpdatedAt.Format("2006-01-02 15:04"),
-			)
-		}
-		_ = w.Flush()
+				_, _ = fmt.Fprintf(w, "%s%s\t%s\t%d\t%s\t%s\n",
+					marker,
+					p.Name,
+					truncateString(p.Description, 30),
+					len(p.Environments),
+					p.CreatedAt.Format("2006-01-02 15:04"),
+					p.UpdatedAt.Format("2006-01-02 15:04"),
+				)
+			}
+			_ = w.Flush()
 		
-		// 显示图例
-		fmt.Println("\n* = Current project")
+			// 显示图例
+			fmt.Println("\n* = Current project")
+		})
 	},
 }
 
 var projectShowCmd = &cobra.Command{
-	Use:   "show <name>",
-	Short: "Show project details",
-	Args:  cobra.ExactArgs(1),
+	Use:               "show <name>",
+	Short:             "Show project details",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProjects,
 	Run: func(cmd *cobra.Command, args []string) {
 		identifier := args[0]
 
-		manager := project.NewManager()
+		manager := getBackend()
 		proj, err := manager.GetProject(identifier)
 		checkError(err)
 
-		fmt.Printf("Project: %s\n", proj.Name)
-		fmt.Printf("ID: %s\n", proj.ID)
-		fmt.Printf("Description: %s\n", proj.Description)
-		fmt.Printf("Created: %s\n", proj.CreatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Updated: %s\n", proj.UpdatedAt.Format("2006-01-02 15:04:05"))
-		fmt.Printf("Environments: %d\n", len(proj.Environments))
-
-		if len(proj.Environments) > 0 {
-			fmt.Println("\nEnvironments:")
-			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			_, _ = fmt.Fprintln(w, "  NAME\tDESCRIPTION\tFILES\tLAST SWITCH")
-
-			for _, env := range proj.Environments {
-				lastSwitch := "Never"
-				if env.LastSwitchAt != nil {
-					lastSwitch = env.LastSwitchAt.Format("2006-01-02 15:04")
+		printResult(proj, func() {
+			fmt.Printf("Project: %s\n", proj.Name)
+			fmt.Printf("ID: %s\n", proj.ID)
+			fmt.Printf("Description: %s\n", proj.Description)
+			if proj.Root != "" {
+				fmt.Printf("Root: %s\n", proj.Root)
+			}
+			if len(proj.PathVariables) > 0 {
+				fmt.Println("Path Variables:")
+				for _, name := range config.SortedVariableNames(proj.PathVariables) {
+					fmt.Printf("  %s=%s\n", name, proj.PathVariables[name])
 				}
-
-				_, _ = fmt.Fprintf(w, "  %s\t%s\t%d\t%s\n",
-					env.Name,
-					truncateString(env.Description, 25),
-					len(env.Files),
-					lastSwitch,
-				)
 			}
-			_ = w.Flush()
-		}
+			fmt.Printf("Created: %s\n", proj.CreatedAt.Format("2006-01-02 15:04:05"))
+			fmt.Printf("Updated: %s\n", proj.UpdatedAt.Format("2006-01-02 15:04:05"))
+			fmt.Printf("Environments: %d\n", len(proj.Environments))
+
+			if len(proj.Environments) > 0 {
+				fmt.Println("\nEnvironments:")
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				_, _ = fmt.Fprintln(w, "  NAME\tDESCRIPTION\tFILES\tLAST SWITCH")
+
+				for _, env := range proj.Environments {
+					lastSwitch := "Never"
+					if env.LastSwitchAt != nil {
+						lastSwitch = env.LastSwitchAt.Format("2006-01-02 15:04")
+					}
+
+					_, _ = fmt.Fprintf(w, "  %s\t%s\t%d\t%s\n",
+						env.Name,
+						truncateString(env.Description, 25),
+						len(env.Files),
+						lastSwitch,
+					)
+				}
+				_ = w.Flush()
+			}
+		})
 	},
 }
 
 var projectDeleteCmd = &cobra.Command{
-	Use:   "delete <name>",
-	Short: "Delete a project",
-	Args:  cobra.ExactArgs(1),
+	Use:               "delete <name>",
+	Short:             "Delete a project",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProjects,
 	Run: func(cmd *cobra.Command, args []string) {
 		identifier := args[0]
 		force, _ := cmd.Flags().GetBool("force")
 
-		manager := project.NewManager()
+		manager := getBackend()
 
 		// 获取项目信息用于确认
 		proj, err := manager.GetProject(identifier)
 		checkError(err)
 
 		if !force {
-			fmt.Printf("Are you sure you want to delete project '%s'? This action cannot be undone.\n", proj.Name)
-			fmt.Print("Type 'yes' to confirm: ")
-			var confirmation string
-			_, _ = fmt.Scanln(&confirmation)
-			if confirmation != "yes" {
-				fmt.Println("Operation cancelled")
-				return
-			}
+			confirm(fmt.Sprintf("Are you sure you want to delete project '%s'? This action cannot be undone.", proj.Name))
+		}
+
+		// 删除项目会删除其中的全部环境，每个受保护的环境都需要确认
+		for i := range proj.Environments {
+			confirmProtected(cmd, "delete", &proj.Environments[i])
 		}
 
 		err = manager.DeleteProject(identifier)
 		checkError(err)
 
-		fmt.Printf("Project '%s' deleted successfully\n", proj.Name)
+		printResult(proj, func() {
+			fmt.Printf("Project '%s' deleted successfully\n", proj.Name)
+		})
 	},
 }
 
 var projectUpdateCmd = &cobra.Command{
-	Use:   "update <n>",
-	Short: "Update a project",
-	Args:  cobra.ExactArgs(1),
+	Use:               "update <n>",
+	Short:             "Update a project",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProjects,
 	Run: func(cmd *cobra.Command, args []string) {
 		identifier := args[0]
 		name, _ := cmd.Flags().GetString("name")
 		description, _ := cmd.Flags().GetString("description")
+		root, _ := cmd.Flags().GetString("root")
+		setVars, _ := cmd.Flags().GetStringArray("var")
+		unsetVars, _ := cmd.Flags().GetStringArray("unset-var")
 
 		// 检查是否至少有一个更新字段
-		if name == "" && description == "" {
-			fmt.Println("Error: At least one of --name or --description must be provided")
-			return
+		if name == "" && description == "" && !cmd.Flags().Changed("root") && len(setVars) == 0 && len(unsetVars) == 0 {
+			checkError(usageErrorf("at least one of --name, --description, --root, --var or --unset-var must be provided"))
 		}
 
-		manager := project.NewManager()
+		manager := getBackend()
 
 		// 构建更新映射
 		updates := make(map[string]interface{})
@@ -179,28 +211,51 @@ var projectUpdateCmd = &cobra.Command{
 		if description != "" {
 			updates["description"] = description
 		}
+		if cmd.Flags().Changed("root") {
+			updates["root"] = projectRootPath(root)
+		}
+		if len(setVars) > 0 || len(unsetVars) > 0 {
+			vars := make(map[string]string)
+			for _, v := range setVars {
+				parts := strings.SplitN(v, "=", 2)
+				if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+					checkError(usageErrorf("invalid --var '%s', expected NAME=value", v))
+				}
+				vars[parts[0]] = parts[1]
+			}
+			for _, v := range unsetVars {
+				vars[v] = ""
+			}
+			updates["path_variables"] = vars
+		}
 
 		proj, err := manager.UpdateProject(identifier, updates)
 		checkError(err)
 
-		fmt.Printf("Project '%s' updated successfully\n", proj.Name)
+		printResult(proj, func() {
+			fmt.Printf("Project '%s' updated successfully\n", proj.Name)
 
-		// 显示更新后的信息
-		fmt.Printf("  Name: %s\n", proj.Name)
-		fmt.Printf("  Description: %s\n", proj.Description)
-		fmt.Printf("  Updated: %s\n", proj.UpdatedAt.Format("2006-01-02 15:04:05"))
+			// 显示更新后的信息
+			fmt.Printf("  Name: %s\n", proj.Name)
+			fmt.Printf("  Description: %s\n", proj.Description)
+			if proj.Root != "" {
+				fmt.Printf("  Root: %s\n", proj.Root)
+			}
+			fmt.Printf("  Updated: %s\n", proj.UpdatedAt.Format("2006-01-02 15:04:05"))
+		})
 	},
 }
 
 var projectSetDefaultCmd = &cobra.Command{
-	Use:   "set-default <n>",
-	Short: "Set the default project",
-	Args:  cobra.ExactArgs(1),
+	Use:               "set-default <n>",
+	Short:             "Set the default project",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeProjects,
 	Run: func(cmd *cobra.Command, args []string) {
 		identifier := args[0]
 
 		// 验证项目存在
-		manager := project.NewManager()
+		manager := getBackend()
 		proj, err := manager.GetProject(identifier)
 		checkError(err)
 
@@ -208,20 +263,163 @@ var projectSetDefaultCmd = &cobra.Command{
 		err = config.SetDefaultProject(proj.Name)
 		checkError(err)
 
-		fmt.Printf("Default project set to '%s'\n", proj.Name)
+		printResult(proj, func() {
+			fmt.Printf("Default project set to '%s'\n", proj.Name)
+		})
+	},
+}
+
+var projectExportCmd = &cobra.Command{
+	Use:               "export <name>",
+	Short:             "Export a project as a portable bundle",
+	Long:              "Export a project with its environments and all referenced source files into a single tar.gz bundle",
+	Args:              cobra.ExactArgs(1),
+	Annotations:       map[string]string{legacyFileFlag: "file"},
+	ValidArgsFunction: completeProjects,
+	Run: func(cmd *cobra.Command, args []string) {
+		identifier := args[0]
+		output, _ := cmd.Flags().GetString("file")
+
+		manager := getBackend()
+		proj, err := manager.GetProject(identifier)
+		checkError(err)
+
+		if output == "" {
+			output = proj.Name + ".tar.gz"
+		}
+
+		f, err := os.Create(output)
+		checkError(err)
+
+		if err := manager.ExportProject(proj.ID, f); err != nil {
+			_ = f.Close()
+			_ = os.Remove(output)
+			checkError(err)
+		}
+		checkError(f.Close())
+
+		printResult(struct {
+			Project string `json:"project"`
+			File    string `json:"file"`
+		}{proj.Name, output}, func() {
+			fmt.Printf("Project '%s' exported to %s\n", proj.Name, output)
+		})
+	},
+}
+
+var projectImportCmd = &cobra.Command{
+	Use:   "import <bundle>",
+	Short: "Import a project from a bundle",
+	Long: `Import a project from a bundle created by 'project export'. Source files are stored
+as managed sources in the data directory. IDs are regenerated if they conflict with existing projects.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		bundlePath := args[0]
+		rename, _ := cmd.Flags().GetString("rename")
+		remaps, _ := cmd.Flags().GetStringArray("remap-target")
+
+		opts := project.ImportOptions{
+			Name:        rename,
+			RemapTarget: make(map[string]string),
+		}
+		for _, remap := range remaps {
+			parts := strings.SplitN(remap, "=", 2)
+			if len(parts) != 2 || parts[0] == "" {
+				checkError(usageErrorf("invalid --remap-target '%s', expected old=new", remap))
+			}
+			opts.RemapTarget[parts[0]] = parts[1]
+		}
+
+		f, err := os.Open(bundlePath)
+		checkError(err)
+		defer func() { _ = f.Close() }()
+
+		manager := getBackend()
+		proj, err := manager.ImportProject(f, opts)
+		checkError(err)
+
+		fileCount := 0
+		for _, env := range proj.Environments {
+			fileCount += len(env.Files)
+		}
+
+		printResult(proj, func() {
+			fmt.Printf("Project '%s' imported successfully (ID: %s)\n", proj.Name, proj.ID)
+			fmt.Printf("Environments: %d, Files: %d\n", len(proj.Environments), fileCount)
+		})
+	},
+}
+
+var projectDumpCmd = &cobra.Command{
+	Use:               "dump <name>",
+	Short:             "Generate a manifest from an existing project",
+	Long:              "Generate a declarative manifest (envswitch.yaml) from an existing project. Paths under the manifest directory are written as relative paths.",
+	Args:              cobra.ExactArgs(1),
+	Annotations:       map[string]string{legacyFileFlag: "file"},
+	ValidArgsFunction: completeProjects,
+	Run: func(cmd *cobra.Command, args []string) {
+		identifier := args[0]
+		output, _ := cmd.Flags().GetString("file")
+		format, _ := cmd.Flags().GetString("format")
+
+		manager := getBackend()
+		proj, err := manager.GetProject(identifier)
+		checkError(err)
+
+		// 相对路径以清单文件所在目录为基准
+		baseDir, err := os.Getwd()
+		checkError(err)
+		if output != "" {
+			absOutput, err := filepath.Abs(output)
+			checkError(err)
+			baseDir = filepath.Dir(absOutput)
+			if format == "" && strings.EqualFold(filepath.Ext(output), ".json") {
+				format = "json"
+			}
+		}
+
+		m := manifest.FromProject(proj, func(fileConfig *internal.FileConfig) (string, string, error) {
+			return manager.ResolvePaths(proj, fileConfig)
+		}, baseDir)
+		data, err := m.Marshal(format)
+		checkError(err)
+
+		if output == "" {
+			fmt.Print(string(data))
+			return
+		}
+
+		checkError(os.WriteFile(output, data, 0644))
+		fmt.Printf("Manifest for project '%s' written to %s\n", proj.Name, output)
 	},
 }
 
 func init() {
 	// project create
 	projectCreateCmd.Flags().StringP("description", "d", "", "Project description")
+	projectCreateCmd.Flags().String("root", "", "Project root directory; relative file paths and ${PROJECT_ROOT} resolve against it")
 
 	// project delete
 	projectDeleteCmd.Flags().BoolP("force", "f", false, "Force delete without confirmation")
+	projectDeleteCmd.Flags().StringArray("confirm", nil, "Confirm deleting a protected environment in the project by repeating its name (repeatable)")
 
 	// project update
 	projectUpdateCmd.Flags().StringP("name", "n", "", "New project name")
 	projectUpdateCmd.Flags().StringP("description", "d", "", "New project description")
+	projectUpdateCmd.Flags().String("root", "", "Project root directory (empty to clear)")
+	projectUpdateCmd.Flags().StringArray("var", nil, "Set a path variable, e.g. --var CONF_DIR=~/.config/app (repeatable)")
+	projectUpdateCmd.Flags().StringArray("unset-var", nil, "Remove a path variable (repeatable)")
+
+	// project export
+	projectExportCmd.Flags().StringP("file", "f", "", "Output bundle file (default <name>.tar.gz)")
+
+	// project import
+	projectImportCmd.Flags().String("rename", "", "Import the project under a different name")
+	projectImportCmd.Flags().StringArray("remap-target", nil, "Rewrite target path prefixes, e.g. --remap-target /home/alice=/home/bob (repeatable)")
+
+	// project dump
+	projectDumpCmd.Flags().StringP("file", "f", "", "Output manifest file (default stdout)")
+	projectDumpCmd.Flags().String("format", "", "Manifest format: yaml or json (default yaml, or json for .json output)")
 
 	// 添加子命令
 	projectCmd.AddCommand(projectCreateCmd)
@@ -230,9 +428,24 @@ func init() {
 	projectCmd.AddCommand(projectDeleteCmd)
 	projectCmd.AddCommand(projectSetDefaultCmd)
 	projectCmd.AddCommand(projectUpdateCmd)
+	projectCmd.AddCommand(projectExportCmd)
+	projectCmd.AddCommand(projectImportCmd)
+	projectCmd.AddCommand(projectDumpCmd)
 }
 
 // 辅助函数
+// projectRootPath 将命令行输入的项目根目录转换为绝对路径（~ 和变量保持原样）
+// 远程模式下路径属于服务端所在的机器，原样保存
+func projectRootPath(root string) string {
+	if root == "" || isRemote() || filepath.IsAbs(root) || strings.HasPrefix(root, "~") || strings.Contains(root, "$") {
+		return root
+	}
+	if absRoot, err := filepath.Abs(root); err == nil {
+		return absRoot
+	}
+	return root
+}
+
 func truncateString(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
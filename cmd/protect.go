@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// confirmedEnvironments 本次命令中已确认的受保护环境名称，远程模式下服务端要求确认时直接使用
+var confirmedEnvironments = make(map[string]bool)
+
+// confirmProtected 操作受保护环境前要求确认：--confirm 必须等于环境名称，
+// 未提供时在终端中提示输入环境名称；非交互环境下以受保护退出码退出
+// 受保护环境的确认不能被 --force 跳过
+func confirmProtected(cmd *cobra.Command, action string, env *internal.Environment) {
+	reason := config.ProtectionReason(env)
+	if reason == "" {
+		return
+	}
+	confirmEnvironment(cmd, action, env.Name, reason)
+}
+
+// confirmEnvironment 要求确认对受保护环境 name 的操作，reason 为受保护的原因
+func confirmEnvironment(cmd *cobra.Command, action, name, reason string) {
+	if flag := cmd.Flags().Lookup("confirm"); flag != nil && flag.Changed {
+		// 可重复的 --confirm（如删除项目）需要包含每个受保护环境的名称
+		if values, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, value := range values.GetSlice() {
+				if value == name {
+					confirmedEnvironments[name] = true
+					return
+				}
+			}
+			checkError(&protectedError{msg: fmt.Sprintf("environment '%s' is protected (%s); add --confirm=%s to %s it", name, reason, name, action)})
+		}
+		if flag.Value.String() != name {
+			checkError(usageErrorf("--confirm value '%s' does not match protected environment '%s'", flag.Value.String(), name))
+		}
+		confirmedEnvironments[name] = true
+		return
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		checkError(&protectedError{msg: fmt.Sprintf("environment '%s' is protected (%s); pass --confirm=%s to %s it", name, reason, name, action)})
+	}
+
+	out := os.Stdout
+	if structuredOutput() {
+		out = os.Stderr
+	}
+	_, _ = fmt.Fprintf(out, "Environment '%s' is protected (%s).\n", name, reason)
+	_, _ = fmt.Fprintf(out, "Type the environment name to %s it: ", action)
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(line) != name {
+		_, _ = fmt.Fprintln(out, "Operation cancelled")
+		os.Exit(exitCancelled)
+	}
+	confirmedEnvironments[name] = true
+}
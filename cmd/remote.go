@@ -0,0 +1,499 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/zoyopei/envswitch/api"
+	"github.com/zoyopei/envswitch/client"
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	// remoteEnvVar 指定远程服务地址的环境变量
+	remoteEnvVar = "ENVSWITCH_REMOTE"
+	// remoteTokenEnvVar 指定远程服务令牌的环境变量，优先于档案中保存的令牌
+	remoteTokenEnvVar = "ENVSWITCH_TOKEN"
+)
+
+// confirmActions 服务端确认动作对应的命令行提示
+var confirmActions = map[string]string{
+	"switch":      "switch to",
+	"delete":      "delete",
+	"unprotect":   "remove protection from",
+	"update-file": "update a file in",
+	"remove-file": "remove a file from",
+}
+
+// remoteBackend 通过 REST API 操作远程 envswitch 服务的数据来源
+// 路径解析使用服务端返回的环境解析结果，与在服务端机器上执行命令的输出一致
+type remoteBackend struct {
+	cmd     *cobra.Command
+	address string
+	client  *client.Client
+	ctx     context.Context
+
+	fileEnvs map[string]string               // 文件配置 ID -> 所属环境 ID
+	plans    map[string]*api.EnvironmentPlan // 环境 ID -> 解析结果
+	previews map[string]*api.EnvironmentPlan // 环境 ID -> 带预览的解析结果
+}
+
+// newRemoteBackend 创建远程数据来源，cmd 用于服务端要求确认受保护环境时读取 --confirm
+func newRemoteBackend(cmd *cobra.Command, address, token string) *remoteBackend {
+	var opts []client.Option
+	if token != "" {
+		opts = append(opts, client.WithToken(token))
+	}
+
+	return &remoteBackend{
+		cmd:      cmd,
+		address:  address,
+		client:   client.New(address, opts...),
+		ctx:      context.Background(),
+		fileEnvs: make(map[string]string),
+		plans:    make(map[string]*api.EnvironmentPlan),
+		previews: make(map[string]*api.EnvironmentPlan),
+	}
+}
+
+// remoteError 将服务端错误转换为与本地相同类别的错误，保证退出码一致
+func (r *remoteBackend) remoteError(err error) error {
+	var apiErr *client.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusNotFound:
+		return internal.NotFoundf("%s", apiErr.Message)
+	case http.StatusConflict:
+		return internal.AlreadyExistsf("%s", apiErr.Message)
+	case http.StatusUnauthorized:
+		return fmt.Errorf("%s (%s); set a token with 'envswitch profile set-remote' or %s", apiErr.Message, r.address, remoteTokenEnvVar)
+	}
+	return errors.New(apiErr.Message)
+}
+
+// confirmed 执行可能需要确认的操作：服务端返回 428 时，已在命令行确认的环境直接使用确认令牌重试，
+// 仅在服务端受保护的环境按 --confirm 或终端输入确认后重试；涉及多个受保护环境时依次确认，
+// 每次重试携带已获得的全部令牌，同一环境再次要求确认（如令牌过期）时返回错误
+func (r *remoteBackend) confirmed(call func(ctx context.Context) error) error {
+	ctx := r.ctx
+	asked := make(map[string]bool)
+	for {
+		err := call(ctx)
+
+		var apiErr *client.Error
+		if !errors.As(err, &apiErr) || apiErr.Confirmation == nil || asked[apiErr.Confirmation.Environment] {
+			return r.remoteError(err)
+		}
+
+		confirmation := apiErr.Confirmation
+		asked[confirmation.Environment] = true
+		if !confirmedEnvironments[confirmation.Environment] {
+			action := confirmActions[confirmation.Action]
+			if action == "" {
+				action = confirmation.Action
+			}
+			confirmEnvironment(r.cmd, action, confirmation.Environment, confirmation.Reason)
+		}
+
+		ctx = client.WithConfirmation(ctx, confirmation.ConfirmToken)
+	}
+}
+
+// track 记录项目中文件配置所属的环境，用于路径解析
+func (r *remoteBackend) track(projects ...internal.Project) {
+	for _, proj := range projects {
+		r.trackEnvironments(proj.Environments...)
+	}
+}
+
+// trackEnvironments 记录环境中文件配置所属的环境，环境内容变化后解析结果需要重新获取
+func (r *remoteBackend) trackEnvironments(environments ...internal.Environment) {
+	for _, env := range environments {
+		for _, fileConfig := range env.Files {
+			r.fileEnvs[fileConfig.ID] = env.ID
+		}
+	}
+}
+
+// plan 获取环境在服务端的解析结果
+func (r *remoteBackend) plan(envID string) (*api.EnvironmentPlan, error) {
+	if plan, ok := r.plans[envID]; ok {
+		return plan, nil
+	}
+
+	plan, err := r.client.EnvironmentPlan(r.ctx, envID, false)
+	if err != nil {
+		return nil, r.remoteError(err)
+	}
+	r.plans[envID] = plan
+	return plan, nil
+}
+
+// plannedFile 获取文件配置在服务端的解析结果
+func (r *remoteBackend) plannedFile(fileConfig *internal.FileConfig) (*api.PlannedFile, error) {
+	envID, ok := r.fileEnvs[fileConfig.ID]
+	if !ok {
+		return nil, internal.NotFoundf("file config not found: %s", fileConfig.ID)
+	}
+
+	plan, err := r.plan(envID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range plan.Files {
+		if plan.Files[i].FileID == fileConfig.ID {
+			return &plan.Files[i], nil
+		}
+	}
+	return nil, internal.NotFoundf("file config not found: %s", fileConfig.ID)
+}
+
+// planError 将解析结果中的错误信息转换为错误
+func planError(message string) error {
+	if message == "" {
+		return nil
+	}
+	return errors.New(message)
+}
+
+// 项目
+
+func (r *remoteBackend) ListProjects() ([]internal.Project, error) {
+	projects, err := r.client.ListProjects(r.ctx)
+	if err != nil {
+		return nil, r.remoteError(err)
+	}
+	r.track(projects...)
+	return projects, nil
+}
+
+func (r *remoteBackend) GetProject(identifier string) (*internal.Project, error) {
+	proj, err := r.client.GetProject(r.ctx, identifier)
+	if err != nil {
+		var apiErr *client.Error
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+			return nil, internal.NotFoundf("project not found: %s", identifier)
+		}
+		return nil, r.remoteError(err)
+	}
+	r.track(*proj)
+	return proj, nil
+}
+
+func (r *remoteBackend) CreateProject(name, description string) (*internal.Project, error) {
+	proj, err := r.client.CreateProject(r.ctx, api.CreateProjectRequest{Name: name, Description: description})
+	return proj, r.remoteError(err)
+}
+
+func (r *remoteBackend) UpdateProject(identifier string, updates map[string]interface{}) (*internal.Project, error) {
+	var request api.UpdateProjectRequest
+	if name, ok := updates["name"].(string); ok {
+		request.Name = name
+	}
+	if description, ok := updates["description"].(string); ok {
+		request.Description = &description
+	}
+	if root, ok := updates["root"].(string); ok {
+		request.Root = &root
+	}
+	if vars, ok := updates["path_variables"].(map[string]string); ok {
+		request.PathVariables = vars
+	}
+
+	proj, err := r.client.UpdateProject(r.ctx, identifier, request)
+	return proj, r.remoteError(err)
+}
+
+func (r *remoteBackend) DeleteProject(identifier string) error {
+	return r.confirmed(func(ctx context.Context) error {
+		return r.client.DeleteProject(ctx, identifier)
+	})
+}
+
+func (r *remoteBackend) ExportProject(identifier string, w io.Writer) error {
+	return r.remoteError(r.client.ExportProject(r.ctx, identifier, w))
+}
+
+func (r *remoteBackend) ImportProject(bundle io.Reader, opts project.ImportOptions) (*internal.Project, error) {
+	proj, err := r.client.ImportProject(r.ctx, bundle, client.ImportOptions{
+		Name:        opts.Name,
+		RemapTarget: opts.RemapTarget,
+	})
+	return proj, r.remoteError(err)
+}
+
+// 环境
+
+func (r *remoteBackend) ListEnvironments(projectIdentifier string) ([]internal.Environment, error) {
+	environments, err := r.client.ListEnvironments(r.ctx, projectIdentifier)
+	if err != nil {
+		return nil, r.remoteError(err)
+	}
+	r.trackEnvironments(environments...)
+	return environments, nil
+}
+
+func (r *remoteBackend) GetEnvironment(projectIdentifier, envIdentifier string) (*internal.Environment, error) {
+	proj, err := r.GetProject(projectIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, env := range proj.Environments {
+		if env.ID == envIdentifier || env.Name == envIdentifier {
+			return &env, nil
+		}
+	}
+	return nil, internal.NotFoundf("environment not found: %s", envIdentifier)
+}
+
+func (r *remoteBackend) AddEnvironment(projectIdentifier string, env *internal.Environment) error {
+	created, err := r.client.CreateEnvironment(r.ctx, projectIdentifier, api.CreateEnvironmentRequest{
+		Name:        env.Name,
+		Description: env.Description,
+		Tags:        env.Tags,
+		Variables:   env.Variables,
+		DotEnvPath:  env.DotEnvPath,
+		Protected:   env.Protected,
+	})
+	if err != nil {
+		return r.remoteError(err)
+	}
+
+	*env = *created
+	return nil
+}
+
+func (r *remoteBackend) UpdateEnvironment(projectIdentifier, envIdentifier string, updates map[string]interface{}) (*internal.Environment, error) {
+	env, err := r.GetEnvironment(projectIdentifier, envIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var request api.UpdateEnvironmentRequest
+	if name, ok := updates["name"].(string); ok {
+		request.Name = name
+	}
+	if description, ok := updates["description"].(string); ok {
+		request.Description = &description
+	}
+	if _, ok := updates["tags"]; ok {
+		// 空列表表示清除标签
+		tags, _ := updates["tags"].([]string)
+		request.Tags = append([]string{}, tags...)
+	}
+	if vars, ok := updates["variables"].(map[string]string); ok {
+		request.Variables = vars
+	}
+	if dotEnvPath, ok := updates["dotenv_path"].(string); ok {
+		request.DotEnvPath = &dotEnvPath
+	}
+	if protected, ok := updates["protected"].(bool); ok {
+		request.Protected = &protected
+	}
+
+	delete(r.plans, env.ID)
+	var updated *internal.Environment
+	err = r.confirmed(func(ctx context.Context) error {
+		var err error
+		updated, err = r.client.UpdateEnvironment(ctx, env.ID, request)
+		return err
+	})
+	return updated, err
+}
+
+func (r *remoteBackend) RemoveEnvironment(projectIdentifier, envIdentifier string) error {
+	env, err := r.GetEnvironment(projectIdentifier, envIdentifier)
+	if err != nil {
+		return err
+	}
+
+	return r.confirmed(func(ctx context.Context) error {
+		return r.client.DeleteEnvironment(ctx, env.ID)
+	})
+}
+
+func (r *remoteBackend) CloneEnvironment(srcProjectIdentifier, srcEnvIdentifier, dstProjectIdentifier, newName string, copySources bool) (*internal.Environment, error) {
+	env, err := r.GetEnvironment(srcProjectIdentifier, srcEnvIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	cloned, err := r.client.CloneEnvironment(r.ctx, env.ID, api.CloneEnvironmentRequest{
+		Name:        newName,
+		ProjectID:   dstProjectIdentifier,
+		CopySources: copySources,
+	})
+	if err != nil {
+		return nil, r.remoteError(err)
+	}
+	r.trackEnvironments(*cloned)
+	return cloned, nil
+}
+
+// 文件配置
+
+func (r *remoteBackend) GetFileConfig(projectID, environmentID, fileID string) (*internal.FileConfig, error) {
+	proj, err := r.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	for _, env := range proj.Environments {
+		if env.ID != environmentID {
+			continue
+		}
+		for _, fileConfig := range env.Files {
+			if fileConfig.ID == fileID {
+				return &fileConfig, nil
+			}
+		}
+		return nil, internal.NotFoundf("file config not found: %s", fileID)
+	}
+	return nil, internal.NotFoundf("environment not found: %s", environmentID)
+}
+
+func (r *remoteBackend) CreateFileConfig(_, environmentID string, fileConfig *internal.FileConfig, importSource bool) (*internal.FileConfig, error) {
+	created, err := r.client.AddFile(r.ctx, environmentID, api.AddFileRequest{
+		SourcePath:  fileConfig.SourcePath,
+		TargetPath:  fileConfig.TargetPath,
+		Description: fileConfig.Description,
+		Import:      importSource,
+		AllowEmpty:  fileConfig.AllowEmpty,
+	})
+	if err != nil {
+		return nil, r.remoteError(err)
+	}
+
+	r.fileEnvs[created.ID] = environmentID
+	delete(r.plans, environmentID)
+	return created, nil
+}
+
+func (r *remoteBackend) UpdateFileConfig(_, environmentID, fileID string, updates map[string]interface{}) (*internal.FileConfig, error) {
+	var request api.UpdateFileRequest
+	if sourcePath, ok := updates["source_path"].(string); ok {
+		request.SourcePath = &sourcePath
+	}
+	if targetPath, ok := updates["target_path"].(string); ok {
+		request.TargetPath = &targetPath
+	}
+	if description, ok := updates["description"].(string); ok {
+		request.Description = &description
+	}
+	if managed, ok := updates["managed"].(bool); ok {
+		request.Managed = &managed
+	}
+	if allowEmpty, ok := updates["allow_empty"].(bool); ok {
+		request.AllowEmpty = &allowEmpty
+	}
+
+	delete(r.plans, environmentID)
+	var updated *internal.FileConfig
+	err := r.confirmed(func(ctx context.Context) error {
+		var err error
+		updated, err = r.client.UpdateFile(ctx, fileID, request)
+		return err
+	})
+	return updated, err
+}
+
+func (r *remoteBackend) RemoveFileConfig(_, environmentID, fileID string) error {
+	delete(r.plans, environmentID)
+	return r.confirmed(func(ctx context.Context) error {
+		return r.client.DeleteFile(ctx, fileID)
+	})
+}
+
+// 切换
+
+func (r *remoteBackend) GetCurrentState() (*internal.AppState, error) {
+	status, err := r.client.Status(r.ctx)
+	if err != nil {
+		return nil, r.remoteError(err)
+	}
+	return &status.AppState, nil
+}
+
+func (r *remoteBackend) SwitchEnvironment(projectID, environmentID string) error {
+	return r.confirmed(func(ctx context.Context) error {
+		_, err := r.client.Switch(ctx, projectID, environmentID)
+		return err
+	})
+}
+
+func (r *remoteBackend) Rollback(backupID string) (*internal.RollbackResult, error) {
+	result, err := r.client.Rollback(r.ctx, backupID)
+	return result, r.remoteError(err)
+}
+
+// 路径解析
+
+func (r *remoteBackend) ResolveSourcePath(fileConfig *internal.FileConfig) string {
+	if !fileConfig.Managed {
+		return fileConfig.SourcePath
+	}
+	planned, err := r.plannedFile(fileConfig)
+	if err != nil || planned.SourcePath == "" {
+		return fileConfig.SourcePath
+	}
+	return planned.SourcePath
+}
+
+func (r *remoteBackend) ResolvePaths(_ *internal.Project, fileConfig *internal.FileConfig) (string, string, error) {
+	planned, err := r.plannedFile(fileConfig)
+	if err != nil {
+		return "", "", err
+	}
+	return planned.SourcePath, planned.TargetPath, planError(planned.ResolveError)
+}
+
+func (r *remoteBackend) ExpandFileConfig(_ *internal.Project, fileConfig *internal.FileConfig) ([]internal.FileMapping, error) {
+	planned, err := r.plannedFile(fileConfig)
+	if err != nil {
+		return nil, err
+	}
+	if planned.ExpandError != "" {
+		return nil, planError(planned.ExpandError)
+	}
+	return planned.Mappings, nil
+}
+
+func (r *remoteBackend) ResolveDotEnvPath(_ *internal.Project, env *internal.Environment) (string, error) {
+	plan, err := r.plan(env.ID)
+	if err != nil {
+		return "", err
+	}
+	return plan.DotEnvPath, planError(plan.DotEnvError)
+}
+
+func (r *remoteBackend) PreviewEnvironment(_ *internal.Project, env *internal.Environment) ([]file.FilePreview, error) {
+	plan, ok := r.previews[env.ID]
+	if !ok {
+		var err error
+		plan, err = r.client.EnvironmentPlan(r.ctx, env.ID, true)
+		if err != nil {
+			return nil, r.remoteError(err)
+		}
+		r.previews[env.ID] = plan
+	}
+	return plan.Preview, planError(plan.PreviewError)
+}
+
+func (r *remoteBackend) SourcesDir(_, envID string) string {
+	plan, err := r.plan(envID)
+	if err != nil {
+		return ""
+	}
+	return plan.SourcesDir
+}
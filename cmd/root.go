@@ -1,8 +1,7 @@
 package cmd
 
 import (
-	"fmt"
-	"os"
+	"github.com/zoyopei/envswitch/internal/config"
 
 	"github.com/spf13/cobra"
 )
@@ -15,34 +14,81 @@ It allows you to quickly switch between different environment configurations
 by replacing files in your system according to predefined configurations.
 
 Complete documentation is available at https://github.com/zoyopei/envswitch`,
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		if err := validateOutputFormat(cmd); err != nil {
+			return err
+		}
+
+		// 应用 --profile 指定的会话档案
+		profile, _ := cmd.Flags().GetString("profile")
+		if err := config.SetSessionProfile(profile); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+
+		// 设置了远程地址时通过 REST API 操作远程服务
+		if err := setupBackend(cmd); err != nil {
+			cmd.SilenceUsage = true
+			return err
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, _ []string) {
 		_ = cmd.Help()
 	},
 }
 
-// Execute 执行根命令
+// Execute 执行根命令，出错时输出错误信息，返回的错误可通过 ExitCode 转换为退出码
 func Execute() error {
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	if err == nil {
+		return nil
+	}
+
+	// 未归类的错误来自参数和标志解析
+	if ExitCode(err) == exitError {
+		err = &usageError{msg: err.Error()}
+	}
+	printError(err)
+	return err
 }
 
 func init() {
 	// 全局标志
 	rootCmd.PersistentFlags().StringP("config", "c", "", "config file (default is ./config.json or ~/.envswitch/config.json)")
 	rootCmd.PersistentFlags().BoolP("verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().String("profile", "", "configuration profile to use for this command (default is the active profile)")
+	rootCmd.PersistentFlags().StringP("output", "o", outputTable, "output format: table, json or yaml")
+	rootCmd.PersistentFlags().String("remote", "", "URL of a running envswitch server to operate on (default is $"+remoteEnvVar+" or the profile's remote)")
+	_ = rootCmd.RegisterFlagCompletionFunc("profile", completeProfileFlag)
+	_ = rootCmd.RegisterFlagCompletionFunc("output", cobra.FixedCompletions([]string{outputTable, outputJSON, outputYAML}, cobra.ShellCompDirectiveNoFileComp))
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return &usageError{msg: err.Error()}
+	})
 
 	// 添加子命令
 	rootCmd.AddCommand(projectCmd)
 	rootCmd.AddCommand(envCmd)
 	rootCmd.AddCommand(switchCmd)
+	rootCmd.AddCommand(uiCmd)
 	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(rollbackCmd)
 	rootCmd.AddCommand(serverCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(shellEnvCmd)
+	rootCmd.AddCommand(execCmd)
+	rootCmd.AddCommand(initShellCmd)
+	rootCmd.AddCommand(promptCmd)
+	rootCmd.AddCommand(autoSwitchCmd)
+	rootCmd.AddCommand(completionCmd)
 }
 
-// 通用函数
-func checkError(err error) {
-	if err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
-	}
+// detectProject 未指定项目时，从当前目录的 .envswitch 标记或默认项目确定项目
+func detectProject() string {
+	projectName, _, err := config.DetectProject()
+	checkError(err)
+	return projectName
 }
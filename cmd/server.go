@@ -2,80 +2,481 @@ package cmd
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/zoyopei/envswitch/internal"
 	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/daemon"
 	"github.com/zoyopei/envswitch/internal/web"
 
+	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// 后台服务启动和停止的等待时间
+const (
+	serverStartTimeout = 10 * time.Second
+	serverStopTimeout  = 15 * time.Second
+)
+
+// serverStatus server status 的输出
+type serverStatus struct {
+	Running    bool   `json:"running"`
+	PID        int    `json:"pid,omitempty"`
+	PidFile    string `json:"pid_file"`
+	LogFile    string `json:"log_file"`
+	OutputFile string `json:"output_file"`
+}
+
 var serverCmd = &cobra.Command{
 	Use:   "server",
 	Short: "Start the web server",
-	Long:  "Start the HTTP web server for managing environments through a web interface",
+	Long: `Start the HTTP web server for managing environments through a web interface.
+
+By default the server only listens on ` + config.DefaultWebBind + `; use --bind to accept remote connections,
+--tls-cert/--tls-key or --tls-self-signed to serve HTTPS, and --unix-socket to listen on a unix socket.
+The same settings can be stored with 'envswitch config set' (web_bind, web_tls_cert, web_tls_key,
+web_tls_self_signed, web_unix_socket, web_unix_socket_mode).
+
+--daemon starts the server in the background: it writes a pid file and a rotating log file
+under the data directory and can be managed with 'server stop|status|restart'.
+Startup errors and panics of the background server are written to logs/server.out, which is not rotated.
+--foreground is meant for service managers such as systemd: the server stays in the foreground,
+writes the pid file and reports readiness through $NOTIFY_SOCKET (Type=notify).
+When started by systemd socket activation the server uses the passed socket instead of --port.`,
 	Run: func(cmd *cobra.Command, _ []string) {
-		port, _ := cmd.Flags().GetInt("port")
-		daemon, _ := cmd.Flags().GetBool("daemon")
+		daemonMode, _ := cmd.Flags().GetBool("daemon")
+		foreground, _ := cmd.Flags().GetBool("foreground")
 
-		if port == 0 {
-			port = config.GetWebPort()
+		if daemonMode && foreground {
+			checkError(usageErrorf("--daemon and --foreground cannot be used together"))
 		}
 
-		// 创建web服务器
-		server := web.NewServer()
+		opts, err := getServerOptions(cmd)
+		checkError(err)
 
-		// 设置HTTP服务器
-		httpServer := &http.Server{
-			Addr:    ":" + strconv.Itoa(port),
-			Handler: server.SetupRoutes(),
+		// 后台服务进程由 --daemon 重新执行启动
+		if daemon.IsChild() {
+			opts.managed = true
+			checkError(runDaemonChild(opts))
+			return
 		}
+		if daemonMode {
+			startDaemon(cmd, false)
+			return
+		}
+
+		opts.managed = foreground
+		checkError(runServer(opts, os.Stdout))
+	},
+}
+
+var serverStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the background web server",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		pid, err := daemon.Stop(serverPidFile(), serverStopTimeout)
+		checkError(err)
 
-		if daemon {
-			fmt.Printf("Starting web server in daemon mode on port %d\n", port)
-			// 在实际生产环境中，这里应该实现真正的daemon模式
-			// 这里简化处理，仅在后台运行
-		} else {
-			fmt.Printf("Starting web server on http://localhost:%d\n", port)
-			fmt.Println("Press Ctrl+C to stop the server")
+		printResult(newServerStatus(false, 0), func() {
+			fmt.Printf("Server stopped (pid %d)\n", pid)
+		})
+	},
+}
+
+var serverStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show whether the background web server is running",
+	Long:  "Show whether the web server recorded in the pid file is running. Exits with code 3 when it is not running.",
+	Args:  cobra.NoArgs,
+	Run: func(_ *cobra.Command, _ []string) {
+		pid, running := daemon.Running(serverPidFile())
+		status := newServerStatus(running, pid)
+
+		printResult(status, func() {
+			if status.Running {
+				fmt.Printf("Server is running (pid %d)\n", status.PID)
+			} else {
+				fmt.Println("Server is not running")
+			}
+			fmt.Printf("Pid file: %s\n", status.PidFile)
+			fmt.Printf("Log file: %s\n", status.LogFile)
+			fmt.Printf("Output file: %s\n", status.OutputFile)
+		})
+
+		if !status.Running {
+			os.Exit(exitNotFound)
 		}
+	},
+}
 
-		// 启动服务器
-		go func() {
-			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				fmt.Printf("Failed to start server: %v\n", err)
-				os.Exit(1)
+var serverRestartCmd = &cobra.Command{
+	Use:   "restart",
+	Short: "Restart the background web server",
+	Long: `Stop the background web server if it is running and start it again with --daemon.
+Without listening flags the server is restarted with the flags of the last 'server --daemon' or 'server restart';
+flags given to restart replace the saved ones.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		_, err := getServerOptions(cmd)
+		checkError(err)
+
+		if pid, err := daemon.Stop(serverPidFile(), serverStopTimeout); err == nil {
+			if !structuredOutput() {
+				fmt.Printf("Server stopped (pid %d)\n", pid)
 			}
-		}()
+		} else if _, running := daemon.Running(serverPidFile()); running {
+			checkError(err)
+		}
 
-		// 等待中断信号
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+		startDaemon(cmd, true)
+	},
+}
+
+// serverOptions Web 服务的监听设置，命令行标志优先于配置文件
+type serverOptions struct {
+	port       int
+	bind       string
+	unixSocket string
+	socketMode os.FileMode
+	tlsCert    string
+	tlsKey     string
+	selfSigned bool
+	debug      bool
+	managed    bool // 写入 pid 文件并通知 systemd
+}
+
+// addServerFlags 添加 server 和 server restart 共用的监听标志
+func addServerFlags(flags *pflag.FlagSet) {
+	flags.IntP("port", "p", 0, "Port to run the server on (default from config)")
+	flags.String("bind", "", "Address to listen on, e.g. 0.0.0.0 for all interfaces (default from config or "+config.DefaultWebBind+")")
+	flags.String("tls-cert", "", "TLS certificate file, enables HTTPS together with --tls-key")
+	flags.String("tls-key", "", "TLS private key file")
+	flags.Bool("tls-self-signed", false, "Serve HTTPS with a self-signed certificate generated under the data directory")
+	flags.String("unix-socket", "", "Listen on a unix socket instead of a TCP port")
+	flags.String("unix-socket-mode", "", "File mode of the unix socket in octal (default from config or 0660)")
+	flags.Bool("debug", false, "Run gin in debug mode with route and request debugging output")
+}
 
-		<-c
+// getServerOptions 合并命令行标志和配置文件中的监听设置
+func getServerOptions(cmd *cobra.Command) (serverOptions, error) {
+	cfg := config.GetConfig()
+	flags := cmd.Flags()
 
-		fmt.Println("\nShutting down server...")
+	opts := serverOptions{
+		port:       config.GetWebPort(),
+		bind:       config.GetWebBind(),
+		unixSocket: cfg.WebUnixSocket,
+		tlsCert:    cfg.WebTLSCert,
+		tlsKey:     cfg.WebTLSKey,
+		selfSigned: cfg.WebTLSSelfSigned,
+	}
+	socketMode := cfg.WebUnixSocketMode
 
-		// 创建5秒超时的context
-		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer cancel()
+	if port, _ := flags.GetInt("port"); port != 0 {
+		opts.port = port
+	}
+	if flags.Changed("bind") {
+		opts.bind, _ = flags.GetString("bind")
+	}
+	if flags.Changed("unix-socket") {
+		opts.unixSocket, _ = flags.GetString("unix-socket")
+	}
+	if flags.Changed("unix-socket-mode") {
+		socketMode, _ = flags.GetString("unix-socket-mode")
+	}
+	if flags.Changed("tls-cert") || flags.Changed("tls-key") {
+		opts.tlsCert, _ = flags.GetString("tls-cert")
+		opts.tlsKey, _ = flags.GetString("tls-key")
+	}
+	if flags.Changed("tls-self-signed") {
+		opts.selfSigned, _ = flags.GetBool("tls-self-signed")
+	}
+	opts.debug, _ = flags.GetBool("debug")
 
-		// 优雅关闭服务器
-		if err := httpServer.Shutdown(ctx); err != nil {
-			fmt.Printf("Server forced to shutdown: %v\n", err)
-		} else {
-			fmt.Println("Server stopped gracefully")
+	var err error
+	if opts.socketMode, err = config.ParseSocketMode(socketMode); err != nil {
+		return opts, usageErrorf("%v", err)
+	}
+	if (opts.tlsCert == "") != (opts.tlsKey == "") {
+		return opts, usageErrorf("--tls-cert and --tls-key must be used together")
+	}
+	if opts.selfSigned && opts.tlsCert != "" {
+		return opts, usageErrorf("--tls-self-signed cannot be used with --tls-cert")
+	}
+	if opts.port <= 0 || opts.port > 65535 {
+		return opts, usageErrorf("invalid port %d", opts.port)
+	}
+	return opts, nil
+}
+
+// tlsEnabled 是否以 HTTPS 提供服务
+func (o serverOptions) tlsEnabled() bool {
+	return o.tlsCert != "" || o.selfSigned
+}
+
+// listen 创建监听套接字：systemd 套接字激活优先，其次是 Unix 套接字，最后是 TCP 地址
+// 返回的描述用于启动提示
+func (o serverOptions) listen() (net.Listener, string, error) {
+	listeners, err := daemon.Listeners()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(listeners) > 0 {
+		for _, extra := range listeners[1:] {
+			_ = extra.Close()
 		}
-	},
+		return listeners[0], fmt.Sprintf("socket %s passed by systemd", listeners[0].Addr()), nil
+	}
+
+	if o.unixSocket != "" {
+		// 删除上次未清理的套接字文件，不覆盖其他类型的文件
+		if info, err := os.Lstat(o.unixSocket); err == nil {
+			if info.Mode()&os.ModeSocket == 0 {
+				return nil, "", fmt.Errorf("%s exists and is not a socket", o.unixSocket)
+			}
+			_ = os.Remove(o.unixSocket)
+		}
+		listener, err := daemon.ListenUnix(o.unixSocket, o.socketMode)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to start server: %w", err)
+		}
+		return listener, fmt.Sprintf("unix socket %s (mode %04o)", o.unixSocket, o.socketMode), nil
+	}
+
+	address := net.JoinHostPort(o.bind, strconv.Itoa(o.port))
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to start server: %w", err)
+	}
+	scheme := "http"
+	if o.tlsEnabled() {
+		scheme = "https"
+	}
+	return listener, scheme + "://" + address, nil
+}
+
+// tlsConfig 加载证书，使用自签名证书时在数据目录下生成
+func (o serverOptions) tlsConfig() (*tls.Config, error) {
+	certFile, keyFile := o.tlsCert, o.tlsKey
+	if o.selfSigned {
+		var err error
+		certFile, keyFile, err = web.EnsureSelfSignedCert(filepath.Join(config.GetDataDir(), "tls"), web.SelfSignedHosts(o.bind))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// localAddress 判断监听地址是否只能从本机访问
+func localAddress(bind string) bool {
+	if bind == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(bind)
+	return ip != nil && ip.IsLoopback()
+}
+
+// serverPidFile 后台服务的 pid 文件，位于数据目录下
+func serverPidFile() string {
+	return filepath.Join(config.GetDataDir(), "server.pid")
+}
+
+// serverLogFile 后台服务的日志文件，位于数据目录下
+func serverLogFile() string {
+	return filepath.Join(config.GetDataDir(), "logs", "server.log")
+}
+
+// serverOutputFile 后台服务的标准输出和标准错误，不参与日志轮转
+func serverOutputFile() string {
+	return filepath.Join(config.GetDataDir(), "logs", "server.out")
+}
+
+// serverArgsFile 保存后台服务监听标志的文件，供 restart 复用
+func serverArgsFile() string {
+	return filepath.Join(config.GetDataDir(), "server.args")
+}
+
+// newServerStatus 后台服务的状态和相关文件路径
+func newServerStatus(running bool, pid int) serverStatus {
+	return serverStatus{
+		Running:    running,
+		PID:        pid,
+		PidFile:    serverPidFile(),
+		LogFile:    serverLogFile(),
+		OutputFile: serverOutputFile(),
+	}
+}
+
+// startDaemon 以 server --daemon 重新执行当前程序，传递命令行上设置的标志
+// 监听标志保存到数据目录，restart 未指定监听标志时复用上次保存的标志
+func startDaemon(cmd *cobra.Command, restart bool) {
+	args := []string{"server", "--daemon"}
+	// LocalFlags 和 InheritedFlags 返回的标志集合不记录已设置的标志，需要检查 Changed
+	cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed {
+			args = append(args, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+
+	var listenArgs []string
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Changed && flag.Name != "daemon" {
+			listenArgs = append(listenArgs, "--"+flag.Name+"="+flag.Value.String())
+		}
+	})
+	if restart && len(listenArgs) == 0 {
+		saved, err := daemon.ReadArgsFile(serverArgsFile())
+		if err != nil && !errors.Is(err, internal.ErrNotFound) {
+			checkError(err)
+		}
+		listenArgs = saved
+	}
+
+	pid, err := daemon.Start(append(args, listenArgs...), serverPidFile(), serverOutputFile(), serverStartTimeout)
+	checkError(err)
+	checkError(daemon.WriteArgsFile(serverArgsFile(), listenArgs))
+
+	printResult(newServerStatus(true, pid), func() {
+		fmt.Printf("Server started in the background (pid %d)\n", pid)
+		fmt.Printf("Log file: %s\n", serverLogFile())
+		fmt.Println("Stop it with 'envswitch server stop'")
+	})
+}
+
+// runDaemonChild 后台服务进程：服务器日志和 Gin 访问日志写入轮转的日志文件
+func runDaemonChild(opts serverOptions) error {
+	logger, err := daemon.OpenLog(serverLogFile(), daemon.DefaultLogMaxSize, daemon.DefaultLogMaxBackups)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = logger.Close() }()
+
+	gin.DefaultWriter = logger
+	gin.DefaultErrorWriter = logger
+	log.SetOutput(logger)
+
+	return runServer(opts, logger)
+}
+
+// runServer 启动服务器直到收到中断信号；managed 为 true 时写入 pid 文件并通知 systemd
+func runServer(opts serverOptions, out io.Writer) error {
+	var tlsConfig *tls.Config
+	if opts.tlsEnabled() {
+		var err error
+		if tlsConfig, err = opts.tlsConfig(); err != nil {
+			return err
+		}
+	}
+
+	listener, address, err := opts.listen()
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	if opts.managed {
+		if err := daemon.WritePidFile(serverPidFile()); err != nil {
+			_ = listener.Close()
+			return err
+		}
+		defer daemon.RemovePidFile(serverPidFile())
+	}
+
+	// 创建web服务器
+	server := web.NewServer()
+	server.SetDebug(opts.debug)
+
+	// 设置HTTP服务器
+	httpServer := &http.Server{
+		Handler: server.SetupRoutes(),
+	}
+
+	_, _ = fmt.Fprintf(out, "Starting web server on %s\n", address)
+	if !opts.managed {
+		_, _ = fmt.Fprintln(out, "Press Ctrl+C to stop the server")
+	}
+
+	if len(config.ListTokens()) == 0 {
+		_, _ = fmt.Fprintln(out, "Warning: no API tokens configured, the web server does not require authentication (create one with 'envswitch token create')")
+	}
+	if opts.unixSocket == "" && tlsConfig == nil && !localAddress(opts.bind) {
+		_, _ = fmt.Fprintf(out, "Warning: serving plain HTTP on %s, consider --tls-cert/--tls-key or --tls-self-signed\n", opts.bind)
+	}
+
+	// 启动服务器
+	serveErr := make(chan error, 1)
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			serveErr <- err
+		}
+	}()
+
+	if opts.managed {
+		if _, err := daemon.Notify("READY=1\nMAINPID=" + strconv.Itoa(os.Getpid())); err != nil {
+			_, _ = fmt.Fprintf(out, "Warning: %v\n", err)
+		}
+	}
+
+	// 等待中断信号
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case <-c:
+	case err := <-serveErr:
+		return fmt.Errorf("server failed: %w", err)
+	}
+
+	_, _ = fmt.Fprintln(out, "\nShutting down server...")
+	if opts.managed {
+		_, _ = daemon.Notify("STOPPING=1")
+	}
+
+	// 创建5秒超时的context
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 优雅关闭服务器
+	if err := httpServer.Shutdown(ctx); err != nil {
+		_, _ = fmt.Fprintf(out, "Server forced to shutdown: %v\n", err)
+	} else {
+		_, _ = fmt.Fprintln(out, "Server stopped gracefully")
+	}
+	return nil
 }
 
 func init() {
-	serverCmd.Flags().IntP("port", "p", 0, "Port to run the server on (default from config)")
-	serverCmd.Flags().BoolP("daemon", "d", false, "Run server in daemon mode")
+	addServerFlags(serverCmd.Flags())
+	serverCmd.Flags().BoolP("daemon", "d", false, "Run the server in the background with a pid file and log file under the data directory")
+	serverCmd.Flags().Bool("foreground", false, "Run in the foreground for a service manager: write the pid file and notify systemd when ready")
+
+	// server restart
+	addServerFlags(serverRestartCmd.Flags())
+
+	serverCmd.AddCommand(serverStopCmd)
+	serverCmd.AddCommand(serverStatusCmd)
+	serverCmd.AddCommand(serverRestartCmd)
 }
@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
+	"github.com/zoyopei/envswitch/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultPromptFormat prompt 默认输出格式
+const defaultPromptFormat = "{project}:{env}"
+
+var initShellCmd = &cobra.Command{
+	Use:   "init-shell <bash|zsh|fish>",
+	Short: "Print the shell integration script",
+	Long: `Print a script that integrates envswitch with your shell.
+The script adds the active project and environment to the prompt (disable with --prompt=false).
+With --auto-switch it also installs a cd hook that switches environments automatically when entering a directory
+whose .envswitch marker requests a specific environment (environment: <name>).`,
+	Example: `  # ~/.bashrc
+  eval "$(envswitch init-shell bash)"
+
+  # ~/.zshrc
+  eval "$(envswitch init-shell zsh --auto-switch)"
+
+  # ~/.config/fish/config.fish
+  envswitch init-shell fish | source`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish"},
+	Run: func(cmd *cobra.Command, args []string) {
+		withPrompt, _ := cmd.Flags().GetBool("prompt")
+		autoSwitch, _ := cmd.Flags().GetBool("auto-switch")
+
+		script, err := shellInitScript(args[0], withPrompt, autoSwitch)
+		checkError(err)
+
+		fmt.Print(script)
+	},
+}
+
+var promptCmd = &cobra.Command{
+	Use:   "prompt",
+	Short: "Print the active environment for use in a shell prompt",
+	Long: `Print the active project and environment using only the cached state file, so it is fast enough to run on every prompt.
+Placeholders in --format: {project} and {env}. Nothing is printed when no environment is active.`,
+	Example: `  envswitch prompt
+  envswitch prompt --format '[{env}] '`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		format, _ := cmd.Flags().GetString("format")
+
+		store := storage.NewStorage()
+		state, err := store.LoadAppState()
+		if err != nil || state.CurrentEnvironment == "" {
+			return
+		}
+
+		projectName, envName := state.ProjectName, state.EnvironmentName
+		if projectName == "" || envName == "" {
+			// 旧版本的状态文件没有缓存名称，仅加载当前项目
+			proj, err := store.LoadProject(state.CurrentProject)
+			if err != nil {
+				return
+			}
+			projectName = proj.Name
+			for _, env := range proj.Environments {
+				if env.ID == state.CurrentEnvironment {
+					envName = env.Name
+				}
+			}
+		}
+
+		fmt.Print(strings.NewReplacer("{project}", projectName, "{env}", envName).Replace(format))
+	},
+}
+
+var autoSwitchCmd = &cobra.Command{
+	Use:   "auto-switch",
+	Short: "Switch to the environment requested by the current directory's marker",
+	Long: `Switch to the environment named in the nearest .envswitch marker (environment: <name>), if it is not already active.
+This is called by the cd hook installed with 'envswitch init-shell --auto-switch'. Directories without a marker,
+or whose marker does not name an environment, are ignored. Protected environments are never switched to automatically.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, _ []string) {
+		quiet, _ := cmd.Flags().GetBool("quiet")
+
+		cwd, err := os.Getwd()
+		checkError(err)
+
+		marker, err := config.FindProjectMarker(cwd)
+		checkError(err)
+		if marker == nil || marker.Environment == "" {
+			return
+		}
+
+		fileManager := file.NewManager()
+		state, err := fileManager.GetCurrentState()
+		checkError(err)
+		if state.ProjectName == marker.Project && state.EnvironmentName == marker.Environment {
+			return
+		}
+
+		manager := project.NewManager()
+		proj, err := manager.GetProject(marker.Project)
+		checkError(err)
+
+		env, err := manager.GetEnvironment(proj.ID, marker.Environment)
+		checkError(err)
+
+		if state.CurrentProject == proj.ID && state.CurrentEnvironment == env.ID {
+			return
+		}
+
+		// 受保护的环境不会自动切换
+		if config.IsProtected(env) {
+			if !quiet {
+				_, _ = fmt.Fprintf(os.Stderr, "envswitch: environment '%s' in project '%s' is protected, run 'envswitch switch %s %s' to switch\n", env.Name, proj.Name, proj.Name, env.Name)
+			}
+			return
+		}
+
+		checkError(fileManager.SwitchEnvironment(proj.ID, env.ID))
+
+		if !quiet {
+			fmt.Printf("envswitch: switched to environment '%s' in project '%s' (from %s)\n", env.Name, proj.Name, marker.Path)
+		}
+	},
+}
+
+// shellInitScript 生成 shell 集成脚本
+func shellInitScript(shell string, withPrompt, autoSwitch bool) (string, error) {
+	var b strings.Builder
+
+	switch shell {
+	case "bash":
+		b.WriteString(`# envswitch shell integration for bash
+__envswitch_ps1() {
+    local format='({project}:{env}) '
+    [ -n "${1:-}" ] && format="$1"
+    command envswitch prompt --format "$format" 2>/dev/null
+}
+`)
+		if withPrompt {
+			b.WriteString(`case "$PS1" in
+    *__envswitch_ps1*) ;;
+    *) PS1='$(__envswitch_ps1)'"$PS1" ;;
+esac
+`)
+		}
+		if autoSwitch {
+			b.WriteString(`__envswitch_cd_hook() {
+    if [ "$PWD" != "${__envswitch_last_pwd:-}" ]; then
+        __envswitch_last_pwd="$PWD"
+        command envswitch auto-switch
+    fi
+}
+case ";${PROMPT_COMMAND:-};" in
+    *";__envswitch_cd_hook;"*) ;;
+    *) PROMPT_COMMAND="__envswitch_cd_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+esac
+`)
+		}
+
+	case "zsh":
+		b.WriteString(`# envswitch shell integration for zsh
+__envswitch_ps1() {
+    local format='({project}:{env}) '
+    [ -n "${1:-}" ] && format="$1"
+    command envswitch prompt --format "$format" 2>/dev/null
+}
+`)
+		if withPrompt {
+			b.WriteString(`setopt PROMPT_SUBST
+case "$PROMPT" in
+    *__envswitch_ps1*) ;;
+    *) PROMPT='$(__envswitch_ps1)'"$PROMPT" ;;
+esac
+`)
+		}
+		if autoSwitch {
+			b.WriteString(`__envswitch_cd_hook() {
+    command envswitch auto-switch
+}
+autoload -Uz add-zsh-hook
+add-zsh-hook chpwd __envswitch_cd_hook
+__envswitch_cd_hook
+`)
+		}
+
+	case "fish":
+		b.WriteString(`# envswitch shell integration for fish
+function __envswitch_prompt
+    set -l format '({project}:{env}) '
+    if set -q argv[1]
+        set format $argv[1]
+    end
+    command envswitch prompt --format $format 2>/dev/null
+end
+`)
+		if withPrompt {
+			b.WriteString(`if not functions -q __envswitch_original_fish_prompt
+    functions -c fish_prompt __envswitch_original_fish_prompt
+    function fish_prompt
+        __envswitch_prompt
+        __envswitch_original_fish_prompt
+    end
+end
+`)
+		}
+		if autoSwitch {
+			b.WriteString(`function __envswitch_cd_hook --on-variable PWD
+    command envswitch auto-switch
+end
+__envswitch_cd_hook
+`)
+		}
+
+	default:
+		return "", fmt.Errorf("unsupported shell '%s', expected one of: bash, zsh, fish", shell)
+	}
+
+	return b.String(), nil
+}
+
+func init() {
+	initShellCmd.Flags().Bool("prompt", true, "Add the active environment to the shell prompt")
+	initShellCmd.Flags().Bool("auto-switch", false, "Switch environments automatically when entering a directory with an .envswitch marker that names an environment")
+
+	promptCmd.Flags().String("format", defaultPromptFormat, "Output format, placeholders: {project}, {env}")
+
+	autoSwitchCmd.Flags().BoolP("quiet", "q", false, "Do not print a message after switching")
+}
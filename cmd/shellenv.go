@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
+
+	"github.com/spf13/cobra"
+)
+
+var shellEnvCmd = &cobra.Command{
+	Use:   "shell-env [project] <env-name>",
+	Short: "Print shell statements that export an environment's variables",
+	Long: `Print the variables declared on an environment as shell statements, so they can be loaded into the current shell with eval.
+If the project is omitted, it is detected from the nearest .envswitch marker or the default project.
+Supported shells: bash, zsh, fish and powershell (default detected from $SHELL).`,
+	Example: `  eval "$(envswitch shell-env dev)"
+  envswitch shell-env myproject dev --shell fish | source
+  envswitch shell-env myproject dev --shell powershell | Invoke-Expression`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: completeOptionalProjectEnv,
+	Run: func(cmd *cobra.Command, args []string) {
+		var projectName, envName string
+		if len(args) == 2 {
+			projectName, envName = args[0], args[1]
+		} else {
+			projectName, envName = detectProject(), args[0]
+		}
+
+		shell, _ := cmd.Flags().GetString("shell")
+		if shell == "" {
+			shell = file.DetectShell()
+		}
+
+		manager := project.NewManager()
+		proj, err := manager.GetProject(projectName)
+		checkError(err)
+
+		env, err := manager.GetEnvironment(proj.ID, envName)
+		checkError(err)
+
+		output, err := file.FormatShellEnv(shell, env.Variables)
+		checkError(err)
+
+		variables := env.Variables
+		if variables == nil {
+			variables = map[string]string{}
+		}
+		printResult(variables, func() {
+			fmt.Print(output)
+		})
+	},
+}
+
+func init() {
+	shellEnvCmd.Flags().String("shell", "", "Shell syntax: "+strings.Join(file.SupportedShells, ", ")+" (default detected from $SHELL)")
+	_ = shellEnvCmd.RegisterFlagCompletionFunc("shell", cobra.FixedCompletions(file.SupportedShells, cobra.ShellCompDirectiveNoFileComp))
+}
@@ -3,9 +3,8 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/zoyopei/envswitch/internal"
 	"github.com/zoyopei/envswitch/internal/config"
-	"github.com/zoyopei/envswitch/internal/file"
-	"github.com/zoyopei/envswitch/internal/project"
 
 	"github.com/spf13/cobra"
 )
@@ -13,29 +12,51 @@ import (
 var switchCmd = &cobra.Command{
 	Use:   "switch [project] <env-name>",
 	Short: "Switch to an environment",
-	Long:  "Switch to the specified environment, replacing files according to the configuration",
-	Args:  cobra.RangeArgs(1, 2),
+	Long: `Switch to the specified environment, replacing files according to the configuration.
+If the project is omitted, it is taken from the nearest .envswitch marker (or envswitch.yaml)
+in the current directory or its parents, falling back to the default project.
+With -i the environment is chosen in an interactive picker (optionally limited to the given project).
+Protected environments require --confirm=<env-name>, or typing the environment name when run in a terminal.`,
+	Example: `  envswitch switch myapp dev
+  envswitch switch dev
+  envswitch switch -i
+  envswitch switch myapp prod --confirm=prod`,
+	Args:              cobra.RangeArgs(0, 2),
+	ValidArgsFunction: completeOptionalProjectEnv,
 	Run: func(cmd *cobra.Command, args []string) {
-		var projectName, envName string
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		interactive, _ := cmd.Flags().GetBool("interactive")
+
+		if interactive {
+			if len(args) > 1 {
+				checkError(usageErrorf("switch -i accepts at most one argument: [project]"))
+			}
+			var projectName string
+			if len(args) == 1 {
+				projectName = args[0]
+			}
+			item := pickEnvironment(projectName)
+			runSwitch(cmd, item.Project, item.Environment, dryRun)
+			return
+		}
 
-		if len(args) == 1 {
-			// 使用默认项目
-			projectName = config.GetDefaultProject()
+		var projectName, envName string
+		switch len(args) {
+		case 0:
+			checkError(usageErrorf("please specify an environment: envswitch switch [project] <env-name>, or use -i to pick one interactively"))
+		case 1:
+			// 使用目录标记或默认项目
+			projectName = detectProject()
 			if projectName == "" {
-				fmt.Println("No default project set. Please specify project name.")
-				fmt.Println("Usage: envswitch switch <project> <env-name>")
-				return
+				checkError(usageErrorf("no %s marker found and no default project set, please specify project name: envswitch switch <project> <env-name>", config.MarkerFile))
 			}
 			envName = args[0]
-		} else {
+		default:
 			projectName = args[0]
 			envName = args[1]
 		}
 
-		dryRun, _ := cmd.Flags().GetBool("dry-run")
-
-		manager := project.NewManager()
-		fileManager := file.NewManager()
+		manager := getBackend()
 
 		// 获取项目和环境信息
 		proj, err := manager.GetProject(projectName)
@@ -44,103 +65,186 @@ var switchCmd = &cobra.Command{
 		env, err := manager.GetEnvironment(projectName, envName)
 		checkError(err)
 
-		if len(env.Files) == 0 {
-			fmt.Printf("Environment '%s' has no file configurations\n", envName)
-			return
+		runSwitch(cmd, proj, env, dryRun)
+	},
+}
+
+// runSwitch 切换到指定环境并输出结果，dryRun 时只输出将要切换的文件
+// 受保护的环境需要通过 --confirm 或输入环境名称确认
+func runSwitch(cmd *cobra.Command, proj *internal.Project, env *internal.Environment, dryRun bool) {
+	manager := getBackend()
+
+	if len(env.Files) == 0 {
+		checkError(usageErrorf("environment '%s' has no file configurations", env.Name))
+	}
+
+	result := &internal.SwitchResult{
+		Project:     proj.Name,
+		Environment: env.Name,
+		DryRun:      dryRun,
+		Files:       []internal.FileMapping{},
+	}
+	for _, fileConfig := range env.Files {
+		if mappings, err := manager.ExpandFileConfig(proj, &fileConfig); err == nil {
+			result.Files = append(result.Files, mappings...)
 		}
+	}
+	result.DotEnvPath, _ = manager.ResolveDotEnvPath(proj, env)
 
-		if dryRun {
-			fmt.Printf("Dry run: Would switch to environment '%s' in project '%s'\n", envName, projectName)
+	if dryRun {
+		printResult(result, func() {
+			fmt.Printf("Dry run: Would switch to environment '%s' in project '%s'\n", env.Name, proj.Name)
 			fmt.Printf("Files that would be switched:\n")
 			for _, fileConfig := range env.Files {
-				fmt.Printf("  %s -> %s\n", fileConfig.SourcePath, fileConfig.TargetPath)
+				for _, line := range describeFileMapping(manager, proj, &fileConfig) {
+					fmt.Printf("  %s\n", line)
+				}
 			}
-			return
-		}
+		})
+		return
+	}
 
-		fmt.Printf("Switching to environment '%s' in project '%s'...\n", envName, projectName)
+	confirmProtected(cmd, "switch to", env)
 
-		// 执行切换
-		err = fileManager.SwitchEnvironment(proj.ID, env.ID)
-		if err != nil {
-			fmt.Printf("Failed to switch environment: %v\n", err)
-			fmt.Println("You may need to run 'envswitch rollback' to restore previous state")
-			return
-		}
+	if !structuredOutput() {
+		fmt.Printf("Switching to environment '%s' in project '%s'...\n", env.Name, proj.Name)
+	}
+
+	// 执行切换
+	err := manager.SwitchEnvironment(proj.ID, env.ID)
+	if err != nil {
+		checkError(fmt.Errorf("failed to switch environment: %w; you may need to run 'envswitch rollback' to restore previous state", err))
+	}
 
-		fmt.Printf("Successfully switched to environment '%s'\n", envName)
+	state, err := manager.GetCurrentState()
+	checkError(err)
+	result.BackupID = state.BackupID
+	result.SwitchedAt = state.LastSwitchAt
+
+	printResult(result, func() {
+		fmt.Printf("Successfully switched to environment '%s'\n", env.Name)
 		fmt.Printf("Switched %d files\n", len(env.Files))
-	},
+	})
 }
 
 var statusCmd = &cobra.Command{
 	Use:   "status",
 	Short: "Show current environment status",
 	Run: func(_ *cobra.Command, _ []string) {
-		fileManager := file.NewManager()
-		projectManager := project.NewManager()
+		manager := getBackend()
 
-		state, err := fileManager.GetCurrentState()
+		state, err := manager.GetCurrentState()
 		checkError(err)
 
-		if state.CurrentProject == "" {
-			fmt.Println("No environment is currently active")
-			return
+		info := &internal.StatusInfo{
+			Profile:       config.GetActiveProfileName(),
+			Active:        state.CurrentProject != "",
+			ProjectID:     state.CurrentProject,
+			EnvironmentID: state.CurrentEnvironment,
+			LastSwitchAt:  state.LastSwitchAt,
+			BackupID:      state.BackupID,
 		}
 
-		// 获取项目和环境信息
-		proj, err := projectManager.GetProject(state.CurrentProject)
-		if err != nil {
-			fmt.Printf("Warning: Could not load current project: %v\n", err)
-			fmt.Printf("Current project ID: %s\n", state.CurrentProject)
-			fmt.Printf("Current environment ID: %s\n", state.CurrentEnvironment)
-			return
+		// 当前目录对应的项目
+		detected, marker, detectErr := config.DetectProject()
+		if detectErr == nil {
+			info.DetectedProject = detected
+			if marker != nil {
+				info.MarkerPath = marker.Path
+			}
 		}
 
-		env, err := projectManager.GetEnvironment(proj.ID, state.CurrentEnvironment)
-		if err != nil {
-			fmt.Printf("Warning: Could not load current environment: %v\n", err)
-			fmt.Printf("Current project: %s\n", proj.Name)
-			fmt.Printf("Current environment ID: %s\n", state.CurrentEnvironment)
-			return
+		// 获取项目和环境信息
+		var proj *internal.Project
+		var env *internal.Environment
+		var loadErr error
+		if info.Active {
+			proj, loadErr = manager.GetProject(state.CurrentProject)
+			if loadErr == nil {
+				info.Project = proj.Name
+				env, loadErr = manager.GetEnvironment(proj.ID, state.CurrentEnvironment)
+			}
+			if loadErr == nil {
+				info.Environment = env.Name
+				for _, fileConfig := range env.Files {
+					if mappings, err := manager.ExpandFileConfig(proj, &fileConfig); err == nil {
+						info.Files = append(info.Files, mappings...)
+					}
+				}
+			}
 		}
 
-		fmt.Printf("Current Status:\n")
-		fmt.Printf("Project: %s\n", proj.Name)
-		fmt.Printf("Environment: %s\n", env.Name)
+		printResult(info, func() {
+			fmt.Printf("Profile: %s\n", info.Profile)
 
-		if state.LastSwitchAt != nil {
-			fmt.Printf("Last Switch: %s\n", state.LastSwitchAt.Format("2006-01-02 15:04:05"))
-		}
+			switch {
+			case detectErr != nil:
+				fmt.Printf("Warning: %v\n", detectErr)
+			case marker != nil:
+				fmt.Printf("Detected Project: %s (from %s)\n", detected, marker.Path)
+			case detected != "":
+				fmt.Printf("Detected Project: %s (default project)\n", detected)
+			}
 
-		if state.BackupID != "" {
-			fmt.Printf("Backup ID: %s\n", state.BackupID)
-		}
+			if !info.Active {
+				fmt.Println("No environment is currently active")
+				return
+			}
 
-		fmt.Printf("Active Files: %d\n", len(env.Files))
+			if info.Project == "" {
+				fmt.Printf("Warning: Could not load current project: %v\n", loadErr)
+				fmt.Printf("Current project ID: %s\n", state.CurrentProject)
+				fmt.Printf("Current environment ID: %s\n", state.CurrentEnvironment)
+				return
+			}
 
-		if len(env.Files) > 0 {
-			fmt.Println("\nActive file configurations:")
-			for _, fileConfig := range env.Files {
-				fmt.Printf("  %s -> %s\n", fileConfig.SourcePath, fileConfig.TargetPath)
+			if info.Environment == "" {
+				fmt.Printf("Warning: Could not load current environment: %v\n", loadErr)
+				fmt.Printf("Current project: %s\n", proj.Name)
+				fmt.Printf("Current environment ID: %s\n", state.CurrentEnvironment)
+				return
 			}
-		}
+
+			fmt.Printf("Current Status:\n")
+			fmt.Printf("Project: %s\n", proj.Name)
+			fmt.Printf("Environment: %s\n", env.Name)
+
+			if state.LastSwitchAt != nil {
+				fmt.Printf("Last Switch: %s\n", state.LastSwitchAt.Format("2006-01-02 15:04:05"))
+			}
+
+			if state.BackupID != "" {
+				fmt.Printf("Backup ID: %s\n", state.BackupID)
+			}
+
+			fmt.Printf("Active Files: %d\n", len(env.Files))
+
+			if len(env.Files) > 0 {
+				fmt.Println("\nActive file configurations:")
+				for _, fileConfig := range env.Files {
+					for _, line := range describeFileMapping(manager, proj, &fileConfig) {
+						fmt.Printf("  %s\n", line)
+					}
+				}
+			}
+		})
 	},
 }
 
 var rollbackCmd = &cobra.Command{
-	Use:   "rollback [backup-id]",
-	Short: "Rollback to previous state",
-	Args:  cobra.MaximumNArgs(1),
+	Use:               "rollback [backup-id]",
+	Short:             "Rollback to previous state",
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeBackupIDs,
 	Run: func(cmd *cobra.Command, args []string) {
-		fileManager := file.NewManager()
+		manager := getBackend()
 
 		var backupID string
 		if len(args) > 0 {
 			backupID = args[0]
 		} else {
 			// 使用当前状态中的备份ID
-			state, err := fileManager.GetCurrentState()
+			state, err := manager.GetCurrentState()
 			checkError(err)
 
 			if state.BackupID == "" {
@@ -154,29 +258,50 @@ var rollbackCmd = &cobra.Command{
 		force, _ := cmd.Flags().GetBool("force")
 
 		if !force {
-			fmt.Printf("Are you sure you want to rollback to backup '%s'?\n", backupID)
-			fmt.Print("Type 'yes' to confirm: ")
-			var confirmation string
-			_, _ = fmt.Scanln(&confirmation)
-			if confirmation != "yes" {
-				fmt.Println("Operation cancelled")
-				return
-			}
+			confirm(fmt.Sprintf("Are you sure you want to rollback to backup '%s'?", backupID))
 		}
 
-		fmt.Printf("Rolling back to backup '%s'...\n", backupID)
+		if !structuredOutput() {
+			fmt.Printf("Rolling back to backup '%s'...\n", backupID)
+		}
 
-		err := fileManager.RollbackFromBackup(backupID)
+		result, err := manager.Rollback(backupID)
 		checkError(err)
 
-		fmt.Println("Rollback completed successfully")
+		printResult(result, func() {
+			fmt.Println("Rollback completed successfully")
+		})
 	},
 }
 
 func init() {
 	// switch flags
 	switchCmd.Flags().BoolP("dry-run", "n", false, "Show what would be done without actually doing it")
+	switchCmd.Flags().BoolP("interactive", "i", false, "Pick the environment in an interactive terminal picker")
+	switchCmd.Flags().String("confirm", "", "Confirm switching to a protected environment by repeating its name")
 
 	// rollback flags
 	rollbackCmd.Flags().BoolP("force", "f", false, "Force rollback without confirmation")
 }
+
+// describeFileMapping 以"源 -> 目标"的形式描述文件映射，使用解析后的路径，通配符配置列出全部匹配文件
+func describeFileMapping(manager backend, proj *internal.Project, fileConfig *internal.FileConfig) []string {
+	mappings, err := manager.ExpandFileConfig(proj, fileConfig)
+	if err != nil {
+		return []string{fmt.Sprintf("%s -> %s (error: %v)", fileConfig.SourcePath, fileConfig.TargetPath, err)}
+	}
+
+	var lines []string
+	if isGlobFileConfig(fileConfig) {
+		lines = append(lines, fmt.Sprintf("%s -> %s/ (%d files)", fileConfig.SourcePath, fileConfig.TargetPath, len(mappings)))
+		for _, mapping := range mappings {
+			lines = append(lines, fmt.Sprintf("  %s -> %s", mapping.SourcePath, mapping.TargetPath))
+		}
+		return lines
+	}
+
+	for _, mapping := range mappings {
+		lines = append(lines, fmt.Sprintf("%s -> %s", mapping.SourcePath, mapping.TargetPath))
+	}
+	return lines
+}
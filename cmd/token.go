@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/project"
+
+	"github.com/spf13/cobra"
+)
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Manage API tokens for the web server",
+	Long: `Manage API tokens used to authenticate to the web server. Once at least one token exists the
+server requires authentication: API clients send "Authorization: Bearer <token>", browsers log in with a token.
+Only a SHA-256 hash of each token is stored in the configuration.
+
+Roles:
+  viewer   - read-only access
+  operator - read access, switch environments and roll back
+  admin    - full access, including creating, editing and deleting projects, environments and files`,
+}
+
+var tokenCreateCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "Create a new API token",
+	Example: `  envswitch token create ci --role operator --project myapp
+  envswitch token create laptop --role admin --expires 30d`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		role, _ := cmd.Flags().GetString("role")
+		projectNames, _ := cmd.Flags().GetStringSlice("project")
+		expires, _ := cmd.Flags().GetString("expires")
+
+		if !slices.Contains(config.Roles, role) {
+			checkError(usageErrorf("invalid role '%s', expected one of: %s", role, strings.Join(config.Roles, ", ")))
+		}
+		ttl, err := parseTTL(expires)
+		checkError(err)
+
+		// 项目按名称保存，创建时校验项目存在
+		var projects []string
+		if len(projectNames) > 0 {
+			manager := project.NewManager()
+			for _, name := range projectNames {
+				proj, err := manager.GetProject(name)
+				checkError(err)
+				projects = append(projects, proj.Name)
+			}
+		}
+
+		token, secret, err := config.CreateToken(args[0], role, projects, ttl)
+		checkError(err)
+
+		result := struct {
+			internal.APIToken
+			Token string `json:"token"`
+		}{config.RedactTokens([]internal.APIToken{*token})[0], secret}
+
+		printResult(result, func() {
+			fmt.Printf("Token '%s' created (role: %s, projects: %s)\n", token.Name, token.Role, tokenProjects(token))
+			if token.ExpiresAt != nil {
+				fmt.Printf("Expires: %s\n", token.ExpiresAt.Format("2006-01-02 15:04:05"))
+			}
+			fmt.Printf("\n%s\n\n", secret)
+			fmt.Println("Store this token now, it cannot be shown again.")
+		})
+	},
+}
+
+var tokenListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API tokens",
+	Run: func(_ *cobra.Command, _ []string) {
+		tokens := config.ListTokens()
+		if tokens == nil {
+			tokens = []internal.APIToken{}
+		}
+
+		printResult(config.RedactTokens(tokens), func() {
+			if len(tokens) == 0 {
+				fmt.Println("No API tokens found, the web server does not require authentication")
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tNAME\tROLE\tPROJECTS\tCREATED\tEXPIRES")
+			for _, token := range tokens {
+				expires := "Never"
+				if token.ExpiresAt != nil {
+					expires = token.ExpiresAt.Format("2006-01-02 15:04")
+					if config.TokenExpired(&token) {
+						expires += " (expired)"
+					}
+				}
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+					token.ID,
+					token.Name,
+					token.Role,
+					tokenProjects(&token),
+					token.CreatedAt.Format("2006-01-02 15:04"),
+					expires,
+				)
+			}
+			_ = w.Flush()
+		})
+	},
+}
+
+var tokenRevokeCmd = &cobra.Command{
+	Use:               "revoke <id-or-name>",
+	Short:             "Revoke an API token",
+	Long:              "Revoke an API token. Requests and browser sessions using the token are rejected immediately.",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: completeTokens,
+	Run: func(_ *cobra.Command, args []string) {
+		token, err := config.RevokeToken(args[0])
+		checkError(err)
+
+		printResult(config.RedactTokens([]internal.APIToken{*token})[0], func() {
+			fmt.Printf("Token '%s' revoked\n", token.Name)
+		})
+	},
+}
+
+// tokenProjects 令牌可访问的项目
+func tokenProjects(token *internal.APIToken) string {
+	if len(token.Projects) == 0 {
+		return "all"
+	}
+	return strings.Join(token.Projects, ", ")
+}
+
+// parseTTL 解析有效期，支持 Go 时间格式（如 12h）和天数（如 30d），为空表示永不过期
+func parseTTL(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if ttl, err := time.ParseDuration(value); err == nil && ttl > 0 {
+		return ttl, nil
+	}
+	return 0, usageErrorf("invalid --expires '%s', expected a duration such as 12h or 30d", value)
+}
+
+func init() {
+	// token create
+	tokenCreateCmd.Flags().String("role", config.RoleViewer, "Role of the token: viewer, operator or admin")
+	_ = tokenCreateCmd.RegisterFlagCompletionFunc("role", cobra.FixedCompletions(config.Roles, cobra.ShellCompDirectiveNoFileComp))
+	tokenCreateCmd.Flags().StringSlice("project", nil, "Limit the token to these projects (repeatable, default all projects)")
+	_ = tokenCreateCmd.RegisterFlagCompletionFunc("project", completeProjectFlag)
+	tokenCreateCmd.Flags().String("expires", "", "Expire the token after this duration, e.g. 12h or 30d (default never)")
+
+	// 添加子命令
+	tokenCmd.AddCommand(tokenCreateCmd)
+	tokenCmd.AddCommand(tokenListCmd)
+	tokenCmd.AddCommand(tokenRevokeCmd)
+}
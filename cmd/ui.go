@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/tui"
+
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui [project]",
+	Short: "Pick and switch environments in an interactive terminal UI",
+	Long: `Open a full-screen picker listing all environments (or only those of the given project) with their tags,
+last switch time and the current environment marked with '*'. Type to fuzzy-filter by project, environment and tags.
+The preview pane shows the diff each target file would receive. Press Enter to switch, Esc to quit.
+Switching to a protected environment asks for its name to be typed after the picker closes.
+This is the same as 'envswitch switch -i'.`,
+	Args:              cobra.MaximumNArgs(1),
+	ValidArgsFunction: completeProjects,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		var projectName string
+		if len(args) == 1 {
+			projectName = args[0]
+		}
+
+		item := pickEnvironment(projectName)
+		runSwitch(cmd, item.Project, item.Environment, dryRun)
+	},
+}
+
+// pickEnvironment 打开交互式选择器选择环境，用户取消时以取消退出码退出
+func pickEnvironment(projectName string) *tui.Item {
+	manager := getBackend()
+
+	var projects []internal.Project
+	if projectName != "" {
+		proj, err := manager.GetProject(projectName)
+		checkError(err)
+		projects = []internal.Project{*proj}
+	} else {
+		var err error
+		projects, err = manager.ListProjects()
+		checkError(err)
+		sort.SliceStable(projects, func(i, j int) bool {
+			return projects[i].Name < projects[j].Name
+		})
+	}
+
+	state, err := manager.GetCurrentState()
+	checkError(err)
+
+	var items []tui.Item
+	for i := range projects {
+		proj := &projects[i]
+		for j := range proj.Environments {
+			env := &proj.Environments[j]
+			items = append(items, tui.Item{
+				Project:     proj,
+				Environment: env,
+				Current:     state.CurrentProject == proj.ID && state.CurrentEnvironment == env.ID,
+				Protected:   config.IsProtected(env),
+			})
+		}
+	}
+	if len(items) == 0 {
+		checkError(internal.NotFoundf("no environments found"))
+	}
+
+	picker := tui.NewPicker(items, func(item tui.Item) []string {
+		return previewEnvironment(manager, item)
+	})
+	selected, err := picker.Run(os.Stdin, os.Stdout)
+	if errors.Is(err, tui.ErrNotTerminal) {
+		checkError(usageErrorf("%v, specify the environment instead: envswitch switch [project] <env-name>", err))
+	}
+	checkError(err)
+
+	if selected == nil {
+		_, _ = fmt.Fprintln(os.Stderr, "Operation cancelled")
+		os.Exit(exitCancelled)
+	}
+	return selected
+}
+
+// previewEnvironment 生成选择器的预览内容：环境信息以及切换后各目标文件的 diff
+func previewEnvironment(manager backend, item tui.Item) []string {
+	env := item.Environment
+	lines := []string{item.Title()}
+
+	if env.Description != "" {
+		lines = append(lines, env.Description)
+	}
+	if len(env.Tags) > 0 {
+		lines = append(lines, "Tags: "+strings.Join(env.Tags, ", "))
+	}
+	if env.LastSwitchAt != nil {
+		lines = append(lines, "Last Switch: "+env.LastSwitchAt.Format("2006-01-02 15:04:05"))
+	} else {
+		lines = append(lines, "Last Switch: Never")
+	}
+	if item.Current {
+		lines = append(lines, "Currently active")
+	}
+	if reason := config.ProtectionReason(env); reason != "" {
+		lines = append(lines, "! Protected ("+reason+"), switching requires typing the environment name")
+	}
+	lines = append(lines, "")
+
+	if len(env.Files) == 0 {
+		return append(lines, "No file configurations")
+	}
+
+	previews, err := manager.PreviewEnvironment(item.Project, env)
+	if err != nil {
+		return append(lines, fmt.Sprintf("! %v", err))
+	}
+
+	counts := make(map[string]int)
+	for _, preview := range previews {
+		counts[preview.Status]++
+	}
+	var summary []string
+	for _, status := range []string{file.PreviewModified, file.PreviewCreated, file.PreviewUnchanged, file.PreviewMissingSource} {
+		if counts[status] > 0 {
+			summary = append(summary, fmt.Sprintf("%d %s", counts[status], status))
+		}
+	}
+	lines = append(lines, fmt.Sprintf("Files: %s", strings.Join(summary, ", ")), "")
+
+	for _, preview := range previews {
+		source := preview.SourcePath
+		if source == "" {
+			source = ".env variables"
+		}
+
+		switch preview.Status {
+		case file.PreviewUnchanged:
+			lines = append(lines, "= "+preview.TargetPath+" (unchanged)")
+			continue
+		case file.PreviewMissingSource:
+			lines = append(lines, "! "+preview.TargetPath+" (source missing: "+source+")")
+			continue
+		case file.PreviewCreated:
+			lines = append(lines, "A "+preview.TargetPath+" (new file)")
+		default:
+			lines = append(lines, "M "+preview.TargetPath)
+		}
+		lines = append(lines, "  <- "+source)
+
+		if preview.Binary {
+			lines = append(lines, "  binary file differs")
+		} else {
+			lines = append(lines, preview.Diff...)
+		}
+		lines = append(lines, "")
+	}
+
+	return lines
+}
+
+func init() {
+	uiCmd.Flags().BoolP("dry-run", "n", false, "Show what would be done for the picked environment without switching")
+}
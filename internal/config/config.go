@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/events"
 )
 
 const (
@@ -90,12 +91,21 @@ func SaveConfig(config *internal.Config) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
+	// 配置中包含令牌哈希和远程服务令牌，只允许当前用户读写；已存在的文件同样收紧权限
+	if err := os.WriteFile(configPath, data, 0600); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	if err := os.Chmod(configPath, 0600); err != nil {
+		return fmt.Errorf("failed to set config file permissions: %w", err)
+	}
 
 	globalConfig = config
-	return ensureDirectories(config)
+	if err := ensureDirectories(config); err != nil {
+		return err
+	}
+
+	events.Publish(events.Event{Type: events.ConfigUpdated})
+	return nil
 }
 
 // GetConfig 获取当前配置
@@ -129,32 +139,45 @@ func GetConfig() *internal.Config {
 func UpdateConfig(updates map[string]interface{}) error {
 	config := GetConfig()
 
+	// 先校验，避免部分更新
+	if socketMode, ok := updates["web_unix_socket_mode"].(string); ok {
+		if _, err := ParseSocketMode(socketMode); err != nil {
+			return err
+		}
+	}
+
+	// 数据目录、备份目录、端口和默认项目属于档案：使用命名档案时更新该档案
+	dataDir, backupDir, webPort, defaultProject := &config.DataDir, &config.BackupDir, &config.WebPort, &config.DefaultProject
+	if profile := activeProfile(); profile != nil {
+		dataDir, backupDir, webPort, defaultProject = &profile.DataDir, &profile.BackupDir, &profile.WebPort, &profile.DefaultProject
+	}
+
 	// 检查是否尝试更新 data_dir
 	if newDataDir, ok := updates["data_dir"]; ok {
-		if dir, ok := newDataDir.(string); ok && dir != config.DataDir {
+		if dir, ok := newDataDir.(string); ok && dir != *dataDir {
 			// 检测到数据目录变更，进行安全检查
-			if err := handleDataDirChange(config, dir); err != nil {
+			if err := handleDataDirChange(config, dataDir, dir); err != nil {
 				return err
 			}
 		}
 	}
 
 	// 其他配置更新
-	if backupDir, ok := updates["backup_dir"]; ok {
-		if dir, ok := backupDir.(string); ok {
-			config.BackupDir = dir
+	if value, ok := updates["backup_dir"]; ok {
+		if dir, ok := value.(string); ok {
+			*backupDir = dir
 		}
 	}
 
-	if webPort, ok := updates["web_port"]; ok {
-		if port, ok := webPort.(int); ok {
-			config.WebPort = port
+	if value, ok := updates["web_port"]; ok {
+		if port, ok := value.(int); ok {
+			*webPort = port
 		}
 	}
 
-	if defaultProject, ok := updates["default_project"]; ok {
-		if proj, ok := defaultProject.(string); ok {
-			config.DefaultProject = proj
+	if value, ok := updates["default_project"]; ok {
+		if proj, ok := value.(string); ok {
+			*defaultProject = proj
 		}
 	}
 
@@ -164,19 +187,45 @@ func UpdateConfig(updates map[string]interface{}) error {
 		}
 	}
 
+	if protectedTags, ok := updates["protected_tags"]; ok {
+		if tags, ok := protectedTags.([]string); ok {
+			config.ProtectedTags = tags
+		}
+	}
+
+	// Web 服务的监听设置
+	for key, field := range map[string]*string{
+		"web_bind":        &config.WebBind,
+		"web_tls_cert":    &config.WebTLSCert,
+		"web_tls_key":     &config.WebTLSKey,
+		"web_unix_socket": &config.WebUnixSocket,
+	} {
+		if value, ok := updates[key].(string); ok {
+			*field = value
+		}
+	}
+
+	if selfSigned, ok := updates["web_tls_self_signed"].(bool); ok {
+		config.WebTLSSelfSigned = selfSigned
+	}
+
+	if socketMode, ok := updates["web_unix_socket_mode"].(string); ok {
+		config.WebUnixSocketMode = socketMode
+	}
+
 	return SaveConfig(config)
 }
 
-// handleDataDirChange 处理数据目录变更
-func handleDataDirChange(config *internal.Config, newDataDir string) error {
+// handleDataDirChange 处理数据目录变更，dataDir 指向顶层配置或当前档案的数据目录
+func handleDataDirChange(config *internal.Config, dataDir *string, newDataDir string) error {
 	// 检查是否启用了数据目录检查
 	if !config.EnableDataDirCheck {
 		fmt.Println("⚠️  警告: 数据目录检查已禁用，直接更新数据目录路径")
-		config.DataDir = newDataDir
+		*dataDir = newDataDir
 		return nil
 	}
 
-	currentDataDir := config.DataDir
+	currentDataDir := *dataDir
 
 	// 检查当前数据目录是否存在且包含数据
 	hasData, err := CheckDataDirHasData(currentDataDir)
@@ -187,8 +236,7 @@ func handleDataDirChange(config *internal.Config, newDataDir string) error {
 	// 如果当前数据目录没有数据，直接更新
 	if !hasData {
 		fmt.Printf("✅ 当前数据目录 '%s' 为空，安全更新到 '%s'\n", currentDataDir, newDataDir)
-		config.DataDir = newDataDir
-		updateDataDirHistory(config, currentDataDir)
+		setDataDir(config, dataDir, currentDataDir, newDataDir)
 		return nil
 	}
 
@@ -214,9 +262,9 @@ func handleDataDirChange(config *internal.Config, newDataDir string) error {
 	case "1":
 		return fmt.Errorf("用户取消了数据目录更改")
 	case "2":
-		return migrateDataDir(config, currentDataDir, newDataDir)
+		return migrateDataDir(config, dataDir, currentDataDir, newDataDir)
 	case "3":
-		return forceUpdateDataDir(config, currentDataDir, newDataDir)
+		return forceUpdateDataDir(config, dataDir, currentDataDir, newDataDir)
 	default:
 		return fmt.Errorf("无效的选择，操作已取消")
 	}
@@ -247,6 +295,14 @@ func CheckDataDirHasData(dataDir string) (bool, error) {
 	return false, nil
 }
 
+// setDataDir 更新数据目录，顶层配置的数据目录变更记录到历史中
+func setDataDir(config *internal.Config, dataDir *string, oldDataDir, newDataDir string) {
+	*dataDir = newDataDir
+	if dataDir == &config.DataDir {
+		updateDataDirHistory(config, oldDataDir)
+	}
+}
+
 // updateDataDirHistory 更新数据目录历史
 func updateDataDirHistory(config *internal.Config, oldDataDir string) {
 	// 设置原始数据目录（如果还没有设置）
@@ -270,7 +326,7 @@ func updateDataDirHistory(config *internal.Config, oldDataDir string) {
 }
 
 // migrateDataDir 迁移数据目录
-func migrateDataDir(config *internal.Config, oldDataDir, newDataDir string) error {
+func migrateDataDir(config *internal.Config, dataDir *string, oldDataDir, newDataDir string) error {
 	fmt.Printf("\n🔄 开始迁移数据从 '%s' 到 '%s'...\n", oldDataDir, newDataDir)
 
 	// 创建新数据目录
@@ -307,8 +363,7 @@ func migrateDataDir(config *internal.Config, oldDataDir, newDataDir string) erro
 	}
 
 	// 更新配置
-	config.DataDir = newDataDir
-	updateDataDirHistory(config, oldDataDir)
+	setDataDir(config, dataDir, oldDataDir, newDataDir)
 
 	fmt.Printf("✅ 数据迁移完成!\n")
 	fmt.Printf("   原数据备份: %s\n", backupDir)
@@ -319,7 +374,7 @@ func migrateDataDir(config *internal.Config, oldDataDir, newDataDir string) erro
 }
 
 // forceUpdateDataDir 强制更新数据目录
-func forceUpdateDataDir(config *internal.Config, oldDataDir, newDataDir string) error {
+func forceUpdateDataDir(config *internal.Config, dataDir *string, oldDataDir, newDataDir string) error {
 	confirm, err := promptUser("\n⚠️  确认强制更改数据目录? 这将导致当前数据无法访问 (输入 'CONFIRM' 确认): ")
 	if err != nil {
 		return err
@@ -330,8 +385,7 @@ func forceUpdateDataDir(config *internal.Config, oldDataDir, newDataDir string)
 	}
 
 	// 更新配置
-	config.DataDir = newDataDir
-	updateDataDirHistory(config, oldDataDir)
+	setDataDir(config, dataDir, oldDataDir, newDataDir)
 
 	fmt.Printf("⚠️  数据目录已强制更改为: %s\n", newDataDir)
 	fmt.Printf("💡 原数据目录 '%s' 的数据仍然存在，可以手动恢复\n", oldDataDir)
@@ -445,6 +499,13 @@ func ensureDirectories(config *internal.Config) error {
 		filepath.Join(config.DataDir, "projects"),
 	}
 
+	// 当前档案的目录也需要存在
+	for _, profile := range config.Profiles {
+		if profile.Name == config.ActiveProfile || profile.Name == sessionProfile {
+			dirs = append(dirs, profile.DataDir, profile.BackupDir, filepath.Join(profile.DataDir, "projects"))
+		}
+	}
+
 	for _, dir := range dirs {
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return fmt.Errorf("failed to create directory %s: %w", dir, err)
@@ -456,25 +517,37 @@ func ensureDirectories(config *internal.Config) error {
 
 // GetDataDir 获取数据目录路径
 func GetDataDir() string {
+	if profile := activeProfile(); profile != nil && profile.DataDir != "" {
+		return profile.DataDir
+	}
 	return GetConfig().DataDir
 }
 
 // GetBackupDir 获取备份目录路径
 func GetBackupDir() string {
+	if profile := activeProfile(); profile != nil && profile.BackupDir != "" {
+		return profile.BackupDir
+	}
 	return GetConfig().BackupDir
 }
 
 // GetWebPort 获取Web端口
 func GetWebPort() int {
+	if profile := activeProfile(); profile != nil && profile.WebPort != 0 {
+		return profile.WebPort
+	}
 	return GetConfig().WebPort
 }
 
 // GetDefaultProject 获取默认项目
 func GetDefaultProject() string {
+	if profile := activeProfile(); profile != nil {
+		return profile.DefaultProject
+	}
 	return GetConfig().DefaultProject
 }
 
-// SetDefaultProject 设置默认项目
+// SetDefaultProject 设置默认项目（作用于当前档案）
 func SetDefaultProject(projectName string) error {
 	return UpdateConfig(map[string]interface{}{
 		"default_project": projectName,
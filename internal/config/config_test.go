@@ -2,7 +2,11 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/zoyopei/envswitch/internal"
 )
@@ -80,11 +84,21 @@ func TestLoadAndSaveConfig(t *testing.T) {
 		DefaultProject: "test_project",
 	}
 
+	// 配置文件已存在且权限较宽时，保存后只允许当前用户读写
+	if err := os.WriteFile(DefaultConfigFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
 	// 保存配置
 	err := SaveConfig(testConfig)
 	if err != nil {
 		t.Fatalf("SaveConfig() error = %v", err)
 	}
+	if info, err := os.Stat(DefaultConfigFile); err != nil {
+		t.Fatal(err)
+	} else if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("Expected config file mode 0600, got %o", info.Mode().Perm())
+	}
 
 	// 加载配置
 	loadedConfig, err := LoadConfig()
@@ -180,3 +194,468 @@ func TestSetDefaultProject(t *testing.T) {
 		t.Errorf("Expected default project = %s, got %s", projectName, GetDefaultProject())
 	}
 }
+
+func TestProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+	originalDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalDir) }()
+
+	// 保存原始配置
+	originalConfig := globalConfig
+	defer func() {
+		globalConfig = originalConfig
+		sessionProfile = ""
+	}()
+
+	_ = os.Chdir(tempDir)
+
+	// 重置全局配置
+	globalConfig = nil
+
+	err := SaveConfig(&internal.Config{
+		DataDir:        "default_data",
+		BackupDir:      "default_backups",
+		WebPort:        DefaultWebPort,
+		DefaultProject: "default_project",
+	})
+	if err != nil {
+		t.Fatalf("SaveConfig() error = %v", err)
+	}
+
+	// 创建档案
+	err = CreateProfile(internal.Profile{
+		Name:           "team",
+		DataDir:        "team_data",
+		BackupDir:      "team_backups",
+		DefaultProject: "team_project",
+	})
+	if err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+
+	// 测试重复和保留名称
+	if err := CreateProfile(internal.Profile{Name: "team"}); err == nil {
+		t.Error("Expected error when creating profile with duplicate name")
+	}
+	if err := CreateProfile(internal.Profile{Name: DefaultProfileName}); err == nil {
+		t.Error("Expected error when creating profile with reserved name")
+	}
+
+	if len(ListProfiles()) != 2 {
+		t.Errorf("Expected 2 profiles, got %d", len(ListProfiles()))
+	}
+
+	// 切换档案后目录应该随之改变
+	if err := UseProfile("team"); err != nil {
+		t.Fatalf("UseProfile() error = %v", err)
+	}
+	if GetDataDir() != "team_data" {
+		t.Errorf("Expected DataDir = team_data, got %s", GetDataDir())
+	}
+	if GetDefaultProject() != "team_project" {
+		t.Errorf("Expected default project = team_project, got %s", GetDefaultProject())
+	}
+	if GetWebPort() != DefaultWebPort {
+		t.Errorf("Expected WebPort to fall back to %d, got %d", DefaultWebPort, GetWebPort())
+	}
+
+	// 使用命名档案时 config set 更新档案而不是顶层配置
+	err = UpdateConfig(map[string]interface{}{
+		"data_dir":        "team_data2",
+		"backup_dir":      "team_backups2",
+		"web_port":        9090,
+		"default_project": "team_project2",
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	if GetDataDir() != "team_data2" || GetBackupDir() != "team_backups2" || GetWebPort() != 9090 || GetDefaultProject() != "team_project2" {
+		t.Errorf("Expected profile settings to be updated, got %s %s %d %s", GetDataDir(), GetBackupDir(), GetWebPort(), GetDefaultProject())
+	}
+	cfg := GetConfig()
+	if cfg.DataDir != "default_data" || cfg.BackupDir != "default_backups" || cfg.WebPort != DefaultWebPort || cfg.DefaultProject != "default_project" {
+		t.Errorf("Expected top-level settings to stay unchanged, got %+v", cfg)
+	}
+	if len(cfg.DataDirHistory) != 0 {
+		t.Errorf("Expected profile data dir change not to be recorded in history, got %v", cfg.DataDirHistory)
+	}
+
+	// 不能删除当前档案
+	if err := DeleteProfile("team"); err == nil {
+		t.Error("Expected error when deleting active profile")
+	}
+
+	// 会话档案优先于持久化档案
+	if err := SetSessionProfile(DefaultProfileName); err != nil {
+		t.Fatalf("SetSessionProfile() error = %v", err)
+	}
+	if GetDataDir() != "default_data" {
+		t.Errorf("Expected DataDir = default_data, got %s", GetDataDir())
+	}
+	if err := SetSessionProfile("missing"); err == nil {
+		t.Error("Expected error when using non-existent profile")
+	}
+	sessionProfile = ""
+
+	// 切回默认档案后可以删除
+	if err := UseProfile(DefaultProfileName); err != nil {
+		t.Fatalf("UseProfile() error = %v", err)
+	}
+	if err := DeleteProfile("team"); err != nil {
+		t.Fatalf("DeleteProfile() error = %v", err)
+	}
+	if _, err := GetProfile("team"); err == nil {
+		t.Error("Expected error when getting deleted profile")
+	}
+}
+
+func TestFindProjectMarker(t *testing.T) {
+	tempDir := t.TempDir()
+	nested := filepath.Join(tempDir, "repo", "src", "pkg")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未找到标记
+	marker, err := FindProjectMarker(nested)
+	if err != nil {
+		t.Fatalf("FindProjectMarker() error = %v", err)
+	}
+	if marker != nil && strings.HasPrefix(marker.Path, tempDir) {
+		t.Errorf("Expected no marker, got %+v", marker)
+	}
+
+	// 清单中的项目名称
+	manifestPath := filepath.Join(tempDir, "repo", ManifestFile)
+	if err := os.WriteFile(manifestPath, []byte("project: from-manifest\nenvironments: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	marker, err = FindProjectMarker(nested)
+	if err != nil || marker == nil || marker.Project != "from-manifest" {
+		t.Fatalf("Expected project from manifest, got %+v, err = %v", marker, err)
+	}
+
+	// 纯文本标记更近且优先
+	markerPath := filepath.Join(tempDir, "repo", "src", MarkerFile)
+	if err := os.WriteFile(markerPath, []byte("plain-project\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	marker, err = FindProjectMarker(nested)
+	if err != nil || marker == nil || marker.Project != "plain-project" || marker.Path != markerPath {
+		t.Fatalf("Expected plain marker, got %+v, err = %v", marker, err)
+	}
+
+	// YAML 标记
+	if err := os.WriteFile(markerPath, []byte("project: yaml-project\nenvironment: dev\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	marker, err = FindProjectMarker(nested)
+	if err != nil || marker == nil || marker.Project != "yaml-project" || marker.Environment != "dev" {
+		t.Fatalf("Expected yaml marker, got %+v, err = %v", marker, err)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+
+	root := filepath.Join(t.TempDir(), "repo")
+	project := &internal.Project{
+		Root:          root,
+		PathVariables: map[string]string{"CONF_DIR": "~/.config/app"},
+	}
+	vars := PathVariables(project)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"~/.bashrc", filepath.Join(home, ".bashrc")},
+		{"$HOME/app.yaml", filepath.Join(home, "app.yaml")},
+		{"${PROJECT_ROOT}/configs/dev.yaml", filepath.Join(root, "configs", "dev.yaml")},
+		{"configs/dev.yaml", filepath.Join(root, "configs", "dev.yaml")},
+		{"${CONF_DIR}/settings.json", filepath.Join(home, ".config", "app", "settings.json")},
+		{"/etc/hosts", "/etc/hosts"},
+	}
+
+	for _, tt := range tests {
+		got, err := ExpandPath(tt.path, vars)
+		if err != nil {
+			t.Errorf("ExpandPath(%q) error = %v", tt.path, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExpandPath(%q) = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := ExpandPath("${ENVSWITCH_UNDEFINED_TEST_VAR}/x", vars); err == nil {
+		t.Error("Expected error for undefined variable")
+	}
+
+	// 未设置根目录时相对路径保持不变
+	if got, _ := ExpandPath("configs/dev.yaml", PathVariables(nil)); got != "configs/dev.yaml" {
+		t.Errorf("Expected relative path to stay relative without project root, got %s", got)
+	}
+}
+
+func TestIsProtected(t *testing.T) {
+	originalConfig := globalConfig
+	defer func() { globalConfig = originalConfig }()
+	globalConfig = &internal.Config{ProtectedTags: []string{"production"}}
+
+	if IsProtected(&internal.Environment{Name: "dev", Tags: []string{"local"}}) {
+		t.Error("Expected environment without protected flag or tag not to be protected")
+	}
+	if !IsProtected(&internal.Environment{Name: "prod", Protected: true}) {
+		t.Error("Expected environment marked as protected to be protected")
+	}
+
+	env := &internal.Environment{Name: "live", Tags: []string{"remote", "production"}}
+	if !IsProtected(env) {
+		t.Error("Expected environment with protected tag to be protected")
+	}
+	if reason := ProtectionReason(env); reason != "tagged 'production'" {
+		t.Errorf("Unexpected protection reason: %s", reason)
+	}
+
+	// 更新后不再受保护时需要确认
+	prod := &internal.Environment{Name: "prod", Protected: true, Tags: []string{"production"}}
+	removes := []struct {
+		updates  map[string]interface{}
+		expected bool
+	}{
+		{map[string]interface{}{"description": "Production"}, false},
+		{map[string]interface{}{"protected": false}, false},
+		{map[string]interface{}{"tags": []string{}}, false},
+		{map[string]interface{}{"protected": false, "tags": []string{"live"}}, true},
+		{map[string]interface{}{"protected": false, "tags": nil}, true},
+	}
+	for _, tc := range removes {
+		if got := RemovesProtection(prod, tc.updates); got != tc.expected {
+			t.Errorf("RemovesProtection(%v) = %v, expected %v", tc.updates, got, tc.expected)
+		}
+	}
+	if !RemovesProtection(env, map[string]interface{}{"tags": []string{"remote"}}) {
+		t.Error("Expected removing the protected tag to remove protection")
+	}
+	if RemovesProtection(&internal.Environment{Name: "dev"}, map[string]interface{}{"protected": false}) {
+		t.Error("Expected unprotected environment not to require confirmation")
+	}
+
+	globalConfig.ProtectedTags = nil
+	if IsProtected(env) {
+		t.Error("Expected tag protection to be disabled when no protected tags are configured")
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tempDir := t.TempDir()
+	originalDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalDir) }()
+	_ = os.Chdir(tempDir)
+
+	originalConfig := globalConfig
+	defer func() { globalConfig = originalConfig }()
+
+	// 当前目录下的 config.json 优先，避免写入用户目录
+	if err := os.WriteFile(DefaultConfigFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	globalConfig = &internal.Config{
+		DataDir:   filepath.Join(tempDir, "data"),
+		BackupDir: filepath.Join(tempDir, "backups"),
+	}
+
+	token, secret, err := CreateToken("ci", RoleOperator, []string{"web-app"}, time.Hour)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if !strings.HasPrefix(secret, TokenPrefix) || token.Hash == secret || token.Hash != HashToken(secret) {
+		t.Errorf("Expected only the hash of the token to be stored, got %+v", token)
+	}
+	if _, _, err := CreateToken("ci", RoleAdmin, nil, 0); err == nil {
+		t.Error("Expected error for duplicate token name")
+	}
+	if _, _, err := CreateToken("bad", "root", nil, 0); err == nil {
+		t.Error("Expected error for invalid role")
+	}
+
+	// 从配置文件重新读取，其他进程创建的令牌也能生效
+	tokens, err := LoadTokens()
+	if err != nil {
+		t.Fatalf("LoadTokens() error = %v", err)
+	}
+	found := FindToken(tokens, secret)
+	if found == nil || found.Name != "ci" {
+		t.Fatalf("Expected to find token by secret, got %+v", found)
+	}
+	if FindToken(tokens, secret+"x") != nil {
+		t.Error("Expected wrong secret not to match")
+	}
+
+	if !TokenAllowsProject(found, &internal.Project{ID: "1", Name: "web-app"}) || TokenAllowsProject(found, &internal.Project{ID: "2", Name: "api"}) {
+		t.Error("Expected token to be limited to its projects")
+	}
+	if !RoleAllows(RoleAdmin, RoleOperator) || !RoleAllows(RoleOperator, RoleOperator) || RoleAllows(RoleViewer, RoleOperator) {
+		t.Error("Unexpected role hierarchy")
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	found.ExpiresAt = &expired
+	if FindToken(tokens, secret) != nil {
+		t.Error("Expected expired token not to match")
+	}
+
+	if _, err := RevokeToken(token.ID); err != nil {
+		t.Fatalf("RevokeToken() error = %v", err)
+	}
+	if tokens, _ := LoadTokens(); len(tokens) != 0 {
+		t.Errorf("Expected token to be removed, got %v", tokens)
+	}
+	if _, err := RevokeToken("ci"); err == nil {
+		t.Error("Expected error when revoking unknown token")
+	}
+}
+
+func TestRemote(t *testing.T) {
+	tempDir := t.TempDir()
+	originalDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalDir) }()
+	_ = os.Chdir(tempDir)
+
+	originalConfig := globalConfig
+	defer func() {
+		globalConfig = originalConfig
+		sessionProfile = ""
+	}()
+
+	// 当前目录下的 config.json 优先，避免写入用户目录
+	if err := os.WriteFile(DefaultConfigFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	globalConfig = &internal.Config{
+		DataDir:   filepath.Join(tempDir, "data"),
+		BackupDir: filepath.Join(tempDir, "backups"),
+	}
+	if err := CreateProfile(internal.Profile{
+		Name:      "shared",
+		DataDir:   filepath.Join(tempDir, "shared", "data"),
+		BackupDir: filepath.Join(tempDir, "shared", "backups"),
+	}); err != nil {
+		t.Fatalf("CreateProfile() error = %v", err)
+	}
+
+	if address, _ := GetRemote(); address != "" {
+		t.Errorf("Expected no remote by default, got %s", address)
+	}
+	if err := SetRemote("shared", "devbox:8080", "secret"); err == nil {
+		t.Error("Expected error for remote without scheme")
+	}
+	if err := SetRemote("shared", "http://devbox:8080/", "secret"); err != nil {
+		t.Fatalf("SetRemote() error = %v", err)
+	}
+	if err := SetRemote("missing", "http://devbox:8080", ""); err == nil {
+		t.Error("Expected error for non-existent profile")
+	}
+
+	// 远程地址和令牌属于档案
+	if address, _ := GetRemote(); address != "" {
+		t.Errorf("Expected default profile to stay local, got %s", address)
+	}
+	if err := SetSessionProfile("shared"); err != nil {
+		t.Fatal(err)
+	}
+	if address, token := GetRemote(); address != "http://devbox:8080" || token != "secret" {
+		t.Errorf("Expected remote of profile 'shared', got %s %s", address, token)
+	}
+
+	// 显示配置和档案时不输出令牌
+	globalConfig.RemoteToken = "top-secret"
+	globalConfig.Tokens = []internal.APIToken{{ID: "1", Name: "ci", Hash: "abc"}}
+	redacted := RedactConfig(globalConfig)
+	if redacted.RemoteToken != RedactedValue || redacted.Profiles[0].RemoteToken != RedactedValue || redacted.Tokens[0].Hash != "" {
+		t.Errorf("Expected tokens to be redacted, got %+v", redacted)
+	}
+	if globalConfig.RemoteToken != "top-secret" || globalConfig.Profiles[0].RemoteToken != "secret" || globalConfig.Tokens[0].Hash != "abc" {
+		t.Error("Expected redaction not to modify the config")
+	}
+	if profiles := RedactProfiles(ListProfiles()); profiles[0].RemoteToken != RedactedValue || profiles[1].RemoteToken != RedactedValue {
+		t.Errorf("Expected profile tokens to be redacted, got %+v", profiles)
+	}
+	globalConfig.RemoteToken = ""
+	globalConfig.Tokens = nil
+
+	// 清除地址时一并清除令牌
+	if err := SetRemote("shared", "", "secret"); err != nil {
+		t.Fatalf("SetRemote() error = %v", err)
+	}
+	if address, token := GetRemote(); address != "" || token != "" {
+		t.Errorf("Expected remote to be cleared, got %s %s", address, token)
+	}
+}
+
+func TestWebServerSettings(t *testing.T) {
+	tempDir := t.TempDir()
+	originalDir, _ := os.Getwd()
+	defer func() { _ = os.Chdir(originalDir) }()
+	_ = os.Chdir(tempDir)
+
+	originalConfig := globalConfig
+	defer func() { globalConfig = originalConfig }()
+
+	if err := os.WriteFile(DefaultConfigFile, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	globalConfig = &internal.Config{
+		DataDir:   filepath.Join(tempDir, "data"),
+		BackupDir: filepath.Join(tempDir, "backups"),
+	}
+
+	// 默认只监听本机
+	if bind := GetWebBind(); bind != DefaultWebBind {
+		t.Errorf("Expected default bind %s, got %s", DefaultWebBind, bind)
+	}
+	if mode := GetUnixSocketMode(); mode != DefaultUnixSocketMode {
+		t.Errorf("Expected default socket mode %o, got %o", DefaultUnixSocketMode, mode)
+	}
+
+	err := UpdateConfig(map[string]interface{}{
+		"web_bind":             "0.0.0.0",
+		"web_tls_self_signed":  true,
+		"web_unix_socket":      "/run/envswitch.sock",
+		"web_unix_socket_mode": "0600",
+	})
+	if err != nil {
+		t.Fatalf("UpdateConfig() error = %v", err)
+	}
+	config := GetConfig()
+	if GetWebBind() != "0.0.0.0" || !config.WebTLSSelfSigned || config.WebUnixSocket != "/run/envswitch.sock" || GetUnixSocketMode() != 0600 {
+		t.Errorf("Unexpected web server settings %+v", config)
+	}
+
+	// 无效的权限不会部分更新配置
+	err = UpdateConfig(map[string]interface{}{
+		"web_bind":             "127.0.0.1",
+		"web_unix_socket_mode": "rw-rw----",
+	})
+	if err == nil {
+		t.Error("Expected error for invalid socket mode")
+	}
+	if GetWebBind() != "0.0.0.0" {
+		t.Errorf("Expected bind to stay unchanged, got %s", GetWebBind())
+	}
+
+	for _, value := range []string{"0660", "644", "777"} {
+		if _, err := ParseSocketMode(value); err != nil {
+			t.Errorf("ParseSocketMode(%q) error = %v", value, err)
+		}
+	}
+	for _, value := range []string{"0999", "1777", "abc"} {
+		if _, err := ParseSocketMode(value); err == nil {
+			t.Errorf("Expected error for socket mode %q", value)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// MarkerFile 项目标记文件名，内容为项目名称或 YAML（project: <name>）
+	MarkerFile = ".envswitch"
+	// ManifestFile 声明式清单文件名，其中的 project 字段同样可用于识别项目
+	ManifestFile = "envswitch.yaml"
+)
+
+// ProjectMarker 从目录中识别出的项目标记
+type ProjectMarker struct {
+	Project     string `json:"project"`
+	Environment string `json:"environment,omitempty"` // 标记文件中可选的默认环境
+	Path        string `json:"path"`                  // 标记文件的绝对路径
+}
+
+// markerContent 标记文件的 YAML 形式
+type markerContent struct {
+	Project     string `yaml:"project"`
+	Environment string `yaml:"environment"`
+}
+
+// FindProjectMarker 从 dir 开始向上查找 .envswitch 标记文件或 envswitch.yaml 清单
+// 同一目录中 .envswitch 优先；未找到时返回 nil
+func FindProjectMarker(dir string) (*ProjectMarker, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		for _, name := range []string{MarkerFile, ManifestFile} {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue
+			}
+
+			marker, err := readProjectMarker(path)
+			if err != nil {
+				return nil, err
+			}
+			if marker.Project != "" {
+				return marker, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// readProjectMarker 解析标记文件，支持纯文本项目名称和 YAML 两种格式
+func readProjectMarker(path string) (*ProjectMarker, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read marker %s: %w", path, err)
+	}
+
+	marker := &ProjectMarker{Path: path}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, fmt.Errorf("failed to parse marker %s: %w", path, err)
+	}
+
+	// 纯文本：整个文件就是项目名称
+	if len(node.Content) == 1 && node.Content[0].Kind == yaml.ScalarNode {
+		marker.Project = strings.TrimSpace(node.Content[0].Value)
+		return marker, nil
+	}
+
+	var content markerContent
+	if err := yaml.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("failed to parse marker %s: %w", path, err)
+	}
+	marker.Project = strings.TrimSpace(content.Project)
+	marker.Environment = strings.TrimSpace(content.Environment)
+
+	return marker, nil
+}
+
+// DetectProject 确定未显式指定项目时使用的项目
+// 优先使用当前目录（及上级目录）中的标记文件，其次使用默认项目；marker 为 nil 表示使用了默认项目
+func DetectProject() (string, *ProjectMarker, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return GetDefaultProject(), nil, nil
+	}
+
+	marker, err := FindProjectMarker(cwd)
+	if err != nil {
+		return "", nil, err
+	}
+	if marker != nil {
+		return marker.Project, marker, nil
+	}
+	return GetDefaultProject(), nil, nil
+}
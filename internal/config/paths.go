@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// ProjectRootVariable 项目根目录路径变量名
+const ProjectRootVariable = "PROJECT_ROOT"
+
+var pathVariableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// PathVariables 获取解析项目路径时可用的变量
+// 优先级：本机配置 > 项目定义 > 内置变量（HOME、PROJECT_ROOT），未定义的变量回退到进程环境变量
+func PathVariables(project *internal.Project) map[string]string {
+	vars := make(map[string]string)
+
+	if home, err := os.UserHomeDir(); err == nil {
+		vars["HOME"] = home
+	}
+
+	if project != nil {
+		if project.Root != "" {
+			vars[ProjectRootVariable] = expandHome(project.Root, vars["HOME"])
+		}
+		for name, value := range project.PathVariables {
+			vars[name] = expandHome(value, vars["HOME"])
+		}
+	}
+
+	for name, value := range GetConfig().PathVariables {
+		vars[name] = expandHome(value, vars["HOME"])
+	}
+
+	return vars
+}
+
+// ExpandPath 展开路径中的 ~、$VAR 和 ${VAR}，相对路径以 PROJECT_ROOT 为基准（如已设置）
+func ExpandPath(path string, vars map[string]string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	var undefined []string
+	expanded := os.Expand(expandHome(path, vars["HOME"]), func(name string) string {
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		undefined = append(undefined, name)
+		return ""
+	})
+
+	if len(undefined) > 0 {
+		return "", fmt.Errorf("undefined path variable %s in '%s'", strings.Join(undefined, ", "), path)
+	}
+
+	if !filepath.IsAbs(expanded) {
+		if root := vars[ProjectRootVariable]; root != "" {
+			expanded = filepath.Join(root, expanded)
+		}
+	}
+
+	return filepath.Clean(expanded), nil
+}
+
+// SetPathVariable 设置本机路径变量，value 为空时删除
+func SetPathVariable(name, value string) error {
+	if err := ValidatePathVariableName(name); err != nil {
+		return err
+	}
+
+	config := GetConfig()
+	if value == "" {
+		delete(config.PathVariables, name)
+	} else {
+		if config.PathVariables == nil {
+			config.PathVariables = make(map[string]string)
+		}
+		config.PathVariables[name] = value
+	}
+
+	return SaveConfig(config)
+}
+
+// ValidatePathVariableName 验证路径变量名称
+func ValidatePathVariableName(name string) error {
+	if !pathVariableNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid path variable name '%s'", name)
+	}
+	if name == "HOME" || name == ProjectRootVariable {
+		return fmt.Errorf("path variable '%s' is built in and cannot be overridden", name)
+	}
+	return nil
+}
+
+// SortedVariableNames 按名称排序的变量名列表
+func SortedVariableNames(vars map[string]string) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// expandHome 展开开头的 ~
+func expandHome(path, home string) string {
+	if home == "" {
+		return path
+	}
+	if path == "~" {
+		return home
+	}
+	if strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// DefaultProfileName 顶层配置对应的隐式档案名称
+const DefaultProfileName = "default"
+
+// sessionProfile 通过 --profile 指定的本次会话档案，不写入配置文件
+var sessionProfile string
+
+// ListProfiles 列出所有档案（包含隐式的 default 档案）
+func ListProfiles() []internal.Profile {
+	config := GetConfig()
+
+	profiles := []internal.Profile{defaultProfile(config)}
+	profiles = append(profiles, config.Profiles...)
+	return profiles
+}
+
+// GetProfile 获取指定名称的档案
+func GetProfile(name string) (*internal.Profile, error) {
+	config := GetConfig()
+
+	if name == "" || name == DefaultProfileName {
+		profile := defaultProfile(config)
+		return &profile, nil
+	}
+
+	for i := range config.Profiles {
+		if config.Profiles[i].Name == name {
+			return &config.Profiles[i], nil
+		}
+	}
+
+	return nil, internal.NotFoundf("profile not found: %s", name)
+}
+
+// CreateProfile 创建新档案，未指定的目录默认放在 ~/.envswitch/profiles/<name> 下
+func CreateProfile(profile internal.Profile) error {
+	if profile.Name == "" {
+		return fmt.Errorf("profile name cannot be empty")
+	}
+	if profile.Name == DefaultProfileName {
+		return fmt.Errorf("profile name '%s' is reserved", DefaultProfileName)
+	}
+
+	config := GetConfig()
+	for _, existing := range config.Profiles {
+		if existing.Name == profile.Name {
+			return internal.AlreadyExistsf("profile with name '%s' already exists", profile.Name)
+		}
+	}
+
+	if profile.DataDir == "" || profile.BackupDir == "" {
+		baseDir := filepath.Join("profiles", profile.Name)
+		if homeDir, err := os.UserHomeDir(); err == nil {
+			baseDir = filepath.Join(homeDir, ".envswitch", "profiles", profile.Name)
+		}
+		if profile.DataDir == "" {
+			profile.DataDir = filepath.Join(baseDir, "data")
+		}
+		if profile.BackupDir == "" {
+			profile.BackupDir = filepath.Join(baseDir, "backups")
+		}
+	}
+
+	config.Profiles = append(config.Profiles, profile)
+	return SaveConfig(config)
+}
+
+// DeleteProfile 删除档案（不删除档案的数据目录）
+func DeleteProfile(name string) error {
+	if name == DefaultProfileName {
+		return fmt.Errorf("cannot delete the '%s' profile", DefaultProfileName)
+	}
+
+	config := GetConfig()
+	if GetActiveProfileName() == name {
+		return fmt.Errorf("cannot delete active profile '%s', switch to another profile first", name)
+	}
+
+	for i, profile := range config.Profiles {
+		if profile.Name == name {
+			config.Profiles = append(config.Profiles[:i], config.Profiles[i+1:]...)
+			return SaveConfig(config)
+		}
+	}
+
+	return internal.NotFoundf("profile not found: %s", name)
+}
+
+// UseProfile 设置持久化的当前档案
+func UseProfile(name string) error {
+	if _, err := GetProfile(name); err != nil {
+		return err
+	}
+
+	config := GetConfig()
+	if name == DefaultProfileName {
+		name = ""
+	}
+	config.ActiveProfile = name
+	return SaveConfig(config)
+}
+
+// SetSessionProfile 为本次进程临时指定档案（对应 --profile 全局标志）
+func SetSessionProfile(name string) error {
+	if name == "" {
+		sessionProfile = ""
+		return nil
+	}
+
+	profile, err := GetProfile(name)
+	if err != nil {
+		return err
+	}
+
+	sessionProfile = name
+	return ensureDirectories(&internal.Config{DataDir: profile.DataDir, BackupDir: profile.BackupDir})
+}
+
+// GetActiveProfileName 获取当前生效的档案名称
+func GetActiveProfileName() string {
+	if sessionProfile != "" {
+		return sessionProfile
+	}
+	if active := GetConfig().ActiveProfile; active != "" {
+		return active
+	}
+	return DefaultProfileName
+}
+
+// activeProfile 获取当前生效的命名档案，使用顶层配置时返回nil
+func activeProfile() *internal.Profile {
+	name := GetActiveProfileName()
+	if name == DefaultProfileName {
+		return nil
+	}
+
+	config := GetConfig()
+	for i := range config.Profiles {
+		if config.Profiles[i].Name == name {
+			return &config.Profiles[i]
+		}
+	}
+
+	// 档案已被删除时回退到顶层配置
+	return nil
+}
+
+// defaultProfile 将顶层配置表示为 default 档案
+func defaultProfile(config *internal.Config) internal.Profile {
+	return internal.Profile{
+		Name:           DefaultProfileName,
+		DataDir:        config.DataDir,
+		BackupDir:      config.BackupDir,
+		WebPort:        config.WebPort,
+		DefaultProject: config.DefaultProject,
+		Remote:         config.Remote,
+		RemoteToken:    config.RemoteToken,
+	}
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// IsProtected 判断环境是否受保护：环境本身标记为受保护，或带有配置中的受保护标签
+func IsProtected(env *internal.Environment) bool {
+	return ProtectionReason(env) != ""
+}
+
+// RemovesProtection 判断更新（"protected" 和 "tags" 字段）是否会使当前受保护的环境不再受保护，
+// 这类更新与删除一样需要确认，否则可以先取消保护再绕过确认
+func RemovesProtection(env *internal.Environment, updates map[string]interface{}) bool {
+	if !IsProtected(env) {
+		return false
+	}
+
+	updated := *env
+	if protected, ok := updates["protected"].(bool); ok {
+		updated.Protected = protected
+	}
+	if _, ok := updates["tags"]; ok {
+		updated.Tags, _ = updates["tags"].([]string)
+	}
+	return !IsProtected(&updated)
+}
+
+// ProtectionReason 返回环境受保护的原因，未受保护时返回空字符串
+func ProtectionReason(env *internal.Environment) string {
+	if env == nil {
+		return ""
+	}
+	if env.Protected {
+		return "marked as protected"
+	}
+	for _, tag := range env.Tags {
+		for _, protectedTag := range GetConfig().ProtectedTags {
+			if tag == protectedTag {
+				return fmt.Sprintf("tagged '%s'", tag)
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// RedactedValue 输出中代替敏感值的占位符
+const RedactedValue = "[redacted]"
+
+// RedactConfig 返回用于显示的配置副本：远程服务令牌替换为占位符，API 令牌不包含哈希
+func RedactConfig(cfg *internal.Config) *internal.Config {
+	redacted := *cfg
+	redacted.RemoteToken = redact(cfg.RemoteToken)
+	redacted.Profiles = RedactProfiles(cfg.Profiles)
+	redacted.Tokens = RedactTokens(cfg.Tokens)
+	return &redacted
+}
+
+// RedactProfile 返回用于显示的档案副本，远程服务令牌替换为占位符
+func RedactProfile(profile *internal.Profile) *internal.Profile {
+	redacted := *profile
+	redacted.RemoteToken = redact(profile.RemoteToken)
+	return &redacted
+}
+
+// RedactProfiles 返回用于显示的档案列表副本
+func RedactProfiles(profiles []internal.Profile) []internal.Profile {
+	if profiles == nil {
+		return nil
+	}
+	redacted := make([]internal.Profile, len(profiles))
+	for i := range profiles {
+		redacted[i] = *RedactProfile(&profiles[i])
+	}
+	return redacted
+}
+
+// RedactTokens 返回不包含哈希的 API 令牌列表副本
+func RedactTokens(tokens []internal.APIToken) []internal.APIToken {
+	if tokens == nil {
+		return nil
+	}
+	redacted := make([]internal.APIToken, len(tokens))
+	for i, token := range tokens {
+		token.Hash = ""
+		redacted[i] = token
+	}
+	return redacted
+}
+
+func redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	return RedactedValue
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// GetRemote 获取当前档案的远程服务地址和令牌，未设置时地址为空
+func GetRemote() (string, string) {
+	if profile := activeProfile(); profile != nil {
+		return profile.Remote, profile.RemoteToken
+	}
+	config := GetConfig()
+	return config.Remote, config.RemoteToken
+}
+
+// SetRemote 设置指定档案的远程服务地址和令牌，address 为空时同时清除令牌
+func SetRemote(name, address, token string) error {
+	if address != "" {
+		normalized, err := ValidateRemote(address)
+		if err != nil {
+			return err
+		}
+		address = normalized
+	} else {
+		token = ""
+	}
+
+	config := GetConfig()
+	if name == "" || name == DefaultProfileName {
+		config.Remote = address
+		config.RemoteToken = token
+		return SaveConfig(config)
+	}
+
+	for i := range config.Profiles {
+		if config.Profiles[i].Name == name {
+			config.Profiles[i].Remote = address
+			config.Profiles[i].RemoteToken = token
+			return SaveConfig(config)
+		}
+	}
+
+	return internal.NotFoundf("profile not found: %s", name)
+}
+
+// ValidateRemote 校验远程服务地址，必须是 http 或 https URL，返回去掉末尾斜杠的地址
+func ValidateRemote(address string) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("invalid remote '%s', expected an http:// or https:// URL", address)
+	}
+	return strings.TrimRight(address, "/"), nil
+}
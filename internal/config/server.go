@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Web 服务监听的默认设置
+const (
+	DefaultWebBind        = "127.0.0.1"
+	DefaultUnixSocketMode = os.FileMode(0660)
+)
+
+// GetWebBind 获取 Web 服务监听的地址，未设置时只监听本机
+func GetWebBind() string {
+	if bind := GetConfig().WebBind; bind != "" {
+		return bind
+	}
+	return DefaultWebBind
+}
+
+// GetUnixSocketMode 获取 Unix 套接字文件的权限，未设置或无效时使用默认值
+func GetUnixSocketMode() os.FileMode {
+	mode, err := ParseSocketMode(GetConfig().WebUnixSocketMode)
+	if err != nil {
+		return DefaultUnixSocketMode
+	}
+	return mode
+}
+
+// ParseSocketMode 解析八进制的 Unix 套接字文件权限（如 0660），空字符串返回默认值
+func ParseSocketMode(value string) (os.FileMode, error) {
+	if value == "" {
+		return DefaultUnixSocketMode, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid socket mode '%s', expected an octal permission such as 0660", value)
+	}
+	return os.FileMode(mode), nil
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// Web 服务的角色，权限依次递增
+const (
+	RoleViewer   = "viewer"   // 只读访问
+	RoleOperator = "operator" // 只读访问，并可切换环境和回滚
+	RoleAdmin    = "admin"    // 全部操作
+)
+
+// TokenPrefix API 令牌的前缀，便于识别
+const TokenPrefix = "esw_"
+
+// Roles 全部角色，按权限从低到高排列
+var Roles = []string{RoleViewer, RoleOperator, RoleAdmin}
+
+// RoleAllows 判断角色是否具有 required 角色的权限
+func RoleAllows(role, required string) bool {
+	return roleLevel(role) >= roleLevel(required) && roleLevel(required) > 0
+}
+
+// roleLevel 角色的权限等级，未知角色为 0
+func roleLevel(role string) int {
+	for i, r := range Roles {
+		if r == role {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// HashToken 计算令牌的 SHA-256 哈希
+func HashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateToken 创建 API 令牌，返回令牌信息和令牌明文（明文只在创建时返回一次）
+// projects 为空时令牌可访问全部项目，ttl 为 0 时永不过期
+func CreateToken(name, role string, projects []string, ttl time.Duration) (*internal.APIToken, string, error) {
+	if name == "" {
+		return nil, "", fmt.Errorf("token name cannot be empty")
+	}
+	if roleLevel(role) == 0 {
+		return nil, "", fmt.Errorf("invalid role '%s', expected one of: %s", role, strings.Join(Roles, ", "))
+	}
+
+	config := GetConfig()
+	for _, existing := range config.Tokens {
+		if existing.Name == name {
+			return nil, "", internal.AlreadyExistsf("token with name '%s' already exists", name)
+		}
+	}
+
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, "", err
+	}
+	secret = TokenPrefix + secret
+
+	id, err := randomHex(4)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := internal.APIToken{
+		ID:        id,
+		Name:      name,
+		Hash:      HashToken(secret),
+		Role:      role,
+		Projects:  projects,
+		CreatedAt: time.Now(),
+	}
+	if ttl > 0 {
+		expiresAt := token.CreatedAt.Add(ttl)
+		token.ExpiresAt = &expiresAt
+	}
+
+	config.Tokens = append(config.Tokens, token)
+	if err := SaveConfig(config); err != nil {
+		return nil, "", err
+	}
+
+	return &token, secret, nil
+}
+
+// ListTokens 获取全部 API 令牌
+func ListTokens() []internal.APIToken {
+	return GetConfig().Tokens
+}
+
+// RevokeToken 按 ID 或名称吊销 API 令牌
+func RevokeToken(identifier string) (*internal.APIToken, error) {
+	config := GetConfig()
+	for i, token := range config.Tokens {
+		if token.ID == identifier || token.Name == identifier {
+			config.Tokens = append(config.Tokens[:i], config.Tokens[i+1:]...)
+			if err := SaveConfig(config); err != nil {
+				return nil, err
+			}
+			return &token, nil
+		}
+	}
+	return nil, internal.NotFoundf("token not found: %s", identifier)
+}
+
+// LoadTokens 从配置文件重新读取 API 令牌，不影响当前进程的配置
+// Web 服务每次认证时调用，使其他进程创建或吊销的令牌立即生效
+func LoadTokens() ([]internal.APIToken, error) {
+	data, err := os.ReadFile(getConfigPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var config struct {
+		Tokens []internal.APIToken `json:"tokens"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	return config.Tokens, nil
+}
+
+// FindToken 查找与明文匹配且未过期的令牌
+func FindToken(tokens []internal.APIToken, secret string) *internal.APIToken {
+	if secret == "" {
+		return nil
+	}
+	hash := []byte(HashToken(secret))
+	for i := range tokens {
+		if subtle.ConstantTimeCompare(hash, []byte(tokens[i].Hash)) == 1 && !TokenExpired(&tokens[i]) {
+			return &tokens[i]
+		}
+	}
+	return nil
+}
+
+// TokenExpired 判断令牌是否已过期
+func TokenExpired(token *internal.APIToken) bool {
+	return token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt)
+}
+
+// TokenAllowsProject 判断令牌是否可访问指定项目（按名称或 ID 匹配）
+func TokenAllowsProject(token *internal.APIToken, project *internal.Project) bool {
+	if len(token.Projects) == 0 {
+		return true
+	}
+	for _, name := range token.Projects {
+		if name == project.Name || name == project.ID {
+			return true
+		}
+	}
+	return false
+}
+
+// randomHex 生成 n 字节的随机十六进制字符串
+func randomHex(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
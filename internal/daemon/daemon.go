@@ -0,0 +1,131 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// ChildEnvVar 标记由 Start 启动的后台服务进程
+const ChildEnvVar = "ENVSWITCH_DAEMON_CHILD"
+
+// IsChild 判断当前进程是否为后台服务进程
+func IsChild() bool {
+	return os.Getenv(ChildEnvVar) == "1"
+}
+
+// Start 以后台方式重新执行当前程序，标准输出和标准错误追加到 outFile
+// outFile 不参与日志轮转，子进程启动失败或崩溃时的输出不会写入已轮转的旧日志
+// 等待子进程写入 pid 文件表示已开始监听，子进程提前退出或超时时返回错误
+func Start(args []string, pidFile, outFile string, timeout time.Duration) (int, error) {
+	if pid, ok := Running(pidFile); ok {
+		return 0, internal.AlreadyExistsf("server is already running (pid %d)", pid)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		return 0, fmt.Errorf("failed to locate executable: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outFile), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	out, err := os.OpenFile(outFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer func() { _ = out.Close() }()
+
+	cmd := exec.Command(executable, args...)
+	cmd.Env = append(os.Environ(), ChildEnvVar+"=1")
+	cmd.Stdout = out
+	cmd.Stderr = out
+	cmd.SysProcAttr = detachedAttr()
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start server: %w", err)
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for {
+		select {
+		case err := <-exited:
+			return 0, fmt.Errorf("server exited during startup (%v), see %s", err, outFile)
+		case <-deadline:
+			_ = cmd.Process.Kill()
+			return 0, fmt.Errorf("server did not start within %s, see %s", timeout, outFile)
+		case <-ticker.C:
+			if pid, err := ReadPidFile(pidFile); err == nil && pid == cmd.Process.Pid {
+				return pid, nil
+			}
+		}
+	}
+}
+
+// WriteArgsFile 保存后台服务的启动参数，restart 未指定参数时复用
+func WriteArgsFile(path string, args []string) error {
+	if args == nil {
+		args = []string{}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create args file directory: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write args file: %w", err)
+	}
+	return nil
+}
+
+// ReadArgsFile 读取保存的启动参数，文件不存在时返回 NotFound 错误
+func ReadArgsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, internal.NotFoundf("args file does not exist: %s", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read args file: %w", err)
+	}
+
+	var args []string
+	if err := json.Unmarshal(data, &args); err != nil {
+		return nil, fmt.Errorf("invalid args file %s: %w", path, err)
+	}
+	return args, nil
+}
+
+// Stop 结束 pid 文件记录的服务进程并等待其退出，没有运行时返回 NotFound 错误
+func Stop(pidFile string, timeout time.Duration) (int, error) {
+	pid, ok := Running(pidFile)
+	if !ok {
+		return 0, internal.NotFoundf("server is not running")
+	}
+
+	if err := terminate(pid); err != nil {
+		return 0, fmt.Errorf("failed to stop server (pid %d): %w", pid, err)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("server (pid %d) did not stop within %s", pid, timeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// 被强制结束的进程不会删除自己的 pid 文件
+	_ = os.Remove(pidFile)
+	return pid, nil
+}
@@ -0,0 +1,198 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+func TestPidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run", "server.pid")
+
+	if _, ok := Running(path); ok {
+		t.Fatal("Expected no running server without a pid file")
+	}
+	if _, err := ReadPidFile(path); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	if err := WritePidFile(path); err != nil {
+		t.Fatalf("WritePidFile() error = %v", err)
+	}
+	if pid, ok := Running(path); !ok || pid != os.Getpid() {
+		t.Errorf("Running() = %d, %v, want %d", pid, ok, os.Getpid())
+	}
+
+	// 其他进程正在运行时不能覆盖
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getppid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WritePidFile(path); !errors.Is(err, internal.ErrAlreadyExists) {
+		t.Errorf("Expected already exists error, got %v", err)
+	}
+	// 不删除其他进程的 pid 文件
+	RemovePidFile(path)
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("Expected pid file of another process to be kept, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		t.Fatal(err)
+	}
+	RemovePidFile(path)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected pid file to be removed, got %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadPidFile(path); err == nil {
+		t.Error("Expected error for invalid pid file")
+	}
+}
+
+func TestStopNotRunning(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.pid")
+	if _, err := Stop(path, 0); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestArgsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.args")
+
+	if _, err := ReadArgsFile(path); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	args := []string{"--port=9000", "--bind=0.0.0.0"}
+	if err := WriteArgsFile(path, args); err != nil {
+		t.Fatalf("WriteArgsFile() error = %v", err)
+	}
+	if got, err := ReadArgsFile(path); err != nil || strings.Join(got, " ") != strings.Join(args, " ") {
+		t.Errorf("ReadArgsFile() = %v, %v, want %v", got, err, args)
+	}
+
+	if err := WriteArgsFile(path, nil); err != nil {
+		t.Fatalf("WriteArgsFile() error = %v", err)
+	}
+	if got, err := ReadArgsFile(path); err != nil || len(got) != 0 {
+		t.Errorf("ReadArgsFile() = %v, %v, want no args", got, err)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "server.log")
+
+	logger, err := OpenLog(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	defer func() { _ = logger.Close() }()
+
+	for i := 1; i <= 4; i++ {
+		if _, err := fmt.Fprintf(logger, "line %d\n", i); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	// 每行 7 字节，超过 10 字节时轮转，最多保留 2 个旧文件
+	expected := map[string]string{
+		path:        "line 4\n",
+		path + ".1": "line 3\n",
+		path + ".2": "line 2\n",
+	}
+	for file, content := range expected {
+		if data, err := os.ReadFile(file); err != nil || string(data) != content {
+			t.Errorf("Expected %s to contain %q, got %q, %v", file, content, data, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("Expected only 2 backups, got %v", err)
+	}
+
+	// 重新打开时继续追加
+	_ = logger.Close()
+	logger, err = OpenLog(path, 10, 2)
+	if err != nil {
+		t.Fatalf("OpenLog() error = %v", err)
+	}
+	if _, err := logger.Write([]byte("x\n")); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "line 4\nx\n" {
+		t.Errorf("Expected log to be appended, got %q", data)
+	}
+}
+
+func TestListenersWithoutActivation(t *testing.T) {
+	t.Setenv("LISTEN_PID", "1")
+	t.Setenv("LISTEN_FDS", "1")
+
+	listeners, err := Listeners()
+	if err != nil || len(listeners) != 0 {
+		t.Errorf("Expected no listeners for another pid, got %v, %v", listeners, err)
+	}
+	if _, ok := os.LookupEnv("LISTEN_FDS"); ok {
+		t.Error("Expected LISTEN_FDS to be cleared")
+	}
+}
+
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := Notify("READY=1"); sent || err != nil {
+		t.Errorf("Expected no notification without NOTIFY_SOCKET, got %v, %v", sent, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets are not available")
+	}
+
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	if sent, err := Notify("READY=1"); !sent || err != nil {
+		t.Fatalf("Notify() = %v, %v", sent, err)
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil || !strings.HasPrefix(string(buf[:n]), "READY=1") {
+		t.Errorf("Expected READY=1, got %q, %v", buf[:n], err)
+	}
+}
+
+func TestListenUnix(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix socket modes are not supported")
+	}
+
+	socket := filepath.Join(t.TempDir(), "server.sock")
+	listener, err := ListenUnix(socket, 0660)
+	if err != nil {
+		t.Fatalf("ListenUnix() error = %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0660 {
+		t.Errorf("Expected socket mode 0660, got %04o", info.Mode().Perm())
+	}
+}
@@ -0,0 +1,109 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// 日志文件的默认轮转设置
+const (
+	DefaultLogMaxSize    = 10 << 20
+	DefaultLogMaxBackups = 5
+)
+
+// RotatingFile 按大小轮转的日志文件：超过 MaxSize 时将 server.log 重命名为 server.log.1，
+// 已有的 server.log.N 依次后移，最多保留 MaxBackups 个旧文件
+type RotatingFile struct {
+	Path       string
+	MaxSize    int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenLog 以追加方式打开日志文件
+func OpenLog(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{Path: path, MaxSize: maxSize, MaxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.Path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	file, err := os.OpenFile(r.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// Write 写入日志，写入前超过大小上限时先轮转
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate 关闭当前文件，依次后移旧文件并重新打开
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if r.MaxBackups > 0 {
+		_ = os.Remove(fmt.Sprintf("%s.%d", r.Path, r.MaxBackups))
+		for i := r.MaxBackups - 1; i >= 1; i-- {
+			_ = os.Rename(fmt.Sprintf("%s.%d", r.Path, i), fmt.Sprintf("%s.%d", r.Path, i+1))
+		}
+		if err := os.Rename(r.Path, r.Path+".1"); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else if err := os.Remove(r.Path); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	return r.open()
+}
+
+// Close 关闭日志文件
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
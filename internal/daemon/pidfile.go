@@ -0,0 +1,70 @@
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// ReadPidFile 读取 pid 文件中的进程号
+func ReadPidFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, internal.NotFoundf("pid file does not exist: %s", path)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read pid file: %w", err)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid file %s", path)
+	}
+	return pid, nil
+}
+
+// Running 返回 pid 文件记录的仍在运行的进程号，进程不存在时删除过期的 pid 文件
+func Running(path string) (int, bool) {
+	pid, err := ReadPidFile(path)
+	if err != nil {
+		return 0, false
+	}
+	if !processAlive(pid) {
+		_ = os.Remove(path)
+		return 0, false
+	}
+	return pid, true
+}
+
+// WritePidFile 写入当前进程的 pid 文件，已有其他进程在运行时返回 AlreadyExists 错误
+func WritePidFile(path string) error {
+	if pid, ok := Running(path); ok && pid != os.Getpid() {
+		return internal.AlreadyExistsf("server is already running (pid %d)", pid)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create pid file directory: %w", err)
+	}
+
+	// 先写临时文件再重命名，避免读到不完整的内容
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write pid file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write pid file: %w", err)
+	}
+	return nil
+}
+
+// RemovePidFile 删除当前进程的 pid 文件，文件属于其他进程时保留
+func RemovePidFile(path string) {
+	if pid, err := ReadPidFile(path); err == nil && pid == os.Getpid() {
+		_ = os.Remove(path)
+	}
+}
@@ -0,0 +1,39 @@
+//go:build !windows
+
+package daemon
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processAlive 判断进程是否存在，没有权限发送信号的进程也视为存在
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// detachedAttr 后台进程创建新的会话，脱离控制终端
+func detachedAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
+
+// terminate 发送 SIGTERM，由服务器优雅关闭
+func terminate(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(syscall.SIGTERM)
+}
+
+// restrictUmask 将进程的 umask 设为只允许所有者读写，返回恢复原 umask 的函数
+func restrictUmask() func() {
+	old := syscall.Umask(0177)
+	return func() { syscall.Umask(old) }
+}
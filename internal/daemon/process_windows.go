@@ -0,0 +1,45 @@
+//go:build windows
+
+package daemon
+
+import (
+	"os"
+	"syscall"
+)
+
+// detachedProcess Windows 的 DETACHED_PROCESS 创建标志
+const detachedProcess = 0x00000008
+
+// processAlive 判断进程是否存在
+func processAlive(pid int) bool {
+	handle, err := syscall.OpenProcess(syscall.PROCESS_QUERY_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer func() { _ = syscall.CloseHandle(handle) }()
+
+	var code uint32
+	if err := syscall.GetExitCodeProcess(handle, &code); err != nil {
+		return false
+	}
+	return code == 259 // STILL_ACTIVE
+}
+
+// detachedAttr 后台进程不关联控制台
+func detachedAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
+
+// terminate Windows 不支持向其他进程发送 SIGTERM，直接结束进程
+func terminate(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// restrictUmask Windows 没有 umask
+func restrictUmask() func() {
+	return func() {}
+}
@@ -0,0 +1,24 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// ListenUnix 监听 Unix 套接字并设置套接字文件的权限
+// 创建时使用只允许所有者读写的 umask，避免在 chmod 之前短暂地以更宽松的权限存在
+func ListenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	restore := restrictUmask()
+	listener, err := net.Listen("unix", path)
+	restore()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to set socket mode: %w", err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+)
+
+// 错误类别，可通过 errors.Is 判断
+var (
+	// ErrNotFound 项目、环境、文件配置、备份等不存在
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists 名称或目标路径冲突
+	ErrAlreadyExists = errors.New("already exists")
+)
+
+// kindError 带类别的错误，错误信息保持不变
+type kindError struct {
+	kind error
+	msg  string
+}
+
+func (e *kindError) Error() string {
+	return e.msg
+}
+
+func (e *kindError) Unwrap() error {
+	return e.kind
+}
+
+// NotFoundf 创建 ErrNotFound 类别的错误
+func NotFoundf(format string, args ...interface{}) error {
+	return &kindError{kind: ErrNotFound, msg: fmt.Sprintf(format, args...)}
+}
+
+// AlreadyExistsf 创建 ErrAlreadyExists 类别的错误
+func AlreadyExistsf(format string, args ...interface{}) error {
+	return &kindError{kind: ErrAlreadyExists, msg: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,114 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// 事件类型
+const (
+	SwitchStarted   = "switch.started"
+	SwitchCompleted = "switch.completed"
+	SwitchFailed    = "switch.failed"
+	Rollback        = "rollback"
+
+	ProjectCreated = "project.created"
+	ProjectUpdated = "project.updated"
+	ProjectDeleted = "project.deleted"
+
+	EnvironmentCreated = "environment.created"
+	EnvironmentUpdated = "environment.updated"
+	EnvironmentDeleted = "environment.deleted"
+
+	FileAdded   = "file.added"
+	FileUpdated = "file.updated"
+	FileRemoved = "file.removed"
+
+	ConfigUpdated = "config.updated"
+
+	// StatusChanged 其他进程（如命令行）修改了当前状态
+	StatusChanged = "status.changed"
+)
+
+// subscriberBuffer 每个订阅者的缓冲区大小，缓冲区满时丢弃事件而不阻塞发布者
+const subscriberBuffer = 64
+
+// Event 项目、环境、文件配置或状态的变更事件
+// ID 为事件总线分配的递增序号；ProjectID 为空表示与具体项目无关（如配置变更）
+type Event struct {
+	ID              uint64    `json:"id"`
+	Type            string    `json:"type"`
+	ProjectID       string    `json:"project_id,omitempty"`
+	ProjectName     string    `json:"project_name,omitempty"`
+	EnvironmentID   string    `json:"environment_id,omitempty"`
+	EnvironmentName string    `json:"environment_name,omitempty"`
+	FileID          string    `json:"file_id,omitempty"`
+	BackupID        string    `json:"backup_id,omitempty"`
+	Error           string    `json:"error,omitempty"`
+	Time            time.Time `json:"time"`
+}
+
+// Bus 进程内的事件总线，发布不会阻塞
+type Bus struct {
+	mu          sync.Mutex
+	seq         uint64
+	subscribers map[chan Event]struct{}
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[chan Event]struct{})}
+}
+
+// Publish 向全部订阅者发布事件，分配事件序号，未设置时间时使用当前时间
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.ID = b.seq
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// 订阅者处理不及时，丢弃事件
+		}
+	}
+}
+
+// Subscribe 订阅全部事件，返回事件通道和取消订阅函数
+func (b *Bus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// defaultBus 进程内共享的事件总线
+var defaultBus = NewBus()
+
+// Publish 向默认事件总线发布事件
+func Publish(event Event) {
+	defaultBus.Publish(event)
+}
+
+// Subscribe 订阅默认事件总线
+func Subscribe() (<-chan Event, func()) {
+	return defaultBus.Subscribe()
+}
@@ -0,0 +1,68 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusPublishSubscribe(t *testing.T) {
+	bus := NewBus()
+
+	first, cancelFirst := bus.Subscribe()
+	second, cancelSecond := bus.Subscribe()
+	defer cancelSecond()
+
+	bus.Publish(Event{Type: SwitchCompleted, ProjectID: "p1", EnvironmentID: "e1"})
+
+	for _, ch := range []<-chan Event{first, second} {
+		select {
+		case event := <-ch:
+			if event.Type != SwitchCompleted || event.ProjectID != "p1" {
+				t.Errorf("Unexpected event: %+v", event)
+			}
+			if event.Time.IsZero() {
+				t.Error("Expected event time to be set")
+			}
+			if event.ID != 1 {
+				t.Errorf("Expected event ID 1, got %d", event.ID)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+
+	// 取消订阅后通道关闭，不再接收事件
+	cancelFirst()
+	cancelFirst()
+	bus.Publish(Event{Type: ProjectCreated})
+	if _, ok := <-first; ok {
+		t.Error("Expected channel to be closed after cancel")
+	}
+	if event := <-second; event.Type != ProjectCreated || event.ID != 2 {
+		t.Errorf("Expected %s with ID 2, got %s (%d)", ProjectCreated, event.Type, event.ID)
+	}
+}
+
+func TestBusDropsWhenSubscriberIsSlow(t *testing.T) {
+	bus := NewBus()
+	ch, cancel := bus.Subscribe()
+	defer cancel()
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < subscriberBuffer*2; i++ {
+			bus.Publish(Event{Type: FileUpdated})
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+
+	if len(ch) != subscriberBuffer {
+		t.Errorf("Expected %d buffered events, got %d", subscriberBuffer, len(ch))
+	}
+}
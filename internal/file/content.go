@@ -0,0 +1,203 @@
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/events"
+	"github.com/zoyopei/envswitch/internal/storage"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MaxContentSize 通过 Web 界面和 API 查看或保存的文件大小上限
+const MaxContentSize = 1 << 20
+
+// ErrContentTooLarge 文件超过 MaxContentSize
+var ErrContentTooLarge = errors.New("file is too large")
+
+// FileContent 源文件或目标文件的内容，二进制文件不返回内容
+type FileContent struct {
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	Binary     bool      `json:"binary"`
+	Content    string    `json:"content"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// IsBinary 判断内容是否为二进制：包含 NUL 字节或不是有效的 UTF-8
+func IsBinary(content []byte) bool {
+	return bytes.IndexByte(content, 0) >= 0 || !utf8.Valid(content)
+}
+
+// ValidateContent 按文件扩展名检查 JSON 和 YAML 内容的语法，其他类型不检查
+func ValidateContent(path string, content []byte) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if len(bytes.TrimSpace(content)) > 0 && !json.Valid(content) {
+			var value interface{}
+			err := json.Unmarshal(content, &value)
+			return fmt.Errorf("invalid JSON: %v", err)
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		for {
+			var value interface{}
+			err := decoder.Decode(&value)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("invalid YAML: %v", err)
+			}
+		}
+	}
+	return nil
+}
+
+// readContent 读取文件内容，超过大小上限时返回 ErrContentTooLarge
+func readContent(path string) (*FileContent, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, internal.NotFoundf("file does not exist: %s", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", path)
+	}
+	if info.Size() > MaxContentSize {
+		return nil, fmt.Errorf("%w: %s is %d bytes, the limit is %d bytes", ErrContentTooLarge, path, info.Size(), MaxContentSize)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	content := &FileContent{
+		Path:       path,
+		Size:       info.Size(),
+		Binary:     IsBinary(data),
+		ModifiedAt: info.ModTime(),
+	}
+	if !content.Binary {
+		content.Content = string(data)
+	}
+	return content, nil
+}
+
+// singleFilePaths 解析单文件配置的源路径和目标路径，通配符配置没有单一的文件内容
+func (m *Manager) singleFilePaths(project *internal.Project, fileConfig *internal.FileConfig) (string, string, error) {
+	sourcePath, targetPath, err := m.storage.ResolvePaths(project, fileConfig)
+	if err != nil {
+		return "", "", err
+	}
+	if storage.IsGlobPattern(sourcePath) {
+		return "", "", fmt.Errorf("file config %s maps a glob pattern and has no single file content", fileConfig.ID)
+	}
+	return sourcePath, targetPath, nil
+}
+
+// ReadSourceContent 读取文件配置的源文件内容
+func (m *Manager) ReadSourceContent(project *internal.Project, fileConfig *internal.FileConfig) (*FileContent, error) {
+	sourcePath, _, err := m.singleFilePaths(project, fileConfig)
+	if err != nil {
+		return nil, err
+	}
+	return readContent(sourcePath)
+}
+
+// ReadTargetContent 读取文件配置的目标文件当前的内容
+func (m *Manager) ReadTargetContent(project *internal.Project, fileConfig *internal.FileConfig) (*FileContent, error) {
+	_, targetPath, err := m.singleFilePaths(project, fileConfig)
+	if err != nil {
+		return nil, err
+	}
+	return readContent(targetPath)
+}
+
+// WriteSourceContent 保存文件配置的源文件内容，内容必须是文本，JSON 和 YAML 文件检查语法
+// 托管源文件保存为新版本；引用外部文件的配置不修改外部文件，而是将内容写入托管目录并改为托管，便于复现
+func (m *Manager) WriteSourceContent(projectID, environmentID, fileID string, content []byte) (*internal.FileConfig, error) {
+	if len(content) > MaxContentSize {
+		return nil, fmt.Errorf("%w: %d bytes, the limit is %d bytes", ErrContentTooLarge, len(content), MaxContentSize)
+	}
+	if IsBinary(content) {
+		return nil, fmt.Errorf("binary content is not supported, only text files can be edited")
+	}
+
+	fileConfig, err := m.GetFileConfig(projectID, environmentID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if m.IsGlobFileConfig(fileConfig) {
+		return nil, fmt.Errorf("file config %s maps a glob pattern and has no single file content", fileID)
+	}
+	if err := ValidateContent(fileConfig.SourcePath, content); err != nil {
+		return nil, err
+	}
+
+	if fileConfig.Managed {
+		return m.UpdateManagedSource(projectID, environmentID, fileID, content)
+	}
+
+	project, err := m.storage.LoadProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	var environment *internal.Environment
+	for i := range project.Environments {
+		if project.Environments[i].ID == environmentID {
+			environment = &project.Environments[i]
+			break
+		}
+	}
+	if environment == nil {
+		return nil, internal.NotFoundf("environment not found: %s", environmentID)
+	}
+
+	managedPath, err := m.storage.WriteSource(projectID, environmentID, filepath.Base(fileConfig.SourcePath), content)
+	if err != nil {
+		return nil, err
+	}
+
+	var updated *internal.FileConfig
+	for i := range environment.Files {
+		if environment.Files[i].ID == fileID {
+			updated = &environment.Files[i]
+			break
+		}
+	}
+	updated.SourcePath = managedPath
+	updated.Managed = true
+	updated.Version = 1
+	environment.UpdatedAt = time.Now()
+	project.UpdatedAt = time.Now()
+
+	if err := m.storage.SaveProject(project); err != nil {
+		m.removeManagedSource(updated)
+		return nil, err
+	}
+
+	events.Publish(events.Event{
+		Type:            events.FileUpdated,
+		ProjectID:       project.ID,
+		ProjectName:     project.Name,
+		EnvironmentID:   environment.ID,
+		EnvironmentName: environment.Name,
+		FileID:          fileID,
+	})
+	return updated, nil
+}
@@ -0,0 +1,141 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+func TestValidateContent(t *testing.T) {
+	tests := []struct {
+		path    string
+		content string
+		wantErr bool
+	}{
+		{"config.json", `{"env":"dev"}`, false},
+		{"config.json", `{"env":`, true},
+		{"config.JSON", `[1, 2,]`, true},
+		{"config.json", "", false},
+		{"config.yaml", "env: dev\nport: 8080\n", false},
+		{"config.yml", "env: [dev\n", true},
+		{"config.yaml", "a: 1\n---\nb: 2\n", false},
+		{".env", "{not json", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateContent(tt.path, []byte(tt.content))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateContent(%q, %q) error = %v, wantErr %v", tt.path, tt.content, err, tt.wantErr)
+		}
+	}
+}
+
+func TestIsBinary(t *testing.T) {
+	if IsBinary([]byte("key=value\n中文")) {
+		t.Error("Expected UTF-8 text not to be binary")
+	}
+	if !IsBinary([]byte{'a', 0, 'b'}) {
+		t.Error("Expected content with NUL byte to be binary")
+	}
+	if !IsBinary([]byte{0xff, 0xfe, 'a'}) {
+		t.Error("Expected invalid UTF-8 to be binary")
+	}
+}
+
+func TestReadContent(t *testing.T) {
+	manager, project, tempDir := setupTest(t)
+
+	source := filepath.Join(tempDir, "dev.json")
+	target := filepath.Join(tempDir, "config.json")
+	writeTestFile(t, source, `{"env":"dev"}`)
+
+	fileConfig, err := manager.CreateFileConfig(project.ID, "env-1", &internal.FileConfig{
+		SourcePath: source,
+		TargetPath: target,
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateFileConfig() error = %v", err)
+	}
+
+	content, err := manager.ReadSourceContent(project, fileConfig)
+	if err != nil || content.Content != `{"env":"dev"}` || content.Size != 13 || content.Binary {
+		t.Fatalf("ReadSourceContent() = %+v, %v", content, err)
+	}
+
+	// 目标文件不存在
+	if _, err := manager.ReadTargetContent(project, fileConfig); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+
+	// 二进制文件不返回内容
+	writeTestFile(t, target, "\x00\x01\x02")
+	content, err = manager.ReadTargetContent(project, fileConfig)
+	if err != nil || !content.Binary || content.Content != "" || content.Size != 3 {
+		t.Errorf("ReadTargetContent() = %+v, %v", content, err)
+	}
+
+	// 超过大小上限
+	writeTestFile(t, target, strings.Repeat("a", MaxContentSize+1))
+	if _, err := manager.ReadTargetContent(project, fileConfig); !errors.Is(err, ErrContentTooLarge) {
+		t.Errorf("Expected content too large error, got %v", err)
+	}
+}
+
+func TestWriteSourceContent(t *testing.T) {
+	manager, project, tempDir := setupTest(t)
+
+	source := filepath.Join(tempDir, "dev.yaml")
+	writeTestFile(t, source, "env: dev\n")
+
+	fileConfig, err := manager.CreateFileConfig(project.ID, "env-1", &internal.FileConfig{
+		SourcePath: source,
+		TargetPath: filepath.Join(tempDir, "config.yaml"),
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateFileConfig() error = %v", err)
+	}
+
+	// 语法错误、二进制内容和超过大小上限的内容不保存
+	if _, err := manager.WriteSourceContent(project.ID, "env-1", fileConfig.ID, []byte("env: [dev\n")); err == nil {
+		t.Error("Expected error for invalid YAML")
+	}
+	if _, err := manager.WriteSourceContent(project.ID, "env-1", fileConfig.ID, []byte{'a', 0}); err == nil {
+		t.Error("Expected error for binary content")
+	}
+	if _, err := manager.WriteSourceContent(project.ID, "env-1", fileConfig.ID, make([]byte, MaxContentSize+1)); !errors.Is(err, ErrContentTooLarge) {
+		t.Errorf("Expected content too large error, got %v", err)
+	}
+
+	// 外部源文件保持不变，内容写入托管目录
+	saved, err := manager.WriteSourceContent(project.ID, "env-1", fileConfig.ID, []byte("env: staging\n"))
+	if err != nil {
+		t.Fatalf("WriteSourceContent() error = %v", err)
+	}
+	if !saved.Managed || saved.Version != 1 || filepath.Base(saved.SourcePath) != "dev.yaml" {
+		t.Fatalf("Expected managed file config, got %+v", saved)
+	}
+	if data, _ := os.ReadFile(source); string(data) != "env: dev\n" {
+		t.Errorf("Expected external source to be unchanged, got %q", data)
+	}
+	managedPath := manager.ResolveSourcePath(saved)
+	if data, err := os.ReadFile(managedPath); err != nil || string(data) != "env: staging\n" {
+		t.Fatalf("Expected managed source content, got %q, %v", data, err)
+	}
+
+	// 托管源文件保存为新版本
+	saved, err = manager.WriteSourceContent(project.ID, "env-1", fileConfig.ID, []byte("env: prod\n"))
+	if err != nil || saved.Version != 2 || manager.ResolveSourcePath(saved) != managedPath {
+		t.Fatalf("WriteSourceContent() = %+v, %v", saved, err)
+	}
+	if data, _ := os.ReadFile(managedPath); string(data) != "env: prod\n" {
+		t.Errorf("Expected updated managed source, got %q", data)
+	}
+
+	if _, err := manager.WriteSourceContent(project.ID, "env-1", "missing", []byte("a: 1\n")); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
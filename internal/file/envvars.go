@@ -0,0 +1,107 @@
+package file
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+)
+
+// SupportedShells shell-env 支持的 shell 类型
+var SupportedShells = []string{"bash", "zsh", "fish", "powershell"}
+
+// DetectShell 根据 $SHELL 推断当前 shell，Windows 下默认 PowerShell
+func DetectShell() string {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	switch shell {
+	case "zsh", "fish", "bash":
+		return shell
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "bash"
+}
+
+// FormatShellEnv 生成设置环境变量的 shell 语句，可直接 eval
+func FormatShellEnv(shell string, vars map[string]string) (string, error) {
+	var b strings.Builder
+
+	for _, name := range config.SortedVariableNames(vars) {
+		value := vars[name]
+		switch strings.ToLower(shell) {
+		case "bash", "zsh", "sh":
+			fmt.Fprintf(&b, "export %s=%s\n", name, quotePOSIX(value))
+		case "fish":
+			fmt.Fprintf(&b, "set -gx %s %s;\n", name, quoteFish(value))
+		case "powershell", "pwsh":
+			fmt.Fprintf(&b, "$env:%s = %s\n", name, quotePowerShell(value))
+		default:
+			return "", fmt.Errorf("unsupported shell '%s', expected one of: %s", shell, strings.Join(SupportedShells, ", "))
+		}
+	}
+
+	return b.String(), nil
+}
+
+// FormatDotEnv 生成 .env 文件内容
+func FormatDotEnv(vars map[string]string) string {
+	var b strings.Builder
+
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`)
+	for _, name := range config.SortedVariableNames(vars) {
+		fmt.Fprintf(&b, "%s=\"%s\"\n", name, replacer.Replace(vars[name]))
+	}
+
+	return b.String()
+}
+
+// ResolveDotEnvPath 获取环境 .env 文件的实际路径，未配置时返回空字符串
+func (m *Manager) ResolveDotEnvPath(project *internal.Project, env *internal.Environment) (string, error) {
+	if env.DotEnvPath == "" {
+		return "", nil
+	}
+	return config.ExpandPath(env.DotEnvPath, config.PathVariables(project))
+}
+
+// writeDotEnv 将环境变量写入 .env 文件
+func (m *Manager) writeDotEnv(project *internal.Project, env *internal.Environment) error {
+	dotEnvPath, err := m.ResolveDotEnvPath(project, env)
+	if err != nil || dotEnvPath == "" {
+		return err
+	}
+
+	return writeDotEnvFile(dotEnvPath, env.Variables)
+}
+
+// writeDotEnvFile 写入 .env 文件，必要时创建所在目录
+func writeDotEnvFile(dotEnvPath string, vars map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(dotEnvPath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dotEnvPath, err)
+	}
+
+	if err := os.WriteFile(dotEnvPath, []byte(FormatDotEnv(vars)), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dotEnvPath, err)
+	}
+
+	return nil
+}
+
+// quotePOSIX 使用单引号转义 bash/zsh 值
+func quotePOSIX(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// quoteFish 使用单引号转义 fish 值
+func quoteFish(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// quotePowerShell 使用单引号转义 PowerShell 值
+func quotePowerShell(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
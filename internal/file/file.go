@@ -1,14 +1,18 @@
 package file
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/zoyopei/envswitch/internal"
 	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/events"
+	"github.com/zoyopei/envswitch/internal/metrics"
 	"github.com/zoyopei/envswitch/internal/storage"
 
 	"github.com/google/uuid"
@@ -25,19 +29,91 @@ func NewManager() *Manager {
 	}
 }
 
-// SwitchEnvironment 切换到指定环境
+// SwitchEnvironment 切换到指定环境，切换开始、完成和失败时发布事件并记录指标
 func (m *Manager) SwitchEnvironment(projectID, environmentID string) error {
+	event := m.newEvent(events.SwitchStarted, projectID, environmentID)
+	events.Publish(event)
+
+	start := time.Now()
+	err := m.switchEnvironment(projectID, environmentID)
+	recordSwitch(event, err, time.Since(start))
+	if err != nil {
+		event.Type = events.SwitchFailed
+		event.Error = err.Error()
+	} else {
+		event.Type = events.SwitchCompleted
+		if state, err := m.storage.LoadAppState(); err == nil {
+			event.BackupID = state.BackupID
+		}
+	}
+	events.Publish(event)
+
+	return err
+}
+
+// switchError 切换失败的错误，记录失败发生的阶段，错误信息保持不变
+type switchError struct {
+	reason string
+	err    error
+}
+
+func (e *switchError) Error() string {
+	return e.err.Error()
+}
+
+func (e *switchError) Unwrap() error {
+	return e.err
+}
+
+// failed 包装切换失败的错误，不存在和权限不足的错误按类别归类，其他按阶段归类
+func failed(stage string, err error) error {
+	reason := stage
+	switch {
+	case errors.Is(err, fs.ErrPermission):
+		reason = metrics.ReasonPermission
+	case stage == metrics.ReasonFile && (errors.Is(err, internal.ErrNotFound) || errors.Is(err, fs.ErrNotExist)):
+		reason = metrics.ReasonSource
+	case errors.Is(err, internal.ErrNotFound):
+		reason = metrics.ReasonNotFound
+	}
+	return &switchError{reason: reason, err: err}
+}
+
+// recordSwitch 记录切换次数、耗时和失败原因，项目和环境使用名称作为标签
+func recordSwitch(event events.Event, err error, elapsed time.Duration) {
+	project, environment := event.ProjectName, event.EnvironmentName
+	if project == "" {
+		project = event.ProjectID
+	}
+	if environment == "" {
+		environment = event.EnvironmentID
+	}
+
+	metrics.Switches.Inc(project, environment, metrics.Result(err))
+	metrics.SwitchDuration.Observe(elapsed.Seconds(), project, environment)
+	if err != nil {
+		reason := metrics.ReasonState
+		var se *switchError
+		if errors.As(err, &se) {
+			reason = se.reason
+		}
+		metrics.SwitchFailures.Inc(project, environment, reason)
+	}
+}
+
+// switchEnvironment 执行环境切换
+func (m *Manager) switchEnvironment(projectID, environmentID string) error {
 	// 首先创建备份
 	backupID, err := m.CreateBackup(projectID, environmentID)
 	if err != nil {
-		return fmt.Errorf("failed to create backup: %w", err)
+		return failed(metrics.ReasonBackup, fmt.Errorf("failed to create backup: %w", err))
 	}
 
 	// 加载项目和环境信息
 	storage := storage.NewStorage()
 	project, err := storage.LoadProject(projectID)
 	if err != nil {
-		return fmt.Errorf("failed to load project: %w", err)
+		return failed(metrics.ReasonState, fmt.Errorf("failed to load project: %w", err))
 	}
 
 	var envIndex = -1
@@ -49,59 +125,83 @@ func (m *Manager) SwitchEnvironment(projectID, environmentID string) error {
 	}
 
 	if envIndex == -1 {
-		return fmt.Errorf("environment not found: %s", environmentID)
+		return failed(metrics.ReasonNotFound, internal.NotFoundf("environment not found: %s", environmentID))
 	}
 
 	environment := &project.Environments[envIndex]
 
 	// 执行文件切换
 	for _, fileConfig := range environment.Files {
-		if err := m.switchFile(&fileConfig); err != nil {
+		if err := m.switchFile(project, &fileConfig); err != nil {
 			// 如果切换失败，尝试回滚
 			_ = m.RollbackFromBackup(backupID)
-			return fmt.Errorf("failed to switch file %s: %w", fileConfig.TargetPath, err)
+			return failed(metrics.ReasonFile, fmt.Errorf("failed to switch file %s: %w", fileConfig.TargetPath, err))
 		}
 	}
 
+	// 写入 .env 文件
+	if err := m.writeDotEnv(project, environment); err != nil {
+		_ = m.RollbackFromBackup(backupID)
+		return failed(metrics.ReasonDotEnv, err)
+	}
+
 	// 更新环境的最后切换时间
 	now := time.Now()
 	environment.LastSwitchAt = &now
 
 	// 保存项目更新
 	if err := storage.SaveProject(project); err != nil {
-		return fmt.Errorf("failed to update project: %w", err)
+		return failed(metrics.ReasonState, fmt.Errorf("failed to update project: %w", err))
 	}
 
 	// 更新应用状态
 	state := &internal.AppState{
 		CurrentProject:     projectID,
 		CurrentEnvironment: environmentID,
+		ProjectName:        project.Name,
+		EnvironmentName:    environment.Name,
 		LastSwitchAt:       &now,
 		BackupID:           backupID,
 	}
 
 	if err := storage.SaveAppState(state); err != nil {
-		return fmt.Errorf("failed to save app state: %w", err)
+		return failed(metrics.ReasonState, fmt.Errorf("failed to save app state: %w", err))
 	}
 
 	return nil
 }
 
-// switchFile 切换单个文件
-func (m *Manager) switchFile(fileConfig *internal.FileConfig) error {
+// switchFile 切换单个文件配置（通配符配置切换全部匹配文件）
+func (m *Manager) switchFile(project *internal.Project, fileConfig *internal.FileConfig) error {
+	mappings, err := m.storage.ExpandFileConfig(project, fileConfig)
+	if err != nil {
+		return err
+	}
+
+	for _, mapping := range mappings {
+		if err := m.copyMapping(mapping.SourcePath, mapping.TargetPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyMapping 将源文件复制到目标路径
+func (m *Manager) copyMapping(sourcePath, targetPath string) error {
 	// 检查源文件是否存在
-	if _, err := os.Stat(fileConfig.SourcePath); os.IsNotExist(err) {
-		return fmt.Errorf("source file does not exist: %s", fileConfig.SourcePath)
+	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+		return internal.NotFoundf("source file does not exist: %s", sourcePath)
 	}
 
 	// 确保目标目录存在
-	targetDir := filepath.Dir(fileConfig.TargetPath)
+	targetDir := filepath.Dir(targetPath)
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
 		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
 	// 复制文件
-	if err := m.copyFile(fileConfig.SourcePath, fileConfig.TargetPath); err != nil {
+	if err := m.copyFile(sourcePath, targetPath); err != nil {
 		return fmt.Errorf("failed to copy file: %w", err)
 	}
 
@@ -153,7 +253,7 @@ func (m *Manager) CreateBackup(projectID, environmentID string) (string, error)
 	}
 
 	if environment == nil {
-		return "", fmt.Errorf("environment not found: %s", environmentID)
+		return "", internal.NotFoundf("environment not found: %s", environmentID)
 	}
 
 	backupID := uuid.New().String()
@@ -165,23 +265,42 @@ func (m *Manager) CreateBackup(projectID, environmentID string) (string, error)
 
 	backupFiles := make(map[string]string)
 
-	// 备份每个目标文件
+	// 需要备份的目标文件（通配符配置展开为全部目标文件，包括 .env 文件）
+	var targets []string
 	for _, fileConfig := range environment.Files {
-		if _, err := os.Stat(fileConfig.TargetPath); os.IsNotExist(err) {
+		mappings, err := m.storage.ExpandFileConfig(project, &fileConfig)
+		if err != nil {
+			return "", err
+		}
+		for _, mapping := range mappings {
+			targets = append(targets, mapping.TargetPath)
+		}
+	}
+	dotEnvPath, err := m.ResolveDotEnvPath(project, environment)
+	if err != nil {
+		return "", err
+	}
+	if dotEnvPath != "" {
+		targets = append(targets, dotEnvPath)
+	}
+
+	// 备份每个目标文件
+	for _, targetPath := range targets {
+		if _, err := os.Stat(targetPath); os.IsNotExist(err) {
 			// 目标文件不存在，跳过备份
 			continue
 		}
 
 		// 生成备份文件路径
-		backupFileName := fmt.Sprintf("%s_%s", filepath.Base(fileConfig.TargetPath), uuid.New().String())
+		backupFileName := fmt.Sprintf("%s_%s", filepath.Base(targetPath), uuid.New().String())
 		backupFilePath := filepath.Join(backupDir, backupFileName)
 
 		// 复制文件到备份位置
-		if err := m.copyFile(fileConfig.TargetPath, backupFilePath); err != nil {
-			return "", fmt.Errorf("failed to backup file %s: %w", fileConfig.TargetPath, err)
+		if err := m.copyFile(targetPath, backupFilePath); err != nil {
+			return "", fmt.Errorf("failed to backup file %s: %w", targetPath, err)
 		}
 
-		backupFiles[fileConfig.TargetPath] = backupFilePath
+		backupFiles[targetPath] = backupFilePath
 	}
 
 	// 保存备份信息
@@ -200,8 +319,15 @@ func (m *Manager) CreateBackup(projectID, environmentID string) (string, error)
 	return backupID, nil
 }
 
-// RollbackFromBackup 从备份回滚
+// RollbackFromBackup 从备份回滚，并记录回滚结果
 func (m *Manager) RollbackFromBackup(backupID string) error {
+	err := m.rollbackFromBackup(backupID)
+	metrics.Rollbacks.Inc(metrics.Result(err))
+	return err
+}
+
+// rollbackFromBackup 执行回滚
+func (m *Manager) rollbackFromBackup(backupID string) error {
 	storage := storage.NewStorage()
 	backup, err := storage.LoadBackupInfo(backupID)
 	if err != nil {
@@ -221,16 +347,35 @@ func (m *Manager) RollbackFromBackup(backupID string) error {
 		return fmt.Errorf("failed to update app state: %w", err)
 	}
 
+	event := m.newEvent(events.Rollback, backup.ProjectID, backup.EnvID)
+	event.BackupID = backupID
+	events.Publish(event)
+
 	return nil
 }
 
+// newEvent 创建事件，并从项目数据中补全项目和环境名称
+func (m *Manager) newEvent(eventType, projectID, environmentID string) events.Event {
+	event := events.Event{Type: eventType, ProjectID: projectID, EnvironmentID: environmentID}
+	if project, err := m.storage.LoadProject(projectID); err == nil {
+		event.ProjectName = project.Name
+		for _, env := range project.Environments {
+			if env.ID == environmentID {
+				event.EnvironmentName = env.Name
+				break
+			}
+		}
+	}
+	return event
+}
+
 // GetCurrentState 获取当前状态
 func (m *Manager) GetCurrentState() (*internal.AppState, error) {
 	return m.storage.LoadAppState()
 }
 
-// ValidateFileConfig 验证文件配置
-func (m *Manager) ValidateFileConfig(fileConfig *internal.FileConfig) error {
+// ValidateFileConfig 验证文件配置，路径按所属项目的根目录和路径变量解析
+func (m *Manager) ValidateFileConfig(project *internal.Project, fileConfig *internal.FileConfig) error {
 	if fileConfig.SourcePath == "" {
 		return fmt.Errorf("source path cannot be empty")
 	}
@@ -239,36 +384,154 @@ func (m *Manager) ValidateFileConfig(fileConfig *internal.FileConfig) error {
 		return fmt.Errorf("target path cannot be empty")
 	}
 
+	sourcePath, targetPath, err := m.storage.ResolvePaths(project, fileConfig)
+	if err != nil {
+		return err
+	}
+
+	// 通配符配置：至少匹配一个文件（除非允许为空），目标路径为目录
+	if storage.IsGlobPattern(sourcePath) {
+		if _, err := m.storage.ExpandFileConfig(project, fileConfig); err != nil {
+			return err
+		}
+		if info, err := os.Stat(targetPath); err == nil && !info.IsDir() {
+			return fmt.Errorf("target of glob pattern must be a directory: %s", describePath(fileConfig.TargetPath, targetPath))
+		}
+		if err := os.MkdirAll(targetPath, 0755); err != nil {
+			return fmt.Errorf("cannot create target directory %s: %w", describePath(fileConfig.TargetPath, targetPath), err)
+		}
+		return nil
+	}
+
 	// 检查源文件是否存在
-	if _, err := os.Stat(fileConfig.SourcePath); os.IsNotExist(err) {
-		return fmt.Errorf("source file does not exist: %s", fileConfig.SourcePath)
+	if _, err := os.Stat(sourcePath); os.IsNotExist(err) {
+		return fmt.Errorf("source file does not exist: %s", describePath(fileConfig.SourcePath, sourcePath))
 	}
 
 	// 检查目标路径是否有效
-	targetDir := filepath.Dir(fileConfig.TargetPath)
+	targetDir := filepath.Dir(targetPath)
 	if targetDir != "." {
 		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			return fmt.Errorf("cannot create target directory %s: %w", targetDir, err)
+			return fmt.Errorf("cannot create target directory %s: %w", describePath(filepath.Dir(fileConfig.TargetPath), targetDir), err)
 		}
 	}
 
 	return nil
 }
 
+// describePath 同时显示存储路径和解析后的路径（两者不同时）
+func describePath(stored, resolved string) string {
+	if stored == resolved {
+		return stored
+	}
+	return fmt.Sprintf("%s (resolved to %s)", stored, resolved)
+}
+
 // AddFileConfig 添加文件配置到环境
 func (m *Manager) AddFileConfig(projectID, environmentID, sourcePath, targetPath, description string) error {
-	fileConfig := &internal.FileConfig{
-		ID:          uuid.New().String(),
+	_, err := m.CreateFileConfig(projectID, environmentID, &internal.FileConfig{
 		SourcePath:  sourcePath,
 		TargetPath:  targetPath,
 		Description: description,
+	}, false)
+	return err
+}
+
+// ImportFileConfig 导入源文件到托管目录并添加文件配置，源文件被移动或删除后仍可切换
+func (m *Manager) ImportFileConfig(projectID, environmentID, sourcePath, targetPath, description string) (*internal.FileConfig, error) {
+	return m.CreateFileConfig(projectID, environmentID, &internal.FileConfig{
+		SourcePath:  sourcePath,
+		TargetPath:  targetPath,
+		Description: description,
+	}, true)
+}
+
+// CreateFileConfig 验证并添加文件配置，importSource 为 true 时将源文件（或通配符匹配的全部文件）导入托管目录
+func (m *Manager) CreateFileConfig(projectID, environmentID string, fileConfig *internal.FileConfig, importSource bool) (*internal.FileConfig, error) {
+	if fileConfig.ID == "" {
+		fileConfig.ID = uuid.New().String()
 	}
 
-	// 验证文件配置
-	if err := m.ValidateFileConfig(fileConfig); err != nil {
+	project, err := m.storage.LoadProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	// 验证文件配置（导入时验证原始源文件）
+	if err := m.ValidateFileConfig(project, fileConfig); err != nil {
+		return nil, err
+	}
+
+	if !importSource {
+		if err := m.addFileConfig(projectID, environmentID, fileConfig); err != nil {
+			return nil, err
+		}
+		return fileConfig, nil
+	}
+
+	if err := m.importSource(project, environmentID, fileConfig); err != nil {
+		return nil, err
+	}
+
+	if err := m.addFileConfig(projectID, environmentID, fileConfig); err != nil {
+		m.removeManagedSource(fileConfig)
+		return nil, err
+	}
+
+	return fileConfig, nil
+}
+
+// importSource 将文件配置的源文件（或通配符匹配的全部文件）导入托管目录，并将配置改为托管模式
+func (m *Manager) importSource(project *internal.Project, environmentID string, fileConfig *internal.FileConfig) error {
+	resolvedSource, _, err := m.storage.ResolvePaths(project, fileConfig)
+	if err != nil {
 		return err
 	}
 
+	var managedPath string
+	if storage.IsGlobPattern(resolvedSource) {
+		// 通配符：导入全部匹配文件，托管路径保留剩余模式
+		base, files, err := storage.GlobFiles(resolvedSource)
+		if err != nil {
+			return err
+		}
+		managedDir, err := m.storage.ImportSourceTree(project.ID, environmentID, base, files)
+		if err != nil {
+			return err
+		}
+		_, rest := storage.SplitGlobPattern(resolvedSource)
+		managedPath = filepath.Join(managedDir, filepath.FromSlash(rest))
+	} else {
+		managedPath, err = m.storage.ImportSource(project.ID, environmentID, resolvedSource)
+		if err != nil {
+			return err
+		}
+	}
+
+	fileConfig.SourcePath = managedPath
+	fileConfig.Managed = true
+	fileConfig.Version = 1
+	return nil
+}
+
+// ExpandFileConfig 将文件配置展开为具体的文件映射（通配符配置展开为全部匹配文件）
+func (m *Manager) ExpandFileConfig(project *internal.Project, fileConfig *internal.FileConfig) ([]internal.FileMapping, error) {
+	return m.storage.ExpandFileConfig(project, fileConfig)
+}
+
+// removeManagedSource 删除托管源文件，通配符配置删除整个托管目录
+func (m *Manager) removeManagedSource(fileConfig *internal.FileConfig) {
+	sourcePath := m.storage.ResolveSourcePath(fileConfig)
+	if storage.IsGlobPattern(sourcePath) {
+		base, _ := storage.SplitGlobPattern(sourcePath)
+		_ = os.RemoveAll(base)
+		return
+	}
+	_ = os.Remove(sourcePath)
+}
+
+// addFileConfig 将已验证的文件配置保存到环境
+func (m *Manager) addFileConfig(projectID, environmentID string, fileConfig *internal.FileConfig) error {
 	// 加载项目
 	project, err := m.storage.LoadProject(projectID)
 	if err != nil {
@@ -285,14 +548,11 @@ func (m *Manager) AddFileConfig(projectID, environmentID, sourcePath, targetPath
 	}
 
 	if envIndex == -1 {
-		return fmt.Errorf("environment not found: %s", environmentID)
+		return internal.NotFoundf("environment not found: %s", environmentID)
 	}
 
-	// 检查是否已存在相同的目标路径
-	for _, existingFile := range project.Environments[envIndex].Files {
-		if existingFile.TargetPath == targetPath {
-			return fmt.Errorf("file config with target path '%s' already exists", targetPath)
-		}
+	if err := m.checkDuplicateTarget(project, &project.Environments[envIndex], fileConfig); err != nil {
+		return err
 	}
 
 	// 添加文件配置
@@ -300,7 +560,260 @@ func (m *Manager) AddFileConfig(projectID, environmentID, sourcePath, targetPath
 	project.Environments[envIndex].UpdatedAt = time.Now()
 	project.UpdatedAt = time.Now()
 
-	return m.storage.SaveProject(project)
+	if err := m.storage.SaveProject(project); err != nil {
+		return err
+	}
+
+	events.Publish(events.Event{
+		Type:            events.FileAdded,
+		ProjectID:       project.ID,
+		ProjectName:     project.Name,
+		EnvironmentID:   environmentID,
+		EnvironmentName: project.Environments[envIndex].Name,
+		FileID:          fileConfig.ID,
+	})
+	return nil
+}
+
+// checkDuplicateTarget 检查环境中其他文件配置是否已使用相同的目标路径（比较存储路径和解析后的路径）
+func (m *Manager) checkDuplicateTarget(project *internal.Project, env *internal.Environment, fileConfig *internal.FileConfig) error {
+	_, newTarget, _ := m.storage.ResolvePaths(project, fileConfig)
+	for _, existingFile := range env.Files {
+		if existingFile.ID == fileConfig.ID {
+			continue
+		}
+		_, existingTarget, _ := m.storage.ResolvePaths(project, &existingFile)
+		if existingFile.TargetPath == fileConfig.TargetPath || (newTarget != "" && existingTarget == newTarget) {
+			return internal.AlreadyExistsf("file config with target path '%s' already exists", fileConfig.TargetPath)
+		}
+	}
+	return nil
+}
+
+// GetFileConfig 获取环境中的文件配置
+func (m *Manager) GetFileConfig(projectID, environmentID, fileID string) (*internal.FileConfig, error) {
+	project, err := m.storage.LoadProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	for _, env := range project.Environments {
+		if env.ID != environmentID {
+			continue
+		}
+		for _, fileConfig := range env.Files {
+			if fileConfig.ID == fileID {
+				return &fileConfig, nil
+			}
+		}
+		return nil, internal.NotFoundf("file config not found: %s", fileID)
+	}
+
+	return nil, internal.NotFoundf("environment not found: %s", environmentID)
+}
+
+// ResolveSourcePath 获取文件配置源文件的实际路径
+func (m *Manager) ResolveSourcePath(fileConfig *internal.FileConfig) string {
+	return m.storage.ResolveSourcePath(fileConfig)
+}
+
+// IsGlobFileConfig 判断文件配置的源路径是否为通配符模式（托管源文件按数据目录中的实际路径判断）
+func (m *Manager) IsGlobFileConfig(fileConfig *internal.FileConfig) bool {
+	return storage.IsGlobPattern(m.storage.ResolveSourcePath(fileConfig))
+}
+
+// ResolvePaths 获取文件配置在当前机器上的实际源路径和目标路径
+func (m *Manager) ResolvePaths(project *internal.Project, fileConfig *internal.FileConfig) (string, string, error) {
+	return m.storage.ResolvePaths(project, fileConfig)
+}
+
+// UpdateManagedSource 更新托管源文件内容，旧内容保存为历史版本
+func (m *Manager) UpdateManagedSource(projectID, environmentID, fileID string, content []byte) (*internal.FileConfig, error) {
+	project, err := m.storage.LoadProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	var fileConfig *internal.FileConfig
+	var environment *internal.Environment
+	for i := range project.Environments {
+		if project.Environments[i].ID != environmentID {
+			continue
+		}
+		environment = &project.Environments[i]
+		for j := range environment.Files {
+			if environment.Files[j].ID == fileID {
+				fileConfig = &environment.Files[j]
+				break
+			}
+		}
+		break
+	}
+
+	if environment == nil {
+		return nil, internal.NotFoundf("environment not found: %s", environmentID)
+	}
+	if fileConfig == nil {
+		return nil, internal.NotFoundf("file config not found: %s", fileID)
+	}
+	if !fileConfig.Managed {
+		return nil, fmt.Errorf("file config %s is not managed, re-add it with --import to edit its source", fileID)
+	}
+	if m.IsGlobFileConfig(fileConfig) {
+		return nil, fmt.Errorf("file config %s maps a glob pattern and cannot be edited as a single file", fileID)
+	}
+
+	// 保存当前版本
+	if err := m.storage.SaveSourceVersion(projectID, environmentID, fileConfig); err != nil {
+		return nil, err
+	}
+
+	sourcePath := m.storage.ResolveSourcePath(fileConfig)
+	previous, err := os.ReadFile(sourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source file: %w", err)
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(sourcePath); err == nil {
+		mode = info.Mode()
+	}
+	if err := os.WriteFile(sourcePath, content, mode); err != nil {
+		return nil, fmt.Errorf("failed to write source file: %w", err)
+	}
+
+	// 重新验证文件配置，失败时恢复原内容
+	if err := m.ValidateFileConfig(project, fileConfig); err != nil {
+		_ = os.WriteFile(sourcePath, previous, mode)
+		return nil, err
+	}
+
+	if fileConfig.Version == 0 {
+		fileConfig.Version = 1
+	}
+	fileConfig.Version++
+	environment.UpdatedAt = time.Now()
+	project.UpdatedAt = time.Now()
+
+	if err := m.storage.SaveProject(project); err != nil {
+		return nil, err
+	}
+
+	events.Publish(events.Event{
+		Type:            events.FileUpdated,
+		ProjectID:       project.ID,
+		ProjectName:     project.Name,
+		EnvironmentID:   environment.ID,
+		EnvironmentName: environment.Name,
+		FileID:          fileConfig.ID,
+	})
+	return fileConfig, nil
+}
+
+// UpdateFileConfig 更新文件配置，文件配置 ID 保持不变
+// 支持的字段：source_path、target_path、description、allow_empty 和 managed（源文件模式）
+// managed 为 true 时将源文件导入托管目录；为 false 时改为引用外部源文件，必须同时指定 source_path
+// 托管配置只修改 source_path 时重新导入新的源文件；被替换的托管源文件在保存成功后删除
+func (m *Manager) UpdateFileConfig(projectID, environmentID, fileID string, updates map[string]interface{}) (*internal.FileConfig, error) {
+	project, err := m.storage.LoadProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load project: %w", err)
+	}
+
+	var envIndex, fileIndex = -1, -1
+	for i := range project.Environments {
+		if project.Environments[i].ID != environmentID {
+			continue
+		}
+		envIndex = i
+		for j, file := range project.Environments[i].Files {
+			if file.ID == fileID {
+				fileIndex = j
+				break
+			}
+		}
+		break
+	}
+
+	if envIndex == -1 {
+		return nil, internal.NotFoundf("environment not found: %s", environmentID)
+	}
+	if fileIndex == -1 {
+		return nil, internal.NotFoundf("file config not found: %s", fileID)
+	}
+
+	environment := &project.Environments[envIndex]
+	original := environment.Files[fileIndex]
+	updated := original
+
+	// 应用更新
+	if description, ok := updates["description"].(string); ok {
+		updated.Description = description
+	}
+	if targetPath, ok := updates["target_path"].(string); ok {
+		updated.TargetPath = targetPath
+	}
+	if allowEmpty, ok := updates["allow_empty"].(bool); ok {
+		updated.AllowEmpty = allowEmpty
+	}
+
+	sourcePath, sourceChanged := updates["source_path"].(string)
+	managed := original.Managed
+	if value, ok := updates["managed"].(bool); ok {
+		managed = value
+	}
+	if !managed && original.Managed && !sourceChanged {
+		return nil, fmt.Errorf("a source path is required to stop managing file config %s", fileID)
+	}
+
+	// 源文件变化或改为托管时，先按外部源文件验证
+	reimport := managed && (sourceChanged || !original.Managed)
+	if sourceChanged || !managed {
+		if sourceChanged {
+			updated.SourcePath = sourcePath
+		}
+		updated.Managed = false
+		updated.Version = 0
+	}
+
+	if err := m.ValidateFileConfig(project, &updated); err != nil {
+		return nil, err
+	}
+	if err := m.checkDuplicateTarget(project, environment, &updated); err != nil {
+		return nil, err
+	}
+
+	if reimport {
+		if err := m.importSource(project, environmentID, &updated); err != nil {
+			return nil, err
+		}
+	}
+
+	environment.Files[fileIndex] = updated
+	environment.UpdatedAt = time.Now()
+	project.UpdatedAt = time.Now()
+
+	if err := m.storage.SaveProject(project); err != nil {
+		if reimport {
+			m.removeManagedSource(&updated)
+		}
+		return nil, err
+	}
+
+	// 删除被替换的托管源文件
+	if original.Managed && (!updated.Managed || updated.SourcePath != original.SourcePath) {
+		m.removeManagedSource(&original)
+	}
+
+	events.Publish(events.Event{
+		Type:            events.FileUpdated,
+		ProjectID:       project.ID,
+		ProjectName:     project.Name,
+		EnvironmentID:   environment.ID,
+		EnvironmentName: environment.Name,
+		FileID:          fileID,
+	})
+	return &updated, nil
 }
 
 // RemoveFileConfig 从环境移除文件配置
@@ -320,7 +833,7 @@ func (m *Manager) RemoveFileConfig(projectID, environmentID, fileID string) erro
 	}
 
 	if envIndex == -1 {
-		return fmt.Errorf("environment not found: %s", environmentID)
+		return internal.NotFoundf("environment not found: %s", environmentID)
 	}
 
 	// 找到文件配置
@@ -333,16 +846,34 @@ func (m *Manager) RemoveFileConfig(projectID, environmentID, fileID string) erro
 	}
 
 	if fileIndex == -1 {
-		return fmt.Errorf("file config not found: %s", fileID)
+		return internal.NotFoundf("file config not found: %s", fileID)
 	}
 
 	// 移除文件配置
 	files := project.Environments[envIndex].Files
+	removed := files[fileIndex]
 	project.Environments[envIndex].Files = append(files[:fileIndex], files[fileIndex+1:]...)
 	project.Environments[envIndex].UpdatedAt = time.Now()
 	project.UpdatedAt = time.Now()
 
-	return m.storage.SaveProject(project)
+	if err := m.storage.SaveProject(project); err != nil {
+		return err
+	}
+
+	// 删除托管源文件
+	if removed.Managed {
+		m.removeManagedSource(&removed)
+	}
+
+	events.Publish(events.Event{
+		Type:            events.FileRemoved,
+		ProjectID:       project.ID,
+		ProjectName:     project.Name,
+		EnvironmentID:   environmentID,
+		EnvironmentName: project.Environments[envIndex].Name,
+		FileID:          fileID,
+	})
+	return nil
 }
 
 // CleanupOldBackups 清理旧备份
@@ -0,0 +1,218 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/metrics"
+)
+
+func setupTest(t *testing.T) (*Manager, *internal.Project, string) {
+	tempDir := t.TempDir()
+
+	// 保存原始配置并在测试结束后恢复
+	originalConfig := config.GetConfig()
+	t.Cleanup(func() {
+		_ = config.SaveConfig(originalConfig)
+	})
+
+	testConfig := &internal.Config{
+		DataDir:   filepath.Join(tempDir, "data"),
+		BackupDir: filepath.Join(tempDir, "backups"),
+		WebPort:   8080,
+	}
+	if err := config.SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save test config: %v", err)
+	}
+
+	manager := NewManager()
+	project := &internal.Project{
+		ID:   "project-1",
+		Name: "test-project",
+		Environments: []internal.Environment{
+			{ID: "env-1", Name: "dev"},
+		},
+	}
+	if err := manager.storage.SaveProject(project); err != nil {
+		t.Fatalf("Failed to save project: %v", err)
+	}
+
+	return manager, project, tempDir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateFileConfig(t *testing.T) {
+	manager, project, tempDir := setupTest(t)
+
+	devSource := filepath.Join(tempDir, "dev.json")
+	localSource := filepath.Join(tempDir, "local.json")
+	writeTestFile(t, devSource, `{"env":"dev"}`)
+	writeTestFile(t, localSource, `{"env":"local"}`)
+
+	fileConfig, err := manager.CreateFileConfig(project.ID, "env-1", &internal.FileConfig{
+		SourcePath: devSource,
+		TargetPath: filepath.Join(tempDir, "app", "config.json"),
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateFileConfig() error = %v", err)
+	}
+	other, err := manager.CreateFileConfig(project.ID, "env-1", &internal.FileConfig{
+		SourcePath: devSource,
+		TargetPath: filepath.Join(tempDir, "app", "other.json"),
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateFileConfig() error = %v", err)
+	}
+
+	// 修改源、目标和描述，ID 保持不变
+	newTarget := filepath.Join(tempDir, "app", "settings.json")
+	updated, err := manager.UpdateFileConfig(project.ID, "env-1", fileConfig.ID, map[string]interface{}{
+		"source_path": localSource,
+		"target_path": newTarget,
+		"description": "Local settings",
+	})
+	if err != nil {
+		t.Fatalf("UpdateFileConfig() error = %v", err)
+	}
+	if updated.ID != fileConfig.ID || updated.SourcePath != localSource || updated.TargetPath != newTarget || updated.Description != "Local settings" {
+		t.Errorf("Unexpected updated file config %+v", updated)
+	}
+	stored, err := manager.GetFileConfig(project.ID, "env-1", fileConfig.ID)
+	if err != nil || *stored != *updated {
+		t.Errorf("Expected update to be saved, got %+v, %v", stored, err)
+	}
+
+	// 不能与其他文件配置使用相同的目标路径
+	_, err = manager.UpdateFileConfig(project.ID, "env-1", other.ID, map[string]interface{}{"target_path": newTarget})
+	if !errors.Is(err, internal.ErrAlreadyExists) {
+		t.Errorf("Expected duplicate target error, got %v", err)
+	}
+
+	// 源文件必须存在
+	_, err = manager.UpdateFileConfig(project.ID, "env-1", fileConfig.ID, map[string]interface{}{"source_path": filepath.Join(tempDir, "missing.json")})
+	if err == nil {
+		t.Error("Expected error for missing source file")
+	}
+
+	if _, err := manager.UpdateFileConfig(project.ID, "env-1", "missing", map[string]interface{}{}); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+}
+
+func TestUpdateFileConfigMode(t *testing.T) {
+	manager, project, tempDir := setupTest(t)
+
+	source := filepath.Join(tempDir, "dev.json")
+	writeTestFile(t, source, `{"env":"dev"}`)
+
+	fileConfig, err := manager.CreateFileConfig(project.ID, "env-1", &internal.FileConfig{
+		SourcePath: source,
+		TargetPath: filepath.Join(tempDir, "config.json"),
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateFileConfig() error = %v", err)
+	}
+
+	// 改为托管：导入当前源文件
+	managed, err := manager.UpdateFileConfig(project.ID, "env-1", fileConfig.ID, map[string]interface{}{"managed": true})
+	if err != nil {
+		t.Fatalf("UpdateFileConfig() error = %v", err)
+	}
+	if !managed.Managed || managed.Version != 1 || filepath.IsAbs(managed.SourcePath) {
+		t.Fatalf("Expected managed file config, got %+v", managed)
+	}
+	managedPath := manager.ResolveSourcePath(managed)
+	if data, err := os.ReadFile(managedPath); err != nil || string(data) != `{"env":"dev"}` {
+		t.Fatalf("Expected imported source, got %q, %v", data, err)
+	}
+
+	// 取消托管必须指定外部源文件
+	if _, err := manager.UpdateFileConfig(project.ID, "env-1", fileConfig.ID, map[string]interface{}{"managed": false}); err == nil {
+		t.Error("Expected error when unmanaging without a source path")
+	}
+
+	// 取消托管后删除托管源文件
+	unmanaged, err := manager.UpdateFileConfig(project.ID, "env-1", fileConfig.ID, map[string]interface{}{
+		"managed":     false,
+		"source_path": source,
+	})
+	if err != nil {
+		t.Fatalf("UpdateFileConfig() error = %v", err)
+	}
+	if unmanaged.Managed || unmanaged.Version != 0 || unmanaged.SourcePath != source {
+		t.Errorf("Expected unmanaged file config, got %+v", unmanaged)
+	}
+	if _, err := os.Stat(managedPath); !os.IsNotExist(err) {
+		t.Errorf("Expected managed source to be removed, got %v", err)
+	}
+}
+
+func TestSwitchMetrics(t *testing.T) {
+	manager, project, tempDir := setupTest(t)
+
+	source := filepath.Join(tempDir, "dev.json")
+	writeTestFile(t, source, `{"env":"dev"}`)
+	if _, err := manager.CreateFileConfig(project.ID, "env-1", &internal.FileConfig{
+		SourcePath: source,
+		TargetPath: filepath.Join(tempDir, "app", "config.json"),
+	}, false); err != nil {
+		t.Fatalf("CreateFileConfig() error = %v", err)
+	}
+
+	// 指标是全局的，按增量比较
+	successes := metrics.Switches.Value("test-project", "dev", "success")
+	failures := metrics.Switches.Value("test-project", "dev", "failure")
+	missing := metrics.SwitchFailures.Value("test-project", "dev", metrics.ReasonSource)
+	rollbacks := metrics.Rollbacks.Value("success")
+	observed := metrics.SwitchDuration.Count("test-project", "dev")
+
+	if err := manager.SwitchEnvironment(project.ID, "env-1"); err != nil {
+		t.Fatalf("SwitchEnvironment() error = %v", err)
+	}
+	if got := metrics.Switches.Value("test-project", "dev", "success"); got != successes+1 {
+		t.Errorf("Expected %v successful switches, got %v", successes+1, got)
+	}
+
+	// 源文件缺失导致切换失败，并自动回滚
+	if err := os.Remove(source); err != nil {
+		t.Fatal(err)
+	}
+	err := manager.SwitchEnvironment(project.ID, "env-1")
+	if err == nil {
+		t.Fatal("Expected switch to fail for missing source")
+	}
+	if got := metrics.Switches.Value("test-project", "dev", "failure"); got != failures+1 {
+		t.Errorf("Expected %v failed switches, got %v", failures+1, got)
+	}
+	if got := metrics.SwitchFailures.Value("test-project", "dev", metrics.ReasonSource); got != missing+1 {
+		t.Errorf("Expected failure reason %s to be counted, got %v", metrics.ReasonSource, got)
+	}
+	if got := metrics.Rollbacks.Value("success"); got != rollbacks+1 {
+		t.Errorf("Expected automatic rollback to be counted, got %v", got)
+	}
+	if got := metrics.SwitchDuration.Count("test-project", "dev"); got != observed+2 {
+		t.Errorf("Expected %d observed durations, got %d", observed+2, got)
+	}
+
+	// 不存在的环境
+	err = manager.SwitchEnvironment(project.ID, "missing")
+	if !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if got := metrics.SwitchFailures.Value("test-project", "missing", metrics.ReasonNotFound); got == 0 {
+		t.Errorf("Expected failure reason %s to be counted", metrics.ReasonNotFound)
+	}
+}
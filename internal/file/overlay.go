@@ -0,0 +1,178 @@
+package file
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+)
+
+// Overlay 私有覆盖目录：项目根目录的临时副本，环境文件只应用到副本中
+type Overlay struct {
+	Dir  string // 覆盖目录
+	Root string // 被复制的原始根目录
+}
+
+// CreateOverlay 复制 root 目录树到临时目录，并将环境的文件映射和 .env 文件应用到副本中
+// 解析路径时 PROJECT_ROOT 指向覆盖目录，原根目录下的绝对路径映射到副本中，其他目标路径会被拒绝，以免影响全局文件
+func (m *Manager) CreateOverlay(project *internal.Project, env *internal.Environment, root string) (*Overlay, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "envswitch-overlay-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create overlay directory: %w", err)
+	}
+	overlay := &Overlay{Dir: dir, Root: root}
+
+	if err := m.copyTree(root, dir); err != nil {
+		overlay.Remove()
+		return nil, fmt.Errorf("failed to copy %s to overlay: %w", root, err)
+	}
+
+	// 以覆盖目录作为项目根目录解析路径
+	overlayProject := *project
+	overlayProject.Root = dir
+
+	for _, fileConfig := range env.Files {
+		mappings, err := m.storage.ExpandFileConfig(&overlayProject, &fileConfig)
+		if err != nil {
+			overlay.Remove()
+			return nil, err
+		}
+		for _, mapping := range mappings {
+			targetPath := overlay.MapPath(mapping.TargetPath)
+			if !overlay.Contains(targetPath) {
+				overlay.Remove()
+				return nil, fmt.Errorf("target %s is outside the project root %s and cannot be overlaid", fileConfig.TargetPath, root)
+			}
+			if err := overlay.unlinkTarget(targetPath); err != nil {
+				overlay.Remove()
+				return nil, err
+			}
+			if err := m.copyMapping(mapping.SourcePath, targetPath); err != nil {
+				overlay.Remove()
+				return nil, fmt.Errorf("failed to apply file %s to overlay: %w", fileConfig.TargetPath, err)
+			}
+		}
+	}
+
+	if env.DotEnvPath != "" {
+		dotEnvPath, err := config.ExpandPath(env.DotEnvPath, config.PathVariables(&overlayProject))
+		if err != nil {
+			overlay.Remove()
+			return nil, err
+		}
+		dotEnvPath = overlay.MapPath(dotEnvPath)
+		if !overlay.Contains(dotEnvPath) {
+			overlay.Remove()
+			return nil, fmt.Errorf("dotenv file %s is outside the project root %s and cannot be overlaid", env.DotEnvPath, root)
+		}
+		if err := overlay.unlinkTarget(dotEnvPath); err != nil {
+			overlay.Remove()
+			return nil, err
+		}
+		if err := writeDotEnvFile(dotEnvPath, env.Variables); err != nil {
+			overlay.Remove()
+			return nil, err
+		}
+	}
+
+	return overlay, nil
+}
+
+// Contains 判断路径是否位于覆盖目录内
+func (o *Overlay) Contains(path string) bool {
+	rel, err := filepath.Rel(o.Dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// MapPath 将原始根目录下的路径映射到覆盖目录中，其他路径原样返回
+func (o *Overlay) MapPath(path string) string {
+	rel, err := filepath.Rel(o.Root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.Join(o.Dir, rel)
+}
+
+// unlinkTarget 写入覆盖目录中的目标文件前检查符号链接：复制的目录树保留了符号链接，
+// 通过链接写入会修改覆盖目录之外的原文件。目标本身是符号链接时删除链接（只删除副本中的链接），
+// 上级目录是符号链接时拒绝应用
+func (o *Overlay) unlinkTarget(path string) error {
+	rel, err := filepath.Rel(o.Dir, path)
+	if err != nil {
+		return err
+	}
+
+	current := o.Dir
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i, part := range parts {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			continue
+		}
+		if i < len(parts)-1 {
+			return fmt.Errorf("cannot overlay %s: %s is a symbolic link", path, current)
+		}
+		return os.Remove(current)
+	}
+	return nil
+}
+
+// Remove 删除覆盖目录
+func (o *Overlay) Remove() {
+	_ = os.RemoveAll(o.Dir)
+}
+
+// copyTree 复制目录树（跳过 .git 目录），保留文件权限和符号链接；映射的目标位置上的链接由 unlinkTarget 处理
+func (m *Manager) copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case d.IsDir():
+			// 覆盖目录位于源目录内时避免递归复制自身
+			if (d.Name() == ".git" && rel != ".") || path == dst {
+				return filepath.SkipDir
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			return os.MkdirAll(target, info.Mode().Perm()|0700)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return m.copyFile(path, target)
+		}
+
+		// 跳过套接字、设备等特殊文件
+		return nil
+	})
+}
@@ -0,0 +1,61 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+func TestCreateOverlaySymlinkTargets(t *testing.T) {
+	manager, project, tempDir := setupTest(t)
+
+	root := filepath.Join(tempDir, "repo")
+	outside := filepath.Join(tempDir, "outside")
+	writeTestFile(t, filepath.Join(outside, "config.json"), `{"env":"real"}`)
+	writeTestFile(t, filepath.Join(root, "README.md"), "readme")
+	source := filepath.Join(tempDir, "prod.json")
+	writeTestFile(t, source, `{"env":"prod"}`)
+
+	// 目标位置是指向项目之外的绝对符号链接
+	if err := os.Symlink(filepath.Join(outside, "config.json"), filepath.Join(root, "config.json")); err != nil {
+		t.Skipf("Symlinks are not supported: %v", err)
+	}
+
+	env := &internal.Environment{ID: "env-1", Name: "prod", Files: []internal.FileConfig{
+		{ID: "file-1", SourcePath: source, TargetPath: "config.json"},
+	}}
+	project.Root = root
+
+	overlay, err := manager.CreateOverlay(project, env, root)
+	if err != nil {
+		t.Fatalf("CreateOverlay() error = %v", err)
+	}
+	defer overlay.Remove()
+
+	// 链接被替换为副本中的普通文件，原文件保持不变
+	target := filepath.Join(overlay.Dir, "config.json")
+	if info, err := os.Lstat(target); err != nil || !info.Mode().IsRegular() {
+		t.Errorf("Expected a regular file in the overlay, got %v, %v", info, err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != `{"env":"prod"}` {
+		t.Errorf("Unexpected overlay content %s", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(outside, "config.json")); string(data) != `{"env":"real"}` {
+		t.Errorf("Expected the linked file to stay unchanged, got %s", data)
+	}
+
+	// 上级目录是符号链接时拒绝应用
+	if err := os.Symlink(outside, filepath.Join(root, "conf")); err != nil {
+		t.Fatal(err)
+	}
+	env.Files[0].TargetPath = "conf/config.json"
+	if _, err := manager.CreateOverlay(project, env, root); err == nil || !strings.Contains(err.Error(), "symbolic link") {
+		t.Errorf("Expected symbolic link error, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(outside, "config.json")); string(data) != `{"env":"real"}` {
+		t.Errorf("Expected the linked directory to stay unchanged, got %s", data)
+	}
+}
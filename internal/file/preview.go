@@ -0,0 +1,199 @@
+package file
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// 切换预览中目标文件的变更状态
+const (
+	PreviewUnchanged     = "unchanged"      // 内容相同
+	PreviewModified      = "modified"       // 目标文件将被覆盖
+	PreviewCreated       = "created"        // 目标文件不存在，将被创建
+	PreviewMissingSource = "missing-source" // 源文件不存在，切换会失败
+)
+
+const (
+	// diffContextLines diff 中变更行前后保留的上下文行数
+	diffContextLines = 3
+	// maxDiffCells 行 diff 的最大计算量（旧行数 × 新行数），超出时只报告文件已变更
+	maxDiffCells = 4000000
+)
+
+// FilePreview 切换环境时单个目标文件的变更预览
+type FilePreview struct {
+	internal.FileMapping
+	Status string   `json:"status"`
+	Binary bool     `json:"binary,omitempty"`
+	Diff   []string `json:"diff,omitempty"` // 统一 diff 格式的变更行（当前目标文件 -> 源文件）
+}
+
+// PreviewEnvironment 预览切换到指定环境时各目标文件（包括 .env 文件）的变更，不修改任何文件
+func (m *Manager) PreviewEnvironment(project *internal.Project, env *internal.Environment) ([]FilePreview, error) {
+	var previews []FilePreview
+
+	for _, fileConfig := range env.Files {
+		mappings, err := m.storage.ExpandFileConfig(project, &fileConfig)
+		if err != nil {
+			return nil, err
+		}
+		for _, mapping := range mappings {
+			preview := FilePreview{FileMapping: mapping}
+			source, err := os.ReadFile(mapping.SourcePath)
+			if err != nil {
+				preview.Status = PreviewMissingSource
+				previews = append(previews, preview)
+				continue
+			}
+			previews = append(previews, previewContent(preview, source))
+		}
+	}
+
+	dotEnvPath, err := m.ResolveDotEnvPath(project, env)
+	if err != nil {
+		return nil, err
+	}
+	if dotEnvPath != "" {
+		preview := FilePreview{FileMapping: internal.FileMapping{TargetPath: dotEnvPath}}
+		previews = append(previews, previewContent(preview, []byte(FormatDotEnv(env.Variables))))
+	}
+
+	return previews, nil
+}
+
+// previewContent 比较目标文件的当前内容与将要写入的内容
+func previewContent(preview FilePreview, content []byte) FilePreview {
+	current, err := os.ReadFile(preview.TargetPath)
+	switch {
+	case err != nil:
+		preview.Status = PreviewCreated
+		current = nil
+	case bytes.Equal(current, content):
+		preview.Status = PreviewUnchanged
+		return preview
+	default:
+		preview.Status = PreviewModified
+	}
+
+	if isBinary(current) || isBinary(content) {
+		preview.Binary = true
+		return preview
+	}
+	preview.Diff = DiffLines(splitLines(current), splitLines(content))
+	return preview
+}
+
+// isBinary 前 8000 字节中包含 NUL 字符时视为二进制文件
+func isBinary(data []byte) bool {
+	if len(data) > 8000 {
+		data = data[:8000]
+	}
+	return bytes.IndexByte(data, 0) >= 0
+}
+
+// splitLines 按行拆分文件内容
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+}
+
+// DiffLines 计算两组行之间的差异，返回统一 diff 格式的变更块（不含文件头）
+func DiffLines(oldLines, newLines []string) []string {
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		return []string{fmt.Sprintf("@@ -1,%d +1,%d @@ (too large to diff)", len(oldLines), len(newLines))}
+	}
+
+	// lcs[i][j] 为 oldLines[i:] 与 newLines[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	// 生成逐行操作序列
+	type op struct {
+		kind       byte // ' '、'-'、'+'
+		text       string
+		oldN, newN int // 该行在旧/新文件中的行号（从 1 开始）
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			ops = append(ops, op{' ', oldLines[i], i + 1, j + 1})
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{'-', oldLines[i], i + 1, j})
+			i++
+		default:
+			ops = append(ops, op{'+', newLines[j], i, j + 1})
+			j++
+		}
+	}
+
+	// 将变更行及其上下文合并为变更块
+	var result []string
+	for start := 0; start < len(ops); {
+		if ops[start].kind == ' ' {
+			start++
+			continue
+		}
+
+		from := max(start-diffContextLines, 0)
+		to := start
+		for k := start; k < len(ops); k++ {
+			if ops[k].kind != ' ' {
+				to = k
+			} else if k-to > 2*diffContextLines {
+				break
+			}
+		}
+		to = min(to+diffContextLines+1, len(ops))
+
+		var oldStart, newStart, oldCount, newCount int
+		var lines []string
+		for _, o := range ops[from:to] {
+			if o.kind != '+' {
+				if oldCount == 0 {
+					oldStart = o.oldN
+				}
+				oldCount++
+			}
+			if o.kind != '-' {
+				if newCount == 0 {
+					newStart = o.newN
+				}
+				newCount++
+			}
+			lines = append(lines, string(o.kind)+o.text)
+		}
+		if oldCount == 0 {
+			oldStart = ops[from].oldN
+		}
+		if newCount == 0 {
+			newStart = ops[from].newN
+		}
+
+		result = append(result, fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldCount, newStart, newCount))
+		result = append(result, lines...)
+		start = to
+	}
+
+	return result
+}
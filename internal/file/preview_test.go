@@ -0,0 +1,40 @@
+package file
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	oldLines := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l"}
+	newLines := []string{"a", "B", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m"}
+
+	expected := []string{
+		"@@ -1,5 +1,5 @@",
+		" a",
+		"-b",
+		"+B",
+		" c",
+		" d",
+		" e",
+		"@@ -10,3 +10,4 @@",
+		" j",
+		" k",
+		" l",
+		"+m",
+	}
+	if got := DiffLines(oldLines, newLines); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected diff:\n%s", strings.Join(got, "\n"))
+	}
+
+	if got := DiffLines(oldLines, oldLines); len(got) != 0 {
+		t.Errorf("Expected no diff for identical input, got %v", got)
+	}
+
+	// 新建文件
+	got := DiffLines(nil, []string{"x", "y"})
+	if !reflect.DeepEqual(got, []string{"@@ -0,0 +1,2 @@", "+x", "+y"}) {
+		t.Errorf("Unexpected diff for new file: %v", got)
+	}
+}
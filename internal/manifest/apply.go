@@ -0,0 +1,460 @@
+package manifest
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
+)
+
+// Action 计划中的变更类型
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Change 单个变更
+type Change struct {
+	Action      Action `json:"action"`
+	Kind        string `json:"kind"` // project, environment, file
+	Environment string `json:"environment,omitempty"`
+	Target      string `json:"target,omitempty"`
+	Detail      string `json:"detail,omitempty"`
+	Protected   bool   `json:"protected,omitempty"` // 变更涉及受保护环境，应用前需要确认
+
+	env    *Environment
+	file   *File
+	fileID string
+	stored *internal.Environment // 需要确认的受保护环境
+}
+
+// Plan 清单与存储之间的差异
+type Plan struct {
+	Project   string   `json:"project"`
+	Changes   []Change `json:"changes"`
+	Unmanaged []string `json:"unmanaged,omitempty"` // 存储中存在但清单中没有的条目（未启用 prune）
+
+	manifest  *Manifest
+	projectID string
+	project   *internal.Project
+}
+
+// HasChanges 计划是否包含变更
+func (p *Plan) HasChanges() bool {
+	return len(p.Changes) > 0
+}
+
+// Summary 变更统计
+func (p *Plan) Summary() (add, change, destroy int) {
+	for _, c := range p.Changes {
+		switch c.Action {
+		case ActionCreate:
+			add++
+		case ActionUpdate:
+			change++
+		case ActionDelete:
+			destroy++
+		}
+	}
+	return
+}
+
+// ProtectedEnvironments 计划中需要确认的受保护环境（按首次出现的顺序去重）
+func (p *Plan) ProtectedEnvironments() []*internal.Environment {
+	var envs []*internal.Environment
+	seen := make(map[string]bool)
+	for _, c := range p.Changes {
+		if c.stored == nil || seen[c.stored.ID] {
+			continue
+		}
+		seen[c.stored.ID] = true
+		envs = append(envs, c.stored)
+	}
+	return envs
+}
+
+// String 以可读形式输出计划
+func (c Change) String() string {
+	symbol := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[c.Action]
+
+	var b strings.Builder
+	b.WriteString(symbol + " " + c.Kind)
+	if c.Environment != "" {
+		b.WriteString(" " + c.Environment)
+	}
+	if c.Target != "" {
+		b.WriteString(": " + c.Target)
+	}
+	if c.Detail != "" {
+		b.WriteString(" (" + c.Detail + ")")
+	}
+	if c.Protected {
+		b.WriteString(" [protected]")
+	}
+	return b.String()
+}
+
+// Reconciler 将清单同步到存储
+type Reconciler struct {
+	projectManager *project.Manager
+	fileManager    *file.Manager
+}
+
+// NewReconciler 创建清单同步器
+func NewReconciler() *Reconciler {
+	return &Reconciler{
+		projectManager: project.NewManager(),
+		fileManager:    file.NewManager(),
+	}
+}
+
+// Plan 计算清单与存储之间的差异，prune 为 true 时删除清单中不存在的环境和文件
+func (r *Reconciler) Plan(m *Manifest, prune bool) (*Plan, error) {
+	plan := &Plan{Project: m.Project, manifest: m}
+
+	proj, err := r.projectManager.GetProject(m.Project)
+	if err != nil {
+		// 项目不存在，全部新建
+		plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Kind: "project", Target: m.Project})
+		for i := range m.Environments {
+			env := &m.Environments[i]
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Kind: "environment", Environment: env.Name, env: env})
+			for j := range env.Files {
+				plan.Changes = append(plan.Changes, r.fileCreate(m, env, &env.Files[j]))
+			}
+		}
+		return plan, nil
+	}
+
+	plan.projectID = proj.ID
+	plan.project = proj
+
+	var projectDetails []string
+	if proj.Description != m.Description {
+		projectDetails = append(projectDetails, fmt.Sprintf("description %q -> %q", proj.Description, m.Description))
+	}
+	// 未设置根目录的项目以清单所在目录为根目录
+	if proj.Root == "" && m.dir != "" {
+		projectDetails = append(projectDetails, fmt.Sprintf("root -> %s", m.dir))
+	}
+	if len(projectDetails) > 0 {
+		plan.Changes = append(plan.Changes, Change{
+			Action: ActionUpdate,
+			Kind:   "project",
+			Target: m.Project,
+			Detail: strings.Join(projectDetails, ", "),
+		})
+	}
+
+	existingEnvs := make(map[string]*internal.Environment)
+	for i := range proj.Environments {
+		existingEnvs[proj.Environments[i].Name] = &proj.Environments[i]
+	}
+
+	for i := range m.Environments {
+		env := &m.Environments[i]
+		existing, ok := existingEnvs[env.Name]
+		if !ok {
+			plan.Changes = append(plan.Changes, Change{Action: ActionCreate, Kind: "environment", Environment: env.Name, env: env})
+			for j := range env.Files {
+				plan.Changes = append(plan.Changes, r.fileCreate(m, env, &env.Files[j]))
+			}
+			continue
+		}
+		delete(existingEnvs, env.Name)
+
+		var details []string
+		if existing.Description != env.Description {
+			details = append(details, fmt.Sprintf("description %q -> %q", existing.Description, env.Description))
+		}
+		if strings.Join(existing.Tags, ",") != strings.Join(env.Tags, ",") {
+			details = append(details, fmt.Sprintf("tags [%s] -> [%s]", strings.Join(existing.Tags, ", "), strings.Join(env.Tags, ", ")))
+		}
+		if !maps.Equal(existing.Variables, env.Variables) {
+			details = append(details, fmt.Sprintf("variables [%s] -> [%s]",
+				strings.Join(config.SortedVariableNames(existing.Variables), ", "),
+				strings.Join(config.SortedVariableNames(env.Variables), ", ")))
+		}
+		if existing.DotEnvPath != env.DotEnv {
+			details = append(details, fmt.Sprintf("dotenv %q -> %q", existing.DotEnvPath, env.DotEnv))
+		}
+		if existing.Protected != env.Protected {
+			details = append(details, fmt.Sprintf("protected %t -> %t", existing.Protected, env.Protected))
+		}
+		if len(details) > 0 {
+			change := Change{
+				Action:      ActionUpdate,
+				Kind:        "environment",
+				Environment: env.Name,
+				Detail:      strings.Join(details, ", "),
+				env:         env,
+			}
+			// 取消保护（protected: false 或移除受保护标签）与删除一样需要确认
+			if config.RemovesProtection(existing, environmentUpdates(env)) {
+				change.Protected, change.stored = true, existing
+			}
+			plan.Changes = append(plan.Changes, change)
+		}
+
+		r.planFiles(plan, m, env, existing, prune)
+	}
+
+	// 清单中不存在的环境
+	for _, existing := range proj.Environments {
+		if _, ok := existingEnvs[existing.Name]; !ok {
+			continue
+		}
+		switch {
+		case prune && config.IsProtected(&existing):
+			// 受保护的环境不会被 prune 删除
+			plan.Unmanaged = append(plan.Unmanaged, "environment "+existing.Name+" (protected, not pruned)")
+		case prune:
+			plan.Changes = append(plan.Changes, Change{Action: ActionDelete, Kind: "environment", Environment: existing.Name})
+		default:
+			plan.Unmanaged = append(plan.Unmanaged, "environment "+existing.Name)
+		}
+	}
+
+	return plan, nil
+}
+
+// planFiles 计算单个环境的文件映射差异（以目标路径为键）
+// 与 env update-file、env remove-file 一样，修改或删除受保护环境中的文件映射需要确认
+func (r *Reconciler) planFiles(plan *Plan, m *Manifest, env *Environment, existing *internal.Environment, prune bool) {
+	var stored *internal.Environment
+	if config.IsProtected(existing) {
+		stored = existing
+	}
+
+	existingFiles := make(map[string]*internal.FileConfig)
+	existingSources := make(map[string]string)
+	for i := range existing.Files {
+		fileConfig := &existing.Files[i]
+		sourcePath, targetPath := r.resolveStored(plan.project, fileConfig)
+		existingFiles[targetPath] = fileConfig
+		existingSources[fileConfig.ID] = sourcePath
+	}
+
+	for i := range env.Files {
+		mfile := &env.Files[i]
+		target := m.resolvePath(mfile.Target)
+		source := m.resolvePath(mfile.Source)
+
+		fileConfig, ok := existingFiles[target]
+		if !ok {
+			plan.Changes = append(plan.Changes, r.fileCreate(m, env, mfile))
+			continue
+		}
+		delete(existingFiles, target)
+
+		// 托管文件的源文件由 envswitch 保存，清单中的源路径只用于首次导入
+		var details []string
+		if !fileConfig.Managed && existingSources[fileConfig.ID] != source {
+			details = append(details, fmt.Sprintf("source %s -> %s", existingSources[fileConfig.ID], source))
+		}
+		if fileConfig.Description != mfile.Description {
+			details = append(details, fmt.Sprintf("description %q -> %q", fileConfig.Description, mfile.Description))
+		}
+		if len(details) > 0 {
+			plan.Changes = append(plan.Changes, Change{
+				Action:      ActionUpdate,
+				Kind:        "file",
+				Environment: env.Name,
+				Target:      target,
+				Detail:      strings.Join(details, ", "),
+				Protected:   stored != nil,
+				env:         env,
+				file:        mfile,
+				fileID:      fileConfig.ID,
+				stored:      stored,
+			})
+		}
+	}
+
+	// 清单中不存在的文件映射
+	for _, fileConfig := range existing.Files {
+		if _, ok := existingFiles[r.resolveStoredTarget(plan.project, &fileConfig)]; !ok {
+			continue
+		}
+		if prune {
+			plan.Changes = append(plan.Changes, Change{
+				Action:      ActionDelete,
+				Kind:        "file",
+				Environment: env.Name,
+				Target:      fileConfig.TargetPath,
+				Protected:   stored != nil,
+				fileID:      fileConfig.ID,
+				stored:      stored,
+			})
+		} else {
+			plan.Unmanaged = append(plan.Unmanaged, fmt.Sprintf("file %s: %s", env.Name, fileConfig.TargetPath))
+		}
+	}
+}
+
+// fileCreate 生成新增文件映射的变更
+func (r *Reconciler) fileCreate(m *Manifest, env *Environment, mfile *File) Change {
+	return Change{
+		Action:      ActionCreate,
+		Kind:        "file",
+		Environment: env.Name,
+		Target:      m.resolvePath(mfile.Target),
+		Detail:      "source " + m.resolvePath(mfile.Source),
+		env:         env,
+		file:        mfile,
+	}
+}
+
+// Apply 执行计划
+func (r *Reconciler) Apply(plan *Plan) error {
+	m := plan.manifest
+
+	for _, c := range plan.Changes {
+		var err error
+
+		switch {
+		case c.Kind == "project" && c.Action == ActionCreate:
+			var proj *internal.Project
+			proj, err = r.projectManager.CreateProject(m.Project, m.Description)
+			if err == nil {
+				plan.projectID = proj.ID
+				plan.project, err = r.projectManager.UpdateProject(proj.ID, map[string]interface{}{
+					"root": m.dir,
+				})
+			}
+
+		case c.Kind == "project" && c.Action == ActionUpdate:
+			updates := map[string]interface{}{
+				"description": m.Description,
+			}
+			if plan.project.Root == "" {
+				updates["root"] = m.dir
+			}
+			plan.project, err = r.projectManager.UpdateProject(plan.projectID, updates)
+
+		case c.Kind == "environment" && c.Action == ActionCreate:
+			err = r.projectManager.AddEnvironment(plan.projectID, &internal.Environment{
+				Name:        c.env.Name,
+				Description: c.env.Description,
+				Tags:        c.env.Tags,
+				Variables:   c.env.Variables,
+				DotEnvPath:  c.env.DotEnv,
+				Protected:   c.env.Protected,
+				Files:       []internal.FileConfig{},
+			})
+
+		case c.Kind == "environment" && c.Action == ActionUpdate:
+			_, err = r.projectManager.UpdateEnvironment(plan.projectID, c.env.Name, environmentUpdates(c.env))
+
+		case c.Kind == "environment" && c.Action == ActionDelete:
+			err = r.projectManager.RemoveEnvironment(plan.projectID, c.Environment)
+
+		case c.Kind == "file" && c.Action == ActionCreate:
+			err = r.addFile(plan.project, m, c.env, c.file)
+
+		case c.Kind == "file" && c.Action == ActionUpdate:
+			err = r.updateFile(plan.project, m, c.env, c.file, c.fileID)
+
+		case c.Kind == "file" && c.Action == ActionDelete:
+			err = r.removeFile(plan.projectID, c.Environment, c.fileID)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to apply '%s': %w", c.String(), err)
+		}
+	}
+
+	return nil
+}
+
+// environmentUpdates 清单环境对应的环境更新字段
+func environmentUpdates(env *Environment) map[string]interface{} {
+	return map[string]interface{}{
+		"description": env.Description,
+		"tags":        env.Tags,
+		"variables":   env.Variables,
+		"dotenv_path": env.DotEnv,
+		"protected":   env.Protected,
+	}
+}
+
+// addFile 添加清单中的文件映射
+func (r *Reconciler) addFile(proj *internal.Project, m *Manifest, env *Environment, mfile *File) error {
+	storedEnv, err := r.projectManager.GetEnvironment(proj.ID, env.Name)
+	if err != nil {
+		return err
+	}
+
+	sourcePath, targetPath := storedPaths(proj, m, mfile)
+	return r.fileManager.AddFileConfig(proj.ID, storedEnv.ID, sourcePath, targetPath, mfile.Description)
+}
+
+// updateFile 原地更新文件映射，保留文件配置ID；托管文件只更新描述，不重新导入源文件
+func (r *Reconciler) updateFile(proj *internal.Project, m *Manifest, env *Environment, mfile *File, fileID string) error {
+	storedEnv, err := r.projectManager.GetEnvironment(proj.ID, env.Name)
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"description": mfile.Description,
+	}
+	for _, fileConfig := range storedEnv.Files {
+		if fileConfig.ID == fileID && !fileConfig.Managed {
+			sourcePath, _ := storedPaths(proj, m, mfile)
+			updates["source_path"] = sourcePath
+		}
+	}
+
+	_, err = r.fileManager.UpdateFileConfig(proj.ID, storedEnv.ID, fileID, updates)
+	return err
+}
+
+// storedPaths 清单文件映射保存到项目中的源路径和目标路径
+// 项目根目录即清单所在目录时按清单原样保存路径，使项目可移植；否则保存解析后的绝对路径
+func storedPaths(proj *internal.Project, m *Manifest, mfile *File) (string, string) {
+	if root := config.PathVariables(proj)[config.ProjectRootVariable]; root != "" && root == m.dir {
+		return mfile.Source, mfile.Target
+	}
+	return m.resolvePath(mfile.Source), m.resolvePath(mfile.Target)
+}
+
+// removeFile 移除文件映射
+func (r *Reconciler) removeFile(projectID, envName, fileID string) error {
+	storedEnv, err := r.projectManager.GetEnvironment(projectID, envName)
+	if err != nil {
+		return err
+	}
+	return r.fileManager.RemoveFileConfig(projectID, storedEnv.ID, fileID)
+}
+
+// resolveStored 解析已存储文件配置的源路径和目标路径，无法解析时使用原路径
+func (r *Reconciler) resolveStored(proj *internal.Project, fileConfig *internal.FileConfig) (string, string) {
+	sourcePath, targetPath, err := r.fileManager.ResolvePaths(proj, fileConfig)
+	if err != nil {
+		return fileConfig.SourcePath, fileConfig.TargetPath
+	}
+	return absPath(sourcePath), absPath(targetPath)
+}
+
+// resolveStoredTarget 解析已存储文件配置的目标路径
+func (r *Reconciler) resolveStoredTarget(proj *internal.Project, fileConfig *internal.FileConfig) string {
+	_, targetPath := r.resolveStored(proj, fileConfig)
+	return targetPath
+}
+
+// absPath 获取绝对路径，失败时返回原路径
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,217 @@
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/project"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile 默认的清单文件名
+const DefaultFile = "envswitch.yaml"
+
+// Manifest 声明式项目清单
+type Manifest struct {
+	Project      string        `yaml:"project" json:"project"`
+	Description  string        `yaml:"description,omitempty" json:"description,omitempty"`
+	Environments []Environment `yaml:"environments" json:"environments"`
+
+	// dir 清单文件所在目录，用于解析相对路径
+	dir string
+}
+
+// Environment 清单中的环境定义
+type Environment struct {
+	Name        string            `yaml:"name" json:"name"`
+	Description string            `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags        []string          `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Variables   map[string]string `yaml:"variables,omitempty" json:"variables,omitempty"`
+	DotEnv      string            `yaml:"dotenv,omitempty" json:"dotenv,omitempty"`
+	Protected   bool              `yaml:"protected,omitempty" json:"protected,omitempty"`
+	Files       []File            `yaml:"files,omitempty" json:"files,omitempty"`
+}
+
+// File 清单中的文件映射，相对路径以清单文件所在目录为基准
+type File struct {
+	Source      string `yaml:"source" json:"source"`
+	Target      string `yaml:"target" json:"target"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// Load 加载清单文件（.json 按JSON解析，其余按YAML解析）
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &m)
+	} else {
+		err = yaml.Unmarshal(data, &m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	m.dir = filepath.Dir(absPath)
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Validate 验证清单内容
+func (m *Manifest) Validate() error {
+	if m.Project == "" {
+		return fmt.Errorf("manifest: project name cannot be empty")
+	}
+
+	envNames := make(map[string]bool)
+	for _, env := range m.Environments {
+		if env.Name == "" {
+			return fmt.Errorf("manifest: environment name cannot be empty")
+		}
+		if envNames[env.Name] {
+			return fmt.Errorf("manifest: duplicate environment '%s'", env.Name)
+		}
+		envNames[env.Name] = true
+
+		for name := range env.Variables {
+			if err := project.ValidateVariableName(name); err != nil {
+				return fmt.Errorf("manifest: environment '%s': %w", env.Name, err)
+			}
+		}
+
+		targets := make(map[string]bool)
+		for _, file := range env.Files {
+			if file.Source == "" || file.Target == "" {
+				return fmt.Errorf("manifest: environment '%s' has a file without source or target", env.Name)
+			}
+			for _, path := range []string{file.Source, file.Target} {
+				if _, err := m.expandPath(path); err != nil {
+					return fmt.Errorf("manifest: environment '%s': %w", env.Name, err)
+				}
+			}
+			target := m.resolvePath(file.Target)
+			if targets[target] {
+				return fmt.Errorf("manifest: environment '%s' has duplicate target '%s'", env.Name, file.Target)
+			}
+			targets[target] = true
+		}
+	}
+
+	return nil
+}
+
+// Dir 获取清单文件所在目录
+func (m *Manifest) Dir() string {
+	return m.dir
+}
+
+// variables 解析清单路径时使用的变量，PROJECT_ROOT 为清单文件所在目录
+func (m *Manifest) variables() map[string]string {
+	vars := config.PathVariables(nil)
+	if m.dir != "" {
+		vars[config.ProjectRootVariable] = m.dir
+	}
+	return vars
+}
+
+// expandPath 展开清单中的路径变量，相对路径以清单文件所在目录为基准
+func (m *Manifest) expandPath(path string) (string, error) {
+	return config.ExpandPath(path, m.variables())
+}
+
+// resolvePath 将清单中的路径解析为绝对路径，无法解析时返回原路径
+func (m *Manifest) resolvePath(path string) string {
+	resolved, err := m.expandPath(path)
+	if err != nil {
+		return path
+	}
+	return resolved
+}
+
+// FromProject 根据已有项目生成清单，resolve 返回文件配置解析后的源路径和目标路径，路径尽量表示为相对 baseDir 的路径
+func FromProject(project *internal.Project, resolve func(*internal.FileConfig) (string, string, error), baseDir string) *Manifest {
+	m := &Manifest{
+		Project:      project.Name,
+		Description:  project.Description,
+		Environments: make([]Environment, 0, len(project.Environments)),
+		dir:          baseDir,
+	}
+
+	for _, env := range project.Environments {
+		menv := Environment{
+			Name:        env.Name,
+			Description: env.Description,
+			Tags:        env.Tags,
+			Variables:   env.Variables,
+			DotEnv:      env.DotEnvPath,
+			Protected:   env.Protected,
+		}
+		for _, fileConfig := range env.Files {
+			sourcePath, targetPath, err := resolve(&fileConfig)
+			if err != nil {
+				// 无法解析时保留原始路径（如未定义的本机路径变量）
+				sourcePath, targetPath = fileConfig.SourcePath, fileConfig.TargetPath
+			}
+			menv.Files = append(menv.Files, File{
+				Source:      relativePath(baseDir, sourcePath),
+				Target:      relativePath(baseDir, targetPath),
+				Description: fileConfig.Description,
+			})
+		}
+		m.Environments = append(m.Environments, menv)
+	}
+
+	return m
+}
+
+// Marshal 按格式序列化清单（yaml 或 json）
+func (m *Manifest) Marshal(format string) ([]byte, error) {
+	switch strings.ToLower(format) {
+	case "json":
+		return json.MarshalIndent(m, "", "  ")
+	case "yaml", "yml", "":
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(m); err != nil {
+			return nil, err
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unsupported manifest format: %s", format)
+	}
+}
+
+// relativePath 将绝对路径转换为相对 baseDir 的路径，不在 baseDir 下时保持原样
+func relativePath(baseDir, path string) string {
+	if baseDir == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
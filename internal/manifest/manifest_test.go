@@ -0,0 +1,439 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
+)
+
+func setupTest(t *testing.T) string {
+	tempDir := t.TempDir()
+
+	// 保存原始配置并在测试结束后恢复
+	originalConfig := config.GetConfig()
+	t.Cleanup(func() {
+		_ = config.SaveConfig(originalConfig)
+	})
+
+	testConfig := &internal.Config{
+		DataDir:   filepath.Join(tempDir, "data"),
+		BackupDir: filepath.Join(tempDir, "backups"),
+		WebPort:   8080,
+	}
+	if err := config.SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save test config: %v", err)
+	}
+
+	_ = os.MkdirAll(testConfig.DataDir, 0755)
+	_ = os.MkdirAll(testConfig.BackupDir, 0755)
+
+	return tempDir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadValidate(t *testing.T) {
+	tempDir := t.TempDir()
+
+	path := filepath.Join(tempDir, DefaultFile)
+	writeFile(t, path, `project: demo
+environments:
+  - name: dev
+    files:
+      - source: configs/dev.yaml
+        target: app.yaml
+`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Project != "demo" || len(m.Environments) != 1 {
+		t.Fatalf("Unexpected manifest: %+v", m)
+	}
+	if got := m.resolvePath("configs/dev.yaml"); got != filepath.Join(tempDir, "configs/dev.yaml") {
+		t.Errorf("resolvePath() = %s", got)
+	}
+
+	writeFile(t, path, `project: demo
+environments:
+  - name: dev
+  - name: dev
+`)
+	if _, err := Load(path); err == nil {
+		t.Error("Expected error for duplicate environment")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	tempDir := setupTest(t)
+
+	writeFile(t, filepath.Join(tempDir, "configs", "dev.yaml"), "env: dev")
+	writeFile(t, filepath.Join(tempDir, "configs", "prod.yaml"), "env: prod")
+
+	path := filepath.Join(tempDir, DefaultFile)
+	writeFile(t, path, `project: demo
+description: Demo project
+environments:
+  - name: dev
+    tags: [local]
+    files:
+      - source: configs/dev.yaml
+        target: app.yaml
+  - name: prod
+    files:
+      - source: configs/prod.yaml
+        target: app.yaml
+`)
+
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	reconciler := NewReconciler()
+	plan, err := reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if add, change, destroy := plan.Summary(); add != 5 || change != 0 || destroy != 0 {
+		t.Errorf("Expected 5 to add, got %d/%d/%d", add, change, destroy)
+	}
+	if err := reconciler.Apply(plan); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// 再次计划应无变更
+	plan, err = reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("Expected no changes after apply, got %v", plan.Changes)
+	}
+
+	devEnv, err := project.NewManager().GetEnvironment("demo", "dev")
+	if err != nil {
+		t.Fatalf("GetEnvironment() error = %v", err)
+	}
+	devFileID := devEnv.Files[0].ID
+
+	// 删除 prod 并修改 dev
+	writeFile(t, path, `project: demo
+description: Demo project
+environments:
+  - name: dev
+    tags: [local, debug]
+    variables:
+      API_URL: http://localhost:8080
+    dotenv: .env
+    files:
+      - source: configs/prod.yaml
+        target: app.yaml
+`)
+	m, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	plan, err = reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(plan.Unmanaged) != 1 {
+		t.Errorf("Expected prod to be reported as unmanaged, got %v", plan.Unmanaged)
+	}
+
+	plan, err = reconciler.Plan(m, true)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if add, change, destroy := plan.Summary(); add != 0 || change != 2 || destroy != 1 {
+		t.Errorf("Expected 0/2/1, got %d/%d/%d", add, change, destroy)
+	}
+	if err := reconciler.Apply(plan); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	proj, err := project.NewManager().GetProject("demo")
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+	if len(proj.Environments) != 1 || len(proj.Environments[0].Tags) != 2 {
+		t.Fatalf("Unexpected project after prune: %+v", proj.Environments)
+	}
+	// 根目录为清单所在目录，路径按清单原样保存
+	if proj.Root != tempDir {
+		t.Errorf("Expected project root %s, got %s", tempDir, proj.Root)
+	}
+	if proj.Environments[0].Files[0].SourcePath != "configs/prod.yaml" {
+		t.Errorf("Unexpected source path: %s", proj.Environments[0].Files[0].SourcePath)
+	}
+	// 修改的文件映射原地更新，ID 不变
+	if proj.Environments[0].Files[0].ID != devFileID {
+		t.Errorf("Expected file config ID %s to survive apply, got %s", devFileID, proj.Environments[0].Files[0].ID)
+	}
+
+	// dump 后重新加载应与原清单一致
+	dumped := FromProject(proj, func(fc *internal.FileConfig) (string, string, error) {
+		return file.NewManager().ResolvePaths(proj, fc)
+	}, tempDir)
+	if dumped.Environments[0].Files[0].Source != "configs/prod.yaml" {
+		t.Errorf("Expected relative source in dump, got %s", dumped.Environments[0].Files[0].Source)
+	}
+	if dumped.Environments[0].Variables["API_URL"] != "http://localhost:8080" || dumped.Environments[0].DotEnv != ".env" {
+		t.Errorf("Expected variables in dump, got %+v", dumped.Environments[0])
+	}
+}
+
+func TestPruneSkipsProtected(t *testing.T) {
+	tempDir := setupTest(t)
+
+	path := filepath.Join(tempDir, DefaultFile)
+	writeFile(t, path, `project: demo
+environments:
+  - name: dev
+  - name: prod
+    protected: true
+`)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	reconciler := NewReconciler()
+	plan, err := reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if err := reconciler.Apply(plan); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if env, err := project.NewManager().GetEnvironment("demo", "prod"); err != nil || !env.Protected {
+		t.Fatalf("Expected prod to be created as protected, got %+v (%v)", env, err)
+	}
+
+	// 清单中移除 prod 后 prune 不会删除受保护的环境
+	writeFile(t, path, `project: demo
+environments:
+  - name: dev
+`)
+	m, err = Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	plan, err = reconciler.Plan(m, true)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if _, _, destroy := plan.Summary(); destroy != 0 {
+		t.Errorf("Expected protected environment not to be pruned, got %v", plan.Changes)
+	}
+	if len(plan.Unmanaged) != 1 {
+		t.Errorf("Expected protected environment to be reported, got %v", plan.Unmanaged)
+	}
+}
+
+func TestPlanProtectedChanges(t *testing.T) {
+	tempDir := setupTest(t)
+
+	path := filepath.Join(tempDir, DefaultFile)
+	writeFile(t, path, `project: demo
+environments:
+  - name: prod
+    protected: true
+`)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	reconciler := NewReconciler()
+	plan, err := reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if err := reconciler.Apply(plan); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// 只修改描述不需要确认
+	writeFile(t, path, `project: demo
+environments:
+  - name: prod
+    description: Production
+    protected: true
+`)
+	if m, err = Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if plan, err = reconciler.Plan(m, false); err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(plan.Changes) != 1 || len(plan.ProtectedEnvironments()) != 0 {
+		t.Errorf("Expected description change not to require confirmation, got %v", plan.Changes)
+	}
+
+	// 取消保护需要确认
+	writeFile(t, path, `project: demo
+environments:
+  - name: prod
+    protected: false
+`)
+	if m, err = Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if plan, err = reconciler.Plan(m, false); err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	envs := plan.ProtectedEnvironments()
+	if len(envs) != 1 || envs[0].Name != "prod" || !plan.Changes[0].Protected {
+		t.Errorf("Expected removing protection to require confirmation, got %v", plan.Changes)
+	}
+}
+
+func TestPlanProtectedFiles(t *testing.T) {
+	tempDir := setupTest(t)
+
+	writeFile(t, filepath.Join(tempDir, "configs", "app.yaml"), "env: prod")
+	writeFile(t, filepath.Join(tempDir, "configs", "db.yaml"), "db: prod")
+
+	path := filepath.Join(tempDir, DefaultFile)
+	writeFile(t, path, `project: demo
+environments:
+  - name: prod
+    protected: true
+    files:
+      - source: configs/app.yaml
+        target: app.yaml
+      - source: configs/db.yaml
+        target: db.yaml
+`)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	reconciler := NewReconciler()
+	plan, err := reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if len(plan.ProtectedEnvironments()) != 0 {
+		t.Errorf("Expected creating a protected environment not to require confirmation, got %v", plan.Changes)
+	}
+	if err := reconciler.Apply(plan); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// 修改和删除受保护环境中的文件映射需要确认
+	writeFile(t, path, `project: demo
+environments:
+  - name: prod
+    protected: true
+    files:
+      - source: configs/app.yaml
+        target: app.yaml
+        description: App config
+`)
+	if m, err = Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if plan, err = reconciler.Plan(m, true); err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if _, change, destroy := plan.Summary(); change != 1 || destroy != 1 {
+		t.Fatalf("Expected 1 file to change and 1 to destroy, got %v", plan.Changes)
+	}
+	for _, c := range plan.Changes {
+		if !c.Protected {
+			t.Errorf("Expected change %s to require confirmation", c.String())
+		}
+	}
+	if envs := plan.ProtectedEnvironments(); len(envs) != 1 || envs[0].Name != "prod" {
+		t.Errorf("Expected prod to require confirmation once, got %v", envs)
+	}
+}
+
+func TestApplyKeepsManagedSource(t *testing.T) {
+	tempDir := setupTest(t)
+
+	writeFile(t, filepath.Join(tempDir, "configs", "dev.yaml"), "env: dev")
+
+	path := filepath.Join(tempDir, DefaultFile)
+	writeFile(t, path, `project: demo
+environments:
+  - name: dev
+`)
+	m, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	reconciler := NewReconciler()
+	plan, err := reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if err := reconciler.Apply(plan); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	// 将源文件导入为托管文件
+	proj, err := project.NewManager().GetProject("demo")
+	if err != nil {
+		t.Fatalf("GetProject() error = %v", err)
+	}
+	managed, err := file.NewManager().ImportFileConfig(proj.ID, proj.Environments[0].ID, filepath.Join(tempDir, "configs", "dev.yaml"), filepath.Join(tempDir, "app.yaml"), "")
+	if err != nil {
+		t.Fatalf("ImportFileConfig() error = %v", err)
+	}
+
+	// 清单中的源路径与托管路径不同，只有描述变更
+	writeFile(t, path, `project: demo
+environments:
+  - name: dev
+    files:
+      - source: configs/dev.yaml
+        target: app.yaml
+        description: App config
+`)
+	if m, err = Load(path); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	plan, err = reconciler.Plan(m, false)
+	if err != nil {
+		t.Fatalf("Plan() error = %v", err)
+	}
+	if add, change, destroy := plan.Summary(); add != 0 || change != 1 || destroy != 0 {
+		t.Fatalf("Expected 0/1/0, got %d/%d/%d: %v", add, change, destroy, plan.Changes)
+	}
+	if err := reconciler.Apply(plan); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+
+	env, err := project.NewManager().GetEnvironment("demo", "dev")
+	if err != nil {
+		t.Fatalf("GetEnvironment() error = %v", err)
+	}
+	updated := env.Files[0]
+	if updated.ID != managed.ID || !updated.Managed || updated.SourcePath != managed.SourcePath || updated.Version != managed.Version {
+		t.Errorf("Expected managed file config to survive apply, got %+v (was %+v)", updated, managed)
+	}
+	if updated.Description != "App config" {
+		t.Errorf("Expected description to be updated, got %q", updated.Description)
+	}
+	if _, err := os.Stat(filepath.Join(config.GetDataDir(), managed.SourcePath)); err != nil {
+		t.Errorf("Expected managed source to be kept: %v", err)
+	}
+}
@@ -0,0 +1,51 @@
+package metrics
+
+// 切换失败的原因
+const (
+	ReasonNotFound   = "not_found"
+	ReasonBackup     = "backup"
+	ReasonPermission = "permission"
+	ReasonSource     = "source_missing"
+	ReasonFile       = "file"
+	ReasonDotEnv     = "dotenv"
+	ReasonState      = "state"
+)
+
+// envswitch 的业务指标，由切换和回滚的执行者记录
+var (
+	// Switches 环境切换次数，result 为 success 或 failure
+	Switches = NewCounterVec("envswitch_switches_total",
+		"Number of environment switches by project, environment and result.",
+		"project", "environment", "result")
+	// SwitchDuration 环境切换耗时
+	SwitchDuration = NewHistogramVec("envswitch_switch_duration_seconds",
+		"Duration of environment switches in seconds.",
+		DefaultBuckets, "project", "environment")
+	// SwitchFailures 切换失败次数，按失败原因分类
+	SwitchFailures = NewCounterVec("envswitch_switch_failures_total",
+		"Number of failed environment switches by project, environment and reason.",
+		"project", "environment", "reason")
+	// Rollbacks 从备份回滚的次数（包括切换失败后的自动回滚），result 为 success 或 failure
+	Rollbacks = NewCounterVec("envswitch_rollbacks_total",
+		"Number of rollbacks from backups by result, including automatic rollbacks after failed switches.",
+		"result")
+	// HTTPRequestDuration Web 服务的请求耗时，route 为路由模板
+	HTTPRequestDuration = NewHistogramVec("envswitch_http_request_duration_seconds",
+		"Latency of HTTP requests handled by the web server in seconds.",
+		DefaultBuckets, "method", "route", "status")
+)
+
+// Default 全局指标集合，/metrics 输出其中的指标
+var Default = NewRegistry()
+
+func init() {
+	Default.MustRegister(Switches, SwitchDuration, SwitchFailures, Rollbacks, HTTPRequestDuration)
+}
+
+// Result 根据错误返回 result 标签值
+func Result(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
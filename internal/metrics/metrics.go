@@ -0,0 +1,305 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 耗时直方图的默认桶（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Collector 可以 Prometheus 文本格式输出的指标
+type Collector interface {
+	Write(w io.Writer) error
+}
+
+// Registry 指标集合，按注册顺序输出
+type Registry struct {
+	mu         sync.Mutex
+	collectors []Collector
+}
+
+// NewRegistry 创建指标集合
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// MustRegister 注册指标
+func (r *Registry) MustRegister(collectors ...Collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, collectors...)
+}
+
+// Write 依次输出全部指标，Registry 本身也是 Collector，可注册到其他集合中
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]Collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		if err := c.Write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteText 以 Prometheus 文本格式输出全部指标
+func (r *Registry) WriteText(w io.Writer) error {
+	buf := bufio.NewWriter(w)
+	if err := r.Write(buf); err != nil {
+		return err
+	}
+	return buf.Flush()
+}
+
+// series 一组标签值对应的时间序列
+type series struct {
+	labels []string
+	value  float64
+	// 直方图
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// vec 带标签的指标的公共部分
+type vec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func newVec(name, help string, labels []string) vec {
+	return vec{name: name, help: help, labels: labels, series: make(map[string]*series)}
+}
+
+// get 返回标签值对应的时间序列，不存在时创建
+func (v *vec) get(labelValues []string) *series {
+	if len(labelValues) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.name, len(v.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), labelValues...)}
+		v.series[key] = s
+	}
+	return s
+}
+
+// sorted 按标签值排序的时间序列，保证输出稳定
+func (v *vec) sorted() []*series {
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]*series, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, v.series[key])
+	}
+	return result
+}
+
+func (v *vec) writeHeader(w io.Writer, kind string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, escapeHelp(v.help), v.name, kind)
+	return err
+}
+
+// CounterVec 只增不减的计数器
+type CounterVec struct {
+	vec
+}
+
+// NewCounterVec 创建计数器
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{vec: newVec(name, help, labels)}
+}
+
+// Inc 计数加一
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加 delta，delta 不能为负
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.get(labelValues).value += delta
+}
+
+// Value 返回标签值对应的计数
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.get(labelValues).value
+}
+
+// Write 输出计数器
+func (c *CounterVec) Write(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.writeHeader(w, "counter"); err != nil {
+		return err
+	}
+	for _, s := range c.sorted() {
+		if err := writeSample(w, c.name, c.labels, s.labels, "", "", s.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// HistogramVec 按桶统计观测值（如耗时）的直方图
+type HistogramVec struct {
+	vec
+	buckets []float64
+}
+
+// NewHistogramVec 创建直方图，buckets 为递增的桶上限
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return &HistogramVec{vec: newVec(name, help, labels), buckets: buckets}
+}
+
+// Observe 记录一个观测值
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := h.get(labelValues)
+	if s.counts == nil {
+		s.counts = make([]uint64, len(h.buckets))
+	}
+	for i, bound := range h.buckets {
+		if value <= bound {
+			s.counts[i]++
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+// Count 返回标签值对应的观测次数
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.get(labelValues).count
+}
+
+// Write 输出直方图的 _bucket、_sum 和 _count
+func (h *HistogramVec) Write(w io.Writer) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.writeHeader(w, "histogram"); err != nil {
+		return err
+	}
+	for _, s := range h.sorted() {
+		for i, bound := range h.buckets {
+			var count uint64
+			if s.counts != nil {
+				count = s.counts[i]
+			}
+			if err := writeSample(w, h.name+"_bucket", h.labels, s.labels, "le", formatFloat(bound), float64(count)); err != nil {
+				return err
+			}
+		}
+		if err := writeSample(w, h.name+"_bucket", h.labels, s.labels, "le", "+Inf", float64(s.count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.name+"_sum", h.labels, s.labels, "", "", s.sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.name+"_count", h.labels, s.labels, "", "", float64(s.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GaugeFunc 输出时调用函数取值的仪表盘指标
+type GaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc 创建仪表盘指标
+func NewGaugeFunc(name, help string, fn func() float64) *GaugeFunc {
+	return &GaugeFunc{name: name, help: help, fn: fn}
+}
+
+// Write 输出仪表盘指标
+func (g *GaugeFunc) Write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, escapeHelp(g.help), g.name); err != nil {
+		return err
+	}
+	return writeSample(w, g.name, nil, nil, "", "", g.fn())
+}
+
+// writeSample 输出一行样本，extraName 非空时追加一个标签（直方图的 le）
+func writeSample(w io.Writer, name string, labels, values []string, extraName, extraValue string, value float64) error {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labels) > 0 || extraName != "" {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extraName != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(extraName + `="` + extraValue + `"`)
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+	counter := NewCounterVec("test_events_total", "Number of test events.", "name")
+	histogram := NewHistogramVec("test_duration_seconds", "Duration of tests.", []float64{0.1, 1}, "name")
+	gauge := NewGaugeFunc("test_clients", "Connected clients.", func() float64 { return 3 })
+	registry.MustRegister(counter, histogram, gauge)
+
+	counter.Inc("b")
+	counter.Add(2, `a"\`)
+	histogram.Observe(0.05, "x")
+	histogram.Observe(0.5, "x")
+	histogram.Observe(5, "x")
+
+	var b strings.Builder
+	if err := registry.WriteText(&b); err != nil {
+		t.Fatalf("WriteText() error = %v", err)
+	}
+
+	expected := `# HELP test_events_total Number of test events.
+# TYPE test_events_total counter
+test_events_total{name="a\"\\"} 2
+test_events_total{name="b"} 1
+# HELP test_duration_seconds Duration of tests.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{name="x",le="0.1"} 1
+test_duration_seconds_bucket{name="x",le="1"} 2
+test_duration_seconds_bucket{name="x",le="+Inf"} 3
+test_duration_seconds_sum{name="x"} 5.55
+test_duration_seconds_count{name="x"} 3
+# HELP test_clients Connected clients.
+# TYPE test_clients gauge
+test_clients 3
+`
+	if b.String() != expected {
+		t.Errorf("Unexpected output:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+
+	if counter.Value("b") != 1 || histogram.Count("x") != 3 {
+		t.Errorf("Unexpected values %v, %d", counter.Value("b"), histogram.Count("x"))
+	}
+}
+
+func TestLabelCount(t *testing.T) {
+	counter := NewCounterVec("test_total", "Test.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic for wrong number of label values")
+		}
+	}()
+	counter.Inc("only-one")
+}
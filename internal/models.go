@@ -6,54 +6,139 @@ import (
 
 // Project 项目结构
 type Project struct {
-	ID           string        `json:"id"`
-	Name         string        `json:"name"`
-	Description  string        `json:"description"`
-	CreatedAt    time.Time     `json:"created_at"`
-	UpdatedAt    time.Time     `json:"updated_at"`
-	Environments []Environment `json:"environments"`
+	ID            string            `json:"id"`
+	Name          string            `json:"name"`
+	Description   string            `json:"description"`
+	Root          string            `json:"root,omitempty"`           // 项目根目录，相对路径以此为基准，可通过 ${PROJECT_ROOT} 引用
+	PathVariables map[string]string `json:"path_variables,omitempty"` // 项目路径变量，可在文件路径中以 ${NAME} 引用
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Environments  []Environment     `json:"environments"`
 }
 
 // Environment 环境结构
 type Environment struct {
-	ID           string       `json:"id"`
-	Name         string       `json:"name"`
-	Description  string       `json:"description"`
-	Tags         []string     `json:"tags"`
-	CreatedAt    time.Time    `json:"created_at"`
-	UpdatedAt    time.Time    `json:"updated_at"`
-	LastSwitchAt *time.Time   `json:"last_switch_at,omitempty"`
-	Files        []FileConfig `json:"files"`
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Description  string            `json:"description"`
+	Tags         []string          `json:"tags"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	LastSwitchAt *time.Time        `json:"last_switch_at,omitempty"`
+	Files        []FileConfig      `json:"files"`
+	Variables    map[string]string `json:"variables,omitempty"`   // 环境变量，可通过 shell-env 导出
+	DotEnvPath   string            `json:"dotenv_path,omitempty"` // 切换时写入环境变量的 .env 文件路径（可选，支持路径变量）
+	Protected    bool              `json:"protected,omitempty"`   // 受保护环境，切换和删除前需要输入环境名称确认
 }
 
 // FileConfig 文件配置结构
 type FileConfig struct {
 	ID          string `json:"id"`
-	SourcePath  string `json:"source_path"` // 模板文件路径
-	TargetPath  string `json:"target_path"` // 目标替换路径
+	SourcePath  string `json:"source_path"` // 模板文件路径，支持 ~、$HOME、${PROJECT_ROOT} 等路径变量和通配符（含 **）
+	TargetPath  string `json:"target_path"` // 目标替换路径，支持路径变量；源路径为通配符时为目标目录
 	BackupPath  string `json:"backup_path"` // 备份文件路径
 	Description string `json:"description"`
+	Managed     bool   `json:"managed,omitempty"`     // 源文件由envswitch托管，SourcePath为相对数据目录的路径
+	Version     int    `json:"version,omitempty"`     // 托管源文件的版本号，每次编辑递增
+	AllowEmpty  bool   `json:"allow_empty,omitempty"` // 源路径为通配符模式时允许没有匹配文件
+}
+
+// FileMapping 展开后的具体文件映射（通配符文件配置可展开为多个映射）
+type FileMapping struct {
+	SourcePath string `json:"source_path"`
+	TargetPath string `json:"target_path"`
 }
 
 // Config 全局配置结构
 type Config struct {
-	DataDir            string   `json:"data_dir"`
-	BackupDir          string   `json:"backup_dir"`
-	WebPort            int      `json:"web_port"`
-	DefaultProject     string   `json:"default_project"`
-	OriginalDataDir    string   `json:"original_data_dir,omitempty"` // 原始数据目录路径
-	DataDirHistory     []string `json:"data_dir_history,omitempty"`  // 历史数据目录记录
-	EnableDataDirCheck bool     `json:"enable_data_dir_check"`       // 是否启用数据目录变更检查
+	DataDir            string            `json:"data_dir"`
+	BackupDir          string            `json:"backup_dir"`
+	WebPort            int               `json:"web_port"`
+	DefaultProject     string            `json:"default_project"`
+	OriginalDataDir    string            `json:"original_data_dir,omitempty"`    // 原始数据目录路径
+	DataDirHistory     []string          `json:"data_dir_history,omitempty"`     // 历史数据目录记录
+	EnableDataDirCheck bool              `json:"enable_data_dir_check"`          // 是否启用数据目录变更检查
+	ActiveProfile      string            `json:"active_profile,omitempty"`       // 当前使用的配置档案
+	Profiles           []Profile         `json:"profiles,omitempty"`             // 命名配置档案
+	PathVariables      map[string]string `json:"path_variables,omitempty"`       // 本机路径变量，覆盖项目中的同名变量
+	ProtectedTags      []string          `json:"protected_tags,omitempty"`       // 带有这些标签的环境视为受保护环境
+	Tokens             []APIToken        `json:"tokens,omitempty"`               // Web 服务的 API 令牌，存在令牌时启用认证
+	Remote             string            `json:"remote,omitempty"`               // 远程 envswitch 服务地址，设置后命令通过 REST API 操作
+	RemoteToken        string            `json:"remote_token,omitempty"`         // 访问远程服务的 API 令牌
+	WebBind            string            `json:"web_bind,omitempty"`             // Web 服务监听的地址，默认只监听本机
+	WebTLSCert         string            `json:"web_tls_cert,omitempty"`         // HTTPS 证书文件
+	WebTLSKey          string            `json:"web_tls_key,omitempty"`          // HTTPS 私钥文件
+	WebTLSSelfSigned   bool              `json:"web_tls_self_signed,omitempty"`  // 使用自动生成的自签名证书启用 HTTPS
+	WebUnixSocket      string            `json:"web_unix_socket,omitempty"`      // 监听 Unix 套接字而不是 TCP 端口
+	WebUnixSocketMode  string            `json:"web_unix_socket_mode,omitempty"` // Unix 套接字文件的权限（八进制，如 0660）
+}
+
+// APIToken Web 服务的 API 令牌，只保存令牌的 SHA-256 哈希
+type APIToken struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Hash      string     `json:"hash,omitempty"`
+	Role      string     `json:"role"`               // viewer、operator 或 admin
+	Projects  []string   `json:"projects,omitempty"` // 可访问的项目名称，为空时可访问全部项目
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Profile 命名配置档案（类似 kubectl context），每个档案拥有独立的数据目录
+type Profile struct {
+	Name           string `json:"name"`
+	DataDir        string `json:"data_dir"`
+	BackupDir      string `json:"backup_dir"`
+	WebPort        int    `json:"web_port,omitempty"`
+	DefaultProject string `json:"default_project,omitempty"`
+	Remote         string `json:"remote,omitempty"`       // 远程 envswitch 服务地址
+	RemoteToken    string `json:"remote_token,omitempty"` // 访问远程服务的 API 令牌
 }
 
 // AppState 应用状态
 type AppState struct {
 	CurrentProject     string     `json:"current_project"`
 	CurrentEnvironment string     `json:"current_environment"`
+	ProjectName        string     `json:"project_name,omitempty"`     // 项目名称缓存，供 prompt 快速读取
+	EnvironmentName    string     `json:"environment_name,omitempty"` // 环境名称缓存，供 prompt 快速读取
 	LastSwitchAt       *time.Time `json:"last_switch_at,omitempty"`
 	BackupID           string     `json:"backup_id,omitempty"`
 }
 
+// SwitchResult 环境切换结果
+type SwitchResult struct {
+	Project     string        `json:"project"`
+	Environment string        `json:"environment"`
+	DryRun      bool          `json:"dry_run"`
+	BackupID    string        `json:"backup_id,omitempty"`
+	Files       []FileMapping `json:"files"`
+	DotEnvPath  string        `json:"dotenv_path,omitempty"`
+	SwitchedAt  *time.Time    `json:"switched_at,omitempty"`
+}
+
+// RollbackResult 回滚结果
+type RollbackResult struct {
+	BackupID      string   `json:"backup_id"`
+	Project       string   `json:"project,omitempty"`
+	Environment   string   `json:"environment,omitempty"`
+	RestoredFiles []string `json:"restored_files"`
+}
+
+// StatusInfo 当前状态
+type StatusInfo struct {
+	Profile         string        `json:"profile"`
+	DetectedProject string        `json:"detected_project,omitempty"`
+	MarkerPath      string        `json:"marker_path,omitempty"`
+	Active          bool          `json:"active"`
+	Project         string        `json:"project,omitempty"`
+	ProjectID       string        `json:"project_id,omitempty"`
+	Environment     string        `json:"environment,omitempty"`
+	EnvironmentID   string        `json:"environment_id,omitempty"`
+	LastSwitchAt    *time.Time    `json:"last_switch_at,omitempty"`
+	BackupID        string        `json:"backup_id,omitempty"`
+	Files           []FileMapping `json:"files,omitempty"`
+}
+
 // SwitchRequest 切换请求
 type SwitchRequest struct {
 	ProjectID     string `json:"project_id"`
@@ -0,0 +1,391 @@
+package project
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/events"
+	"github.com/zoyopei/envswitch/internal/storage"
+
+	"github.com/google/uuid"
+)
+
+const (
+	bundleProjectFile   = "project.json"
+	bundleSourcesDir    = "sources"
+	bundleFormatVersion = 1
+
+	// MaxBundleEntrySize 导入打包文件时单个文件解压后的大小上限
+	MaxBundleEntrySize = 64 << 20
+	// MaxBundleSize 导入打包文件时全部文件解压后的总大小上限
+	MaxBundleSize = 256 << 20
+)
+
+// ErrBundleTooLarge 打包文件解压后超过 MaxBundleEntrySize 或 MaxBundleSize
+var ErrBundleTooLarge = errors.New("bundle is too large")
+
+// bundleProject 打包文件中的项目描述
+type bundleProject struct {
+	FormatVersion int               `json:"format_version"`
+	ExportedAt    time.Time         `json:"exported_at"`
+	Project       *internal.Project `json:"project"`
+}
+
+// ImportOptions 导入打包文件的选项
+type ImportOptions struct {
+	Name        string            // 导入后的项目名称，为空时使用原名称
+	RemapTarget map[string]string // 目标路径前缀替换 old -> new
+}
+
+// ExportProject 将项目及其引用的全部源文件导出为 tar.gz 打包文件
+func (m *Manager) ExportProject(identifier string, w io.Writer) error {
+	project, err := m.GetProject(identifier)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	// 源文件路径改写为打包文件内的相对路径
+	exported := *project
+	exported.Environments = make([]internal.Environment, len(project.Environments))
+	for i, env := range project.Environments {
+		env.Files = append([]internal.FileConfig{}, env.Files...)
+		for j := range env.Files {
+			fileConfig := &env.Files[j]
+			sourcePath, _, err := m.storage.ResolvePaths(project, fileConfig)
+			if err != nil {
+				return err
+			}
+			bundleDir := path.Join(bundleSourcesDir, env.ID, fileConfig.ID)
+			bundlePath := path.Join(bundleDir, filepath.Base(sourcePath))
+
+			if storage.IsGlobPattern(sourcePath) {
+				// 通配符：导出全部匹配文件，打包路径保留剩余模式
+				base, files, err := storage.GlobFiles(sourcePath)
+				if err != nil {
+					return err
+				}
+				for _, rel := range files {
+					if err := addFileToTar(tw, filepath.Join(base, filepath.FromSlash(rel)), path.Join(bundleDir, rel)); err != nil {
+						return fmt.Errorf("failed to export source file %s: %w", rel, err)
+					}
+				}
+				_, rest := storage.SplitGlobPattern(sourcePath)
+				bundlePath = path.Join(bundleDir, rest)
+			} else if err := addFileToTar(tw, sourcePath, bundlePath); err != nil {
+				return fmt.Errorf("failed to export source file %s: %w", sourcePath, err)
+			}
+
+			fileConfig.SourcePath = bundlePath
+			fileConfig.BackupPath = ""
+			fileConfig.Managed = false
+			fileConfig.Version = 0
+		}
+		exported.Environments[i] = env
+	}
+
+	data, err := json.MarshalIndent(&bundleProject{
+		FormatVersion: bundleFormatVersion,
+		ExportedAt:    time.Now(),
+		Project:       &exported,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal project: %w", err)
+	}
+
+	header := &tar.Header{
+		Name:    bundleProjectFile,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ImportProject 从 tar.gz 打包文件导入项目，源文件导入为托管源文件
+// 项目或环境ID已存在或不是UUID时重新生成所有ID；解压后超过大小上限时返回 ErrBundleTooLarge
+func (m *Manager) ImportProject(r io.Reader, opts ImportOptions) (*internal.Project, error) {
+	tempDir, err := os.MkdirTemp("", "envswitch-import-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer func() { _ = os.RemoveAll(tempDir) }()
+
+	if err := extractBundle(r, tempDir, MaxBundleEntrySize, MaxBundleSize); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, bundleProjectFile))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: missing %s", bundleProjectFile)
+	}
+
+	var bundle bundleProject
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: failed to parse %s: %w", bundleProjectFile, err)
+	}
+	if bundle.Project == nil {
+		return nil, fmt.Errorf("invalid bundle: no project found")
+	}
+	if bundle.FormatVersion > bundleFormatVersion {
+		return nil, fmt.Errorf("unsupported bundle format version %d", bundle.FormatVersion)
+	}
+
+	project := bundle.Project
+	if opts.Name != "" {
+		project.Name = opts.Name
+	}
+	if project.Name == "" {
+		return nil, fmt.Errorf("project name cannot be empty")
+	}
+
+	// 检查项目名称是否已存在
+	if _, err := m.storage.LoadProjectByName(project.Name); err == nil {
+		return nil, internal.AlreadyExistsf("project with name '%s' already exists, use a different name to import", project.Name)
+	}
+
+	// ID 会用作数据目录中的路径，打包文件中的ID不是UUID时一律重新生成
+	conflict, err := m.hasIDConflict(project)
+	if err != nil {
+		return nil, err
+	}
+	if conflict || !hasValidIDs(project) {
+		regenerateIDs(project)
+	}
+
+	now := time.Now()
+	project.CreatedAt = now
+	project.UpdatedAt = now
+
+	for i := range project.Environments {
+		env := &project.Environments[i]
+		env.LastSwitchAt = nil
+
+		for j := range env.Files {
+			fileConfig := &env.Files[j]
+
+			extracted, err := safeJoin(tempDir, fileConfig.SourcePath)
+			if err != nil {
+				_ = m.storage.DeleteSources(project.ID, "")
+				return nil, err
+			}
+
+			managedPath, err := m.importSource(project.ID, env.ID, extracted)
+			if err != nil {
+				_ = m.storage.DeleteSources(project.ID, "")
+				return nil, err
+			}
+
+			fileConfig.SourcePath = managedPath
+			fileConfig.Managed = true
+			fileConfig.Version = 1
+			fileConfig.TargetPath = remapTarget(fileConfig.TargetPath, opts.RemapTarget)
+		}
+	}
+
+	if err := m.storage.SaveProject(project); err != nil {
+		_ = m.storage.DeleteSources(project.ID, "")
+		return nil, fmt.Errorf("failed to save project: %w", err)
+	}
+
+	publishProject(events.ProjectCreated, project)
+	return project, nil
+}
+
+// hasIDConflict 检查项目、环境或文件配置ID是否已被现有项目使用
+func (m *Manager) hasIDConflict(project *internal.Project) (bool, error) {
+	projects, err := m.storage.ListProjects()
+	if err != nil {
+		return false, err
+	}
+
+	used := make(map[string]bool)
+	for _, p := range projects {
+		used[p.ID] = true
+		for _, env := range p.Environments {
+			used[env.ID] = true
+			for _, fileConfig := range env.Files {
+				used[fileConfig.ID] = true
+			}
+		}
+	}
+
+	if used[project.ID] {
+		return true, nil
+	}
+	for _, env := range project.Environments {
+		if used[env.ID] {
+			return true, nil
+		}
+		for _, fileConfig := range env.Files {
+			if used[fileConfig.ID] {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// hasValidIDs 检查项目、环境和文件配置ID是否都是标准格式的UUID
+func hasValidIDs(project *internal.Project) bool {
+	ids := []string{project.ID}
+	for _, env := range project.Environments {
+		ids = append(ids, env.ID)
+		for _, fileConfig := range env.Files {
+			ids = append(ids, fileConfig.ID)
+		}
+	}
+
+	for _, id := range ids {
+		// 只接受标准格式，uuid.Parse 也接受 urn:uuid: 和花括号形式
+		if parsed, err := uuid.Parse(id); err != nil || parsed.String() != id {
+			return false
+		}
+	}
+	return true
+}
+
+// regenerateIDs 为项目及其所有环境和文件配置重新生成ID
+func regenerateIDs(project *internal.Project) {
+	project.ID = uuid.New().String()
+	for i := range project.Environments {
+		env := &project.Environments[i]
+		env.ID = uuid.New().String()
+		for j := range env.Files {
+			env.Files[j].ID = uuid.New().String()
+		}
+	}
+}
+
+// remapTarget 按最长前缀匹配替换目标路径
+func remapTarget(targetPath string, remap map[string]string) string {
+	bestOld := ""
+	for old := range remap {
+		if (targetPath == old || strings.HasPrefix(targetPath, strings.TrimSuffix(old, "/")+"/")) && len(old) > len(bestOld) {
+			bestOld = old
+		}
+	}
+
+	if bestOld == "" {
+		return targetPath
+	}
+	return remap[bestOld] + strings.TrimPrefix(targetPath, bestOld)
+}
+
+// addFileToTar 将文件写入 tar 归档
+func addFileToTar(tw *tar.Writer, srcPath, name string) error {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+// extractBundle 解压打包文件到指定目录，按实际解压的字节数限制单个文件和全部文件的大小
+func extractBundle(r io.Reader, dir string, maxEntrySize, maxTotalSize int64) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid bundle: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	var total int64
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid bundle: %w", err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Size > maxEntrySize {
+			return fmt.Errorf("%w: %s is %d bytes, the limit is %d bytes", ErrBundleTooLarge, header.Name, header.Size, maxEntrySize)
+		}
+
+		dstPath, err := safeJoin(dir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+			return err
+		}
+
+		file, err := os.OpenFile(dstPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode)&0777|0600)
+		if err != nil {
+			return err
+		}
+		// 多读一个字节以判断是否超过上限，不信任头部声明的大小
+		limit := min(maxEntrySize, maxTotalSize-total)
+		n, err := io.Copy(file, io.LimitReader(tr, limit+1))
+		if err != nil {
+			_ = file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		if n > maxEntrySize {
+			return fmt.Errorf("%w: %s exceeds %d bytes", ErrBundleTooLarge, header.Name, maxEntrySize)
+		}
+		total += n
+		if total > maxTotalSize {
+			return fmt.Errorf("%w: extracted content exceeds %d bytes", ErrBundleTooLarge, maxTotalSize)
+		}
+	}
+}
+
+// safeJoin 拼接打包文件内路径，防止路径穿越
+func safeJoin(dir, name string) (string, error) {
+	cleaned := filepath.Clean(filepath.FromSlash(name))
+	if filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid bundle: illegal path %s", name)
+	}
+	return filepath.Join(dir, cleaned), nil
+}
@@ -2,9 +2,13 @@ package project
 
 import (
 	"fmt"
+	"path/filepath"
+	"regexp"
 	"time"
 
 	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/events"
 	"github.com/zoyopei/envswitch/internal/storage"
 
 	"github.com/google/uuid"
@@ -30,7 +34,7 @@ func (m *Manager) CreateProject(name, description string) (*internal.Project, er
 	// 检查项目名称是否已存在
 	_, err := m.storage.LoadProjectByName(name)
 	if err == nil {
-		return nil, fmt.Errorf("project with name '%s' already exists", name)
+		return nil, internal.AlreadyExistsf("project with name '%s' already exists", name)
 	}
 
 	project := &internal.Project{
@@ -46,6 +50,7 @@ func (m *Manager) CreateProject(name, description string) (*internal.Project, er
 		return nil, fmt.Errorf("failed to save project: %w", err)
 	}
 
+	publishProject(events.ProjectCreated, project)
 	return project, nil
 }
 
@@ -60,7 +65,7 @@ func (m *Manager) GetProject(identifier string) (*internal.Project, error) {
 	// 如果通过ID获取失败，尝试通过名称获取
 	project, err = m.storage.LoadProjectByName(identifier)
 	if err != nil {
-		return nil, fmt.Errorf("project not found: %s", identifier)
+		return nil, internal.NotFoundf("project not found: %s", identifier)
 	}
 
 	return project, nil
@@ -84,7 +89,7 @@ func (m *Manager) UpdateProject(identifier string, updates map[string]interface{
 			// 检查新名称是否已被其他项目使用
 			existingProject, err := m.storage.LoadProjectByName(nameStr)
 			if err == nil && existingProject.ID != project.ID {
-				return nil, fmt.Errorf("project with name '%s' already exists", nameStr)
+				return nil, internal.AlreadyExistsf("project with name '%s' already exists", nameStr)
 			}
 			project.Name = nameStr
 		}
@@ -96,12 +101,38 @@ func (m *Manager) UpdateProject(identifier string, updates map[string]interface{
 		}
 	}
 
+	if root, ok := updates["root"]; ok {
+		if rootStr, ok := root.(string); ok {
+			project.Root = rootStr
+		}
+	}
+
+	// 路径变量按名称合并，值为空时删除
+	if variables, ok := updates["path_variables"]; ok {
+		if vars, ok := variables.(map[string]string); ok {
+			for name, value := range vars {
+				if err := config.ValidatePathVariableName(name); err != nil {
+					return nil, err
+				}
+				if value == "" {
+					delete(project.PathVariables, name)
+					continue
+				}
+				if project.PathVariables == nil {
+					project.PathVariables = make(map[string]string)
+				}
+				project.PathVariables[name] = value
+			}
+		}
+	}
+
 	project.UpdatedAt = time.Now()
 
 	if err := m.storage.SaveProject(project); err != nil {
 		return nil, fmt.Errorf("failed to update project: %w", err)
 	}
 
+	publishProject(events.ProjectUpdated, project)
 	return project, nil
 }
 
@@ -112,7 +143,14 @@ func (m *Manager) DeleteProject(identifier string) error {
 		return err
 	}
 
-	return m.storage.DeleteProject(project.ID)
+	if err := m.storage.DeleteProject(project.ID); err != nil {
+		return err
+	}
+
+	publishProject(events.ProjectDeleted, project)
+
+	// 清理项目的托管源文件
+	return m.storage.DeleteSources(project.ID, "")
 }
 
 // AddEnvironment 向项目添加环境
@@ -125,7 +163,13 @@ func (m *Manager) AddEnvironment(projectIdentifier string, env *internal.Environ
 	// 检查环境名称是否已存在
 	for _, existingEnv := range project.Environments {
 		if existingEnv.Name == env.Name {
-			return fmt.Errorf("environment with name '%s' already exists in project '%s'", env.Name, project.Name)
+			return internal.AlreadyExistsf("environment with name '%s' already exists in project '%s'", env.Name, project.Name)
+		}
+	}
+
+	for name := range env.Variables {
+		if err := ValidateVariableName(name); err != nil {
+			return err
 		}
 	}
 
@@ -141,7 +185,12 @@ func (m *Manager) AddEnvironment(projectIdentifier string, env *internal.Environ
 	project.Environments = append(project.Environments, *env)
 	project.UpdatedAt = time.Now()
 
-	return m.storage.SaveProject(project)
+	if err := m.storage.SaveProject(project); err != nil {
+		return err
+	}
+
+	publishEnvironment(events.EnvironmentCreated, project, env)
+	return nil
 }
 
 // UpdateEnvironment 更新环境
@@ -161,7 +210,7 @@ func (m *Manager) UpdateEnvironment(projectIdentifier, envIdentifier string, upd
 	}
 
 	if envIndex == -1 {
-		return nil, fmt.Errorf("environment not found: %s", envIdentifier)
+		return nil, internal.NotFoundf("environment not found: %s", envIdentifier)
 	}
 
 	env := &project.Environments[envIndex]
@@ -172,7 +221,7 @@ func (m *Manager) UpdateEnvironment(projectIdentifier, envIdentifier string, upd
 			// 检查新名称是否已被其他环境使用
 			for i, existingEnv := range project.Environments {
 				if i != envIndex && existingEnv.Name == nameStr {
-					return nil, fmt.Errorf("environment with name '%s' already exists", nameStr)
+					return nil, internal.AlreadyExistsf("environment with name '%s' already exists", nameStr)
 				}
 			}
 			env.Name = nameStr
@@ -191,6 +240,33 @@ func (m *Manager) UpdateEnvironment(projectIdentifier, envIdentifier string, upd
 		}
 	}
 
+	// 环境变量整体替换
+	if variables, ok := updates["variables"]; ok {
+		if vars, ok := variables.(map[string]string); ok {
+			for name := range vars {
+				if err := ValidateVariableName(name); err != nil {
+					return nil, err
+				}
+			}
+			if len(vars) == 0 {
+				vars = nil
+			}
+			env.Variables = vars
+		}
+	}
+
+	if dotEnvPath, ok := updates["dotenv_path"]; ok {
+		if pathStr, ok := dotEnvPath.(string); ok {
+			env.DotEnvPath = pathStr
+		}
+	}
+
+	if protected, ok := updates["protected"]; ok {
+		if protectedBool, ok := protected.(bool); ok {
+			env.Protected = protectedBool
+		}
+	}
+
 	env.UpdatedAt = time.Now()
 	project.UpdatedAt = time.Now()
 
@@ -198,6 +274,7 @@ func (m *Manager) UpdateEnvironment(projectIdentifier, envIdentifier string, upd
 		return nil, fmt.Errorf("failed to update environment: %w", err)
 	}
 
+	publishEnvironment(events.EnvironmentUpdated, project, env)
 	return env, nil
 }
 
@@ -218,14 +295,23 @@ func (m *Manager) RemoveEnvironment(projectIdentifier, envIdentifier string) err
 	}
 
 	if envIndex == -1 {
-		return fmt.Errorf("environment not found: %s", envIdentifier)
+		return internal.NotFoundf("environment not found: %s", envIdentifier)
 	}
 
 	// 移除环境
+	removed := project.Environments[envIndex]
+	envID := removed.ID
 	project.Environments = append(project.Environments[:envIndex], project.Environments[envIndex+1:]...)
 	project.UpdatedAt = time.Now()
 
-	return m.storage.SaveProject(project)
+	if err := m.storage.SaveProject(project); err != nil {
+		return err
+	}
+
+	publishEnvironment(events.EnvironmentDeleted, project, &removed)
+
+	// 清理环境的托管源文件
+	return m.storage.DeleteSources(project.ID, envID)
 }
 
 // GetEnvironment 获取环境
@@ -241,7 +327,7 @@ func (m *Manager) GetEnvironment(projectIdentifier, envIdentifier string) (*inte
 		}
 	}
 
-	return nil, fmt.Errorf("environment not found: %s", envIdentifier)
+	return nil, internal.NotFoundf("environment not found: %s", envIdentifier)
 }
 
 // ListEnvironments 列出项目的所有环境
@@ -254,7 +340,130 @@ func (m *Manager) ListEnvironments(projectIdentifier string) ([]internal.Environ
 	return project.Environments, nil
 }
 
+// CloneEnvironment 克隆环境（可跨项目），复制标签和文件配置
+// copySources 为 true 时将源文件导入为新环境的托管源文件，使克隆可以独立修改
+func (m *Manager) CloneEnvironment(srcProjectIdentifier, srcEnvIdentifier, dstProjectIdentifier, newName string, copySources bool) (*internal.Environment, error) {
+	if newName == "" {
+		return nil, fmt.Errorf("environment name cannot be empty")
+	}
+
+	srcProject, err := m.GetProject(srcProjectIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	srcEnv, err := m.GetEnvironment(srcProject.ID, srcEnvIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	if dstProjectIdentifier == "" {
+		dstProjectIdentifier = srcProjectIdentifier
+	}
+	dstProject, err := m.GetProject(dstProjectIdentifier)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &internal.Environment{
+		ID:          uuid.New().String(),
+		Name:        newName,
+		Description: srcEnv.Description,
+		Tags:        append([]string{}, srcEnv.Tags...),
+		Files:       make([]internal.FileConfig, 0, len(srcEnv.Files)),
+		DotEnvPath:  srcEnv.DotEnvPath,
+		Protected:   srcEnv.Protected,
+	}
+	if len(srcEnv.Variables) > 0 {
+		env.Variables = make(map[string]string, len(srcEnv.Variables))
+		for name, value := range srcEnv.Variables {
+			env.Variables[name] = value
+		}
+	}
+
+	for _, fileConfig := range srcEnv.Files {
+		cloned := fileConfig
+		cloned.ID = uuid.New().String()
+		cloned.BackupPath = ""
+
+		// 托管源文件归属于各自的环境，克隆时总是复制
+		if copySources || fileConfig.Managed {
+			sourcePath, _, err := m.storage.ResolvePaths(srcProject, &fileConfig)
+			if err != nil {
+				_ = m.storage.DeleteSources(dstProject.ID, env.ID)
+				return nil, err
+			}
+
+			newPath, err := m.importSource(dstProject.ID, env.ID, sourcePath)
+			if err != nil {
+				_ = m.storage.DeleteSources(dstProject.ID, env.ID)
+				return nil, err
+			}
+			cloned.SourcePath = newPath
+			cloned.Managed = true
+			cloned.Version = 1
+		}
+
+		env.Files = append(env.Files, cloned)
+	}
+
+	if err := m.AddEnvironment(dstProject.ID, env); err != nil {
+		_ = m.storage.DeleteSources(dstProject.ID, env.ID)
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// publishProject 发布项目变更事件
+func publishProject(eventType string, project *internal.Project) {
+	events.Publish(events.Event{
+		Type:        eventType,
+		ProjectID:   project.ID,
+		ProjectName: project.Name,
+	})
+}
+
+// publishEnvironment 发布环境变更事件
+func publishEnvironment(eventType string, project *internal.Project, env *internal.Environment) {
+	events.Publish(events.Event{
+		Type:            eventType,
+		ProjectID:       project.ID,
+		ProjectName:     project.Name,
+		EnvironmentID:   env.ID,
+		EnvironmentName: env.Name,
+	})
+}
+
 // GetStorage 获取存储实例（用于访问应用状态）
 func (m *Manager) GetStorage() *storage.Storage {
 	return m.storage
 }
+
+// importSource 将源文件导入为托管源文件，通配符源路径导入全部匹配文件并保留剩余模式
+func (m *Manager) importSource(projectID, envID, sourcePath string) (string, error) {
+	if !storage.IsGlobPattern(sourcePath) {
+		return m.storage.ImportSource(projectID, envID, sourcePath)
+	}
+
+	base, files, err := storage.GlobFiles(sourcePath)
+	if err != nil {
+		return "", err
+	}
+	dir, err := m.storage.ImportSourceTree(projectID, envID, base, files)
+	if err != nil {
+		return "", err
+	}
+	_, rest := storage.SplitGlobPattern(sourcePath)
+	return filepath.Join(dir, filepath.FromSlash(rest)), nil
+}
+
+var variableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidateVariableName 验证环境变量名称
+func ValidateVariableName(name string) error {
+	if !variableNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid variable name '%s'", name)
+	}
+	return nil
+}
@@ -1,6 +1,11 @@
 package project
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -267,3 +272,339 @@ func TestAddEnvironment(t *testing.T) {
 		t.Error("Expected error when adding environment with duplicate name")
 	}
 }
+
+func TestUpdateEnvironmentVariables(t *testing.T) {
+	manager, _ := setupTest(t)
+
+	project, err := manager.CreateProject("vars-test", "Variables test project")
+	if err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	if err := manager.AddEnvironment(project.ID, &internal.Environment{Name: "dev", Files: []internal.FileConfig{}}); err != nil {
+		t.Fatalf("AddEnvironment() error = %v", err)
+	}
+
+	env, err := manager.UpdateEnvironment(project.ID, "dev", map[string]interface{}{
+		"variables":   map[string]string{"API_URL": "http://localhost:8080", "DEBUG": "1"},
+		"dotenv_path": ".env",
+	})
+	if err != nil {
+		t.Fatalf("UpdateEnvironment() error = %v", err)
+	}
+	if len(env.Variables) != 2 || env.Variables["DEBUG"] != "1" || env.DotEnvPath != ".env" {
+		t.Errorf("Unexpected environment after update: %+v", env)
+	}
+
+	// 变量整体替换，空映射清除全部变量
+	env, err = manager.UpdateEnvironment(project.ID, "dev", map[string]interface{}{
+		"variables": map[string]string{},
+	})
+	if err != nil {
+		t.Fatalf("UpdateEnvironment() error = %v", err)
+	}
+	if env.Variables != nil {
+		t.Errorf("Expected variables to be cleared, got %v", env.Variables)
+	}
+
+	// 非法变量名
+	_, err = manager.UpdateEnvironment(project.ID, "dev", map[string]interface{}{
+		"variables": map[string]string{"1BAD": "x"},
+	})
+	if err == nil {
+		t.Error("Expected error for invalid variable name")
+	}
+}
+
+func TestUpdateEnvironmentProtected(t *testing.T) {
+	manager, _ := setupTest(t)
+
+	project, err := manager.CreateProject("protect-test", "Protected environment test project")
+	if err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	if err := manager.AddEnvironment(project.ID, &internal.Environment{Name: "prod", Files: []internal.FileConfig{}}); err != nil {
+		t.Fatalf("AddEnvironment() error = %v", err)
+	}
+
+	env, err := manager.UpdateEnvironment(project.ID, "prod", map[string]interface{}{"protected": true})
+	if err != nil {
+		t.Fatalf("UpdateEnvironment() error = %v", err)
+	}
+	if !env.Protected {
+		t.Error("Expected environment to be protected after update")
+	}
+
+	// 克隆的环境保留保护标记
+	clone, err := manager.CloneEnvironment(project.ID, "prod", "", "prod-copy", false)
+	if err != nil {
+		t.Fatalf("CloneEnvironment() error = %v", err)
+	}
+	if !clone.Protected {
+		t.Error("Expected cloned environment to keep the protected flag")
+	}
+
+	env, err = manager.UpdateEnvironment(project.ID, "prod", map[string]interface{}{"protected": false})
+	if err != nil {
+		t.Fatalf("UpdateEnvironment() error = %v", err)
+	}
+	if env.Protected {
+		t.Error("Expected environment to be unprotected after update")
+	}
+}
+
+func TestCloneEnvironment(t *testing.T) {
+	manager, tempDir := setupTest(t)
+
+	// 创建源项目和环境
+	srcProject, err := manager.CreateProject("clone-src", "Clone source project")
+	if err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+	dstProject, err := manager.CreateProject("clone-dst", "Clone target project")
+	if err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+
+	sourceFile := filepath.Join(tempDir, "dev.json")
+	if err := os.WriteFile(sourceFile, []byte(`{"env": "dev"}`), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	env := &internal.Environment{
+		Name: "dev",
+		Tags: []string{"development"},
+		Files: []internal.FileConfig{
+			{ID: "file-1", SourcePath: sourceFile, TargetPath: filepath.Join(tempDir, "app.json")},
+		},
+	}
+	if err := manager.AddEnvironment(srcProject.ID, env); err != nil {
+		t.Fatalf("Failed to add environment: %v", err)
+	}
+
+	// 项目内克隆，共用源文件
+	cloned, err := manager.CloneEnvironment(srcProject.ID, "dev", "", "dev-copy", false)
+	if err != nil {
+		t.Fatalf("CloneEnvironment() error = %v", err)
+	}
+	if cloned.ID == env.ID {
+		t.Error("Cloned environment should have a new ID")
+	}
+	if len(cloned.Tags) != 1 || cloned.Tags[0] != "development" {
+		t.Errorf("Expected tags to be copied, got %v", cloned.Tags)
+	}
+	if len(cloned.Files) != 1 {
+		t.Fatalf("Expected 1 file config, got %d", len(cloned.Files))
+	}
+	if cloned.Files[0].ID == "file-1" {
+		t.Error("Cloned file config should have a new ID")
+	}
+	if cloned.Files[0].SourcePath != sourceFile {
+		t.Errorf("Expected shared source path %s, got %s", sourceFile, cloned.Files[0].SourcePath)
+	}
+
+	// 跨项目克隆，复制源文件
+	cloned, err = manager.CloneEnvironment(srcProject.ID, "dev", dstProject.ID, "dev", true)
+	if err != nil {
+		t.Fatalf("CloneEnvironment() across projects error = %v", err)
+	}
+	if !cloned.Files[0].Managed {
+		t.Error("Expected copied source to be managed")
+	}
+	copiedSource := manager.GetStorage().ResolveSourcePath(&cloned.Files[0])
+	if copiedSource == sourceFile {
+		t.Error("Expected source file to be copied to a new location")
+	}
+	if data, err := os.ReadFile(copiedSource); err != nil || string(data) != `{"env": "dev"}` {
+		t.Errorf("Copied source file content mismatch: %q, %v", string(data), err)
+	}
+
+	if _, err := manager.GetEnvironment(dstProject.ID, "dev"); err != nil {
+		t.Errorf("Cloned environment not found in target project: %v", err)
+	}
+
+	// 测试名称冲突
+	_, err = manager.CloneEnvironment(srcProject.ID, "dev", "", "dev-copy", false)
+	if err == nil {
+		t.Error("Expected error when cloning to an existing environment name")
+	}
+}
+
+func TestExportImportProject(t *testing.T) {
+	manager, tempDir := setupTest(t)
+
+	project, err := manager.CreateProject("bundle-test", "Bundle test project")
+	if err != nil {
+		t.Fatalf("Failed to create test project: %v", err)
+	}
+
+	sourceFile := filepath.Join(tempDir, "dev.json")
+	if err := os.WriteFile(sourceFile, []byte(`{"env": "dev"}`), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	env := &internal.Environment{
+		Name: "dev",
+		Tags: []string{"development"},
+		Files: []internal.FileConfig{
+			{ID: "bundle-file", SourcePath: sourceFile, TargetPath: "/home/alice/app/config.json"},
+		},
+	}
+	if err := manager.AddEnvironment(project.ID, env); err != nil {
+		t.Fatalf("Failed to add environment: %v", err)
+	}
+
+	// 导出项目
+	var buf bytes.Buffer
+	if err := manager.ExportProject("bundle-test", &buf); err != nil {
+		t.Fatalf("ExportProject() error = %v", err)
+	}
+	bundle := buf.Bytes()
+
+	// 同名导入应失败
+	if _, err := manager.ImportProject(bytes.NewReader(bundle), ImportOptions{}); err == nil {
+		t.Error("Expected error when importing project with existing name")
+	}
+
+	// 重命名导入，ID冲突时重新生成
+	imported, err := manager.ImportProject(bytes.NewReader(bundle), ImportOptions{
+		Name:        "bundle-copy",
+		RemapTarget: map[string]string{"/home/alice": "/home/bob"},
+	})
+	if err != nil {
+		t.Fatalf("ImportProject() error = %v", err)
+	}
+
+	if imported.ID == project.ID {
+		t.Error("Expected project ID to be regenerated on conflict")
+	}
+	if len(imported.Environments) != 1 || len(imported.Environments[0].Files) != 1 {
+		t.Fatalf("Expected 1 environment with 1 file, got %+v", imported.Environments)
+	}
+
+	importedFile := imported.Environments[0].Files[0]
+	if importedFile.ID == "bundle-file" {
+		t.Error("Expected file config ID to be regenerated on conflict")
+	}
+	if importedFile.TargetPath != "/home/bob/app/config.json" {
+		t.Errorf("Expected remapped target path, got %s", importedFile.TargetPath)
+	}
+	if !importedFile.Managed {
+		t.Error("Expected imported source to be managed")
+	}
+
+	data, err := os.ReadFile(manager.GetStorage().ResolveSourcePath(&importedFile))
+	if err != nil || string(data) != `{"env": "dev"}` {
+		t.Errorf("Imported source content mismatch: %q, %v", string(data), err)
+	}
+
+	// 测试无效打包文件
+	if _, err := manager.ImportProject(bytes.NewReader([]byte("not a bundle")), ImportOptions{}); err == nil {
+		t.Error("Expected error when importing invalid bundle")
+	}
+}
+
+func TestExtractBundleLimits(t *testing.T) {
+	// 构造打包文件，头部声明的大小与实际内容一致
+	bundle := func(files map[string]int) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for name, size := range files {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(size), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write(bytes.Repeat([]byte("a"), size)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_ = tw.Close()
+		_ = gz.Close()
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		files    map[string]int
+		tooLarge bool
+	}{
+		{"within limits", map[string]int{"a": 100, "b": 100}, false},
+		{"entry too large", map[string]int{"a": 101}, true},
+		{"total too large", map[string]int{"a": 100, "b": 100, "c": 100}, true},
+	}
+
+	for _, tt := range tests {
+		err := extractBundle(bytes.NewReader(bundle(tt.files)), t.TempDir(), 100, 250)
+		if tt.tooLarge != errors.Is(err, ErrBundleTooLarge) {
+			t.Errorf("%s: extractBundle() error = %v", tt.name, err)
+		}
+		if !tt.tooLarge && err != nil {
+			t.Errorf("%s: extractBundle() error = %v", tt.name, err)
+		}
+	}
+}
+
+func TestImportProjectTraversalIDs(t *testing.T) {
+	manager, tempDir := setupTest(t)
+
+	// 数据目录之外的目录不能被导入或清理操作触及
+	victim := filepath.Join(tempDir, "victim")
+	if err := os.MkdirAll(victim, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(victim, "keep.txt"), []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bundle := func(sourcePath string) []byte {
+		data, err := json.Marshal(bundleProject{
+			FormatVersion: bundleFormatVersion,
+			Project: &internal.Project{
+				ID:   "../../victim",
+				Name: "traversal",
+				Environments: []internal.Environment{{
+					ID:    "../../victim",
+					Name:  "dev",
+					Files: []internal.FileConfig{{ID: "../victim", SourcePath: sourcePath, TargetPath: "/tmp/app.json"}},
+				}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		for name, content := range map[string][]byte{bundleProjectFile: data, "sources/app.json": []byte("{}")} {
+			if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write(content); err != nil {
+				t.Fatal(err)
+			}
+		}
+		_ = tw.Close()
+		_ = gz.Close()
+		return buf.Bytes()
+	}
+
+	// 源路径非法时导入失败，清理不会删除数据目录之外的目录
+	if _, err := manager.ImportProject(bytes.NewReader(bundle("../bad")), ImportOptions{}); err == nil {
+		t.Error("Expected error for bundle with illegal source path")
+	}
+	if _, err := os.Stat(filepath.Join(victim, "keep.txt")); err != nil {
+		t.Fatalf("Expected directory outside the data directory to be kept: %v", err)
+	}
+
+	// 非UUID的ID重新生成
+	imported, err := manager.ImportProject(bytes.NewReader(bundle("sources/app.json")), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportProject() error = %v", err)
+	}
+	if !hasValidIDs(imported) || imported.ID == "../../victim" {
+		t.Errorf("Expected IDs to be regenerated, got %+v", imported)
+	}
+	if _, err := os.Stat(filepath.Join(victim, "keep.txt")); err != nil {
+		t.Errorf("Expected directory outside the data directory to be kept: %v", err)
+	}
+}
@@ -0,0 +1,201 @@
+package storage
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+// IsGlobPattern 判断路径是否为通配符模式（支持 *、?、[...] 和 **）
+// 方括号只有构成有效的字符类时才视为通配符；只含字符类的路径本身存在时按普通路径处理，例如 config[dev].json
+func IsGlobPattern(p string) bool {
+	if strings.ContainsAny(p, "*?") {
+		return true
+	}
+	if !hasCharClass(p) {
+		return false
+	}
+	_, err := os.Lstat(p)
+	return err != nil
+}
+
+// hasGlobMeta 判断路径片段是否包含通配符，不检查文件系统
+func hasGlobMeta(p string) bool {
+	return strings.ContainsAny(p, "*?") || hasCharClass(p)
+}
+
+// hasCharClass 判断路径是否包含闭合且语法有效的 [...] 字符类
+func hasCharClass(p string) bool {
+	open := strings.Index(p, "[")
+	if open == -1 || !strings.Contains(p[open+1:], "]") {
+		return false
+	}
+	_, err := filepath.Match(p, "")
+	return err == nil
+}
+
+// SplitGlobPattern 将通配符模式拆分为不含通配符的基础目录和剩余模式（以 / 分隔）
+// 例如 conf/dev/**/*.properties -> conf/dev, **/*.properties
+func SplitGlobPattern(pattern string) (string, string) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	i := 0
+	for i < len(segments) && !hasGlobMeta(segments[i]) {
+		i++
+	}
+
+	base := strings.Join(segments[:i], "/")
+	if base == "" && strings.HasPrefix(filepath.ToSlash(pattern), "/") {
+		base = "/"
+	}
+	if base == "" {
+		base = "."
+	}
+
+	return filepath.FromSlash(base), strings.Join(segments[i:], "/")
+}
+
+// MatchGlob 判断相对路径（以 / 分隔）是否匹配模式，** 匹配零个或多个目录
+func MatchGlob(pattern, relPath string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(relPath, "/"))
+}
+
+// matchSegments 逐段匹配路径
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		if matchSegments(pattern[1:], segments) {
+			return true
+		}
+		return len(segments) > 0 && matchSegments(pattern, segments[1:])
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	matched, err := path.Match(pattern[0], segments[0])
+	if err != nil || !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// GlobFiles 展开通配符模式，返回基础目录和匹配文件相对基础目录的路径（以 / 分隔，按字典序）
+func GlobFiles(pattern string) (string, []string, error) {
+	base, rest := SplitGlobPattern(pattern)
+	if _, err := path.Match(strings.ReplaceAll(rest, "**", "*"), ""); err != nil {
+		return "", nil, fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+	}
+
+	info, err := os.Stat(base)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return base, nil, nil
+		}
+		return "", nil, err
+	}
+	if !info.IsDir() {
+		return "", nil, fmt.Errorf("glob base %s is not a directory", base)
+	}
+
+	var files []string
+	err = filepath.WalkDir(base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if MatchGlob(rest, rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to expand glob pattern '%s': %w", pattern, err)
+	}
+
+	return base, files, nil
+}
+
+// ExpandFileConfig 将文件配置展开为具体的文件映射
+// 通配符源路径按匹配结果展开，目标路径视为目标目录并保留相对基础目录的子目录结构
+func (s *Storage) ExpandFileConfig(project *internal.Project, fileConfig *internal.FileConfig) ([]internal.FileMapping, error) {
+	sourcePath, targetPath, err := s.ResolvePaths(project, fileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	if !IsGlobPattern(sourcePath) {
+		return []internal.FileMapping{{SourcePath: sourcePath, TargetPath: targetPath}}, nil
+	}
+
+	base, files, err := GlobFiles(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 && !fileConfig.AllowEmpty {
+		return nil, fmt.Errorf("glob pattern '%s' matched no files", fileConfig.SourcePath)
+	}
+
+	mappings := make([]internal.FileMapping, 0, len(files))
+	for _, rel := range files {
+		mappings = append(mappings, internal.FileMapping{
+			SourcePath: filepath.Join(base, filepath.FromSlash(rel)),
+			TargetPath: filepath.Join(targetPath, filepath.FromSlash(rel)),
+		})
+	}
+
+	return mappings, nil
+}
+
+// ImportSourceTree 将一组文件（相对 baseDir）导入到环境的托管源文件目录下的新子目录，返回相对数据目录的目录路径
+func (s *Storage) ImportSourceTree(projectID, envID, baseDir string, relFiles []string) (string, error) {
+	sourcesDir := s.SourcesDir(projectID, envID)
+
+	// 同名目录已存在时追加序号，避免覆盖
+	name := filepath.Base(baseDir)
+	if name == "." || name == string(filepath.Separator) {
+		name = "files"
+	}
+	dstDir := filepath.Join(sourcesDir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(dstDir); os.IsNotExist(err) {
+			break
+		}
+		dstDir = filepath.Join(sourcesDir, fmt.Sprintf("%s_%d", name, i))
+	}
+
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create sources directory: %w", err)
+	}
+
+	for _, rel := range relFiles {
+		src := filepath.Join(baseDir, filepath.FromSlash(rel))
+		dst := filepath.Join(dstDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return "", err
+		}
+		if err := copyFile(src, dst); err != nil {
+			_ = os.RemoveAll(dstDir)
+			return "", fmt.Errorf("failed to import source file %s: %w", src, err)
+		}
+	}
+
+	return filepath.Rel(s.dataDir, dstDir)
+}
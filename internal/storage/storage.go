@@ -3,6 +3,7 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 
@@ -51,7 +52,7 @@ func (s *Storage) LoadProject(projectID string) (*internal.Project, error) {
 	data, err := os.ReadFile(filepath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("project not found: %s", projectID)
+			return nil, internal.NotFoundf("project not found: %s", projectID)
 		}
 		return nil, fmt.Errorf("failed to read project file: %w", err)
 	}
@@ -77,7 +78,7 @@ func (s *Storage) LoadProjectByName(name string) (*internal.Project, error) {
 		}
 	}
 
-	return nil, fmt.Errorf("project not found: %s", name)
+	return nil, internal.NotFoundf("project not found: %s", name)
 }
 
 // ListProjects 列出所有项目
@@ -119,7 +120,7 @@ func (s *Storage) DeleteProject(projectID string) error {
 
 	if err := os.Remove(filepath); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("project not found: %s", projectID)
+			return internal.NotFoundf("project not found: %s", projectID)
 		}
 		return fmt.Errorf("failed to delete project file: %w", err)
 	}
@@ -127,6 +128,120 @@ func (s *Storage) DeleteProject(projectID string) error {
 	return nil
 }
 
+// SourcesDir 获取环境的托管源文件目录 (<data_dir>/sources/<project-id>/<env-id>)
+func (s *Storage) SourcesDir(projectID, envID string) string {
+	return filepath.Join(s.dataDir, "sources", projectID, envID)
+}
+
+// ImportSource 将源文件导入到环境的托管源文件目录，返回相对数据目录的路径
+func (s *Storage) ImportSource(projectID, envID, srcPath string) (string, error) {
+	dstPath, err := s.newSourcePath(projectID, envID, filepath.Base(srcPath))
+	if err != nil {
+		return "", err
+	}
+
+	if err := copyFile(srcPath, dstPath); err != nil {
+		return "", fmt.Errorf("failed to import source file %s: %w", srcPath, err)
+	}
+
+	return filepath.Rel(s.dataDir, dstPath)
+}
+
+// WriteSource 将内容写入托管源文件目录中名为 name 的新文件，返回相对数据目录的路径
+func (s *Storage) WriteSource(projectID, envID, name string, content []byte) (string, error) {
+	dstPath, err := s.newSourcePath(projectID, envID, name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(dstPath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write source file: %w", err)
+	}
+
+	return filepath.Rel(s.dataDir, dstPath)
+}
+
+// newSourcePath 在托管源文件目录中为 name 选择不冲突的路径
+func (s *Storage) newSourcePath(projectID, envID, name string) (string, error) {
+	dir := s.SourcesDir(projectID, envID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create sources directory: %w", err)
+	}
+
+	// 同名文件已存在时追加序号，避免覆盖
+	ext := filepath.Ext(name)
+	dstPath := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(dstPath); os.IsNotExist(err) {
+			return dstPath, nil
+		}
+		dstPath = filepath.Join(dir, fmt.Sprintf("%s_%d%s", name[:len(name)-len(ext)], i, ext))
+	}
+}
+
+// ResolveSourcePath 获取文件配置源文件的实际路径
+func (s *Storage) ResolveSourcePath(fileConfig *internal.FileConfig) string {
+	if fileConfig.Managed && !filepath.IsAbs(fileConfig.SourcePath) {
+		return filepath.Join(s.dataDir, fileConfig.SourcePath)
+	}
+	return fileConfig.SourcePath
+}
+
+// ResolvePaths 解析文件配置的实际源路径和目标路径，展开 ~、$HOME、${PROJECT_ROOT} 和自定义路径变量
+func (s *Storage) ResolvePaths(project *internal.Project, fileConfig *internal.FileConfig) (string, string, error) {
+	vars := config.PathVariables(project)
+
+	sourcePath := s.ResolveSourcePath(fileConfig)
+	if !fileConfig.Managed {
+		expanded, err := config.ExpandPath(sourcePath, vars)
+		if err != nil {
+			return "", "", err
+		}
+		sourcePath = expanded
+	}
+
+	targetPath, err := config.ExpandPath(fileConfig.TargetPath, vars)
+	if err != nil {
+		return "", "", err
+	}
+
+	return sourcePath, targetPath, nil
+}
+
+// SaveSourceVersion 将托管源文件的当前内容保存为历史版本
+func (s *Storage) SaveSourceVersion(projectID, envID string, fileConfig *internal.FileConfig) error {
+	historyDir := filepath.Join(s.SourcesDir(projectID, envID), ".history", fileConfig.ID)
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		return fmt.Errorf("failed to create source history directory: %w", err)
+	}
+
+	version := fileConfig.Version
+	if version == 0 {
+		version = 1
+	}
+	historyFile := fmt.Sprintf("v%d%s", version, filepath.Ext(fileConfig.SourcePath))
+
+	if err := copyFile(s.ResolveSourcePath(fileConfig), filepath.Join(historyDir, historyFile)); err != nil {
+		return fmt.Errorf("failed to save source version: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSources 删除托管源文件（envID为空时删除整个项目的源文件）
+func (s *Storage) DeleteSources(projectID, envID string) error {
+	dir := filepath.Join(s.dataDir, "sources", projectID)
+	if envID != "" {
+		dir = s.SourcesDir(projectID, envID)
+	}
+
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to delete sources: %w", err)
+	}
+
+	return nil
+}
+
 // SaveAppState 保存应用状态
 func (s *Storage) SaveAppState(state *internal.AppState) error {
 	filepath := filepath.Join(s.dataDir, "state.json")
@@ -194,7 +309,7 @@ func (s *Storage) LoadBackupInfo(backupID string) (*internal.BackupInfo, error)
 	data, err := os.ReadFile(filepath)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("backup not found: %s", backupID)
+			return nil, internal.NotFoundf("backup not found: %s", backupID)
 		}
 		return nil, fmt.Errorf("failed to read backup info file: %w", err)
 	}
@@ -260,7 +375,7 @@ func (s *Storage) DeleteBackup(backupID string) error {
 	// 删除备份信息文件
 	if err := os.Remove(infoPath); err != nil {
 		if os.IsNotExist(err) {
-			return fmt.Errorf("backup not found: %s", backupID)
+			return internal.NotFoundf("backup not found: %s", backupID)
 		}
 		return fmt.Errorf("failed to delete backup info file: %w", err)
 	}
@@ -297,3 +412,26 @@ func (s *Storage) CleanupOldBackups(keepCount int) error {
 
 	return nil
 }
+
+// copyFile 复制文件并保留权限
+func copyFile(src, dst string) error {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = sourceFile.Close() }()
+
+	sourceInfo, err := sourceFile.Stat()
+	if err != nil {
+		return err
+	}
+
+	destFile, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, sourceInfo.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() { _ = destFile.Close() }()
+
+	_, err = io.Copy(destFile, sourceFile)
+	return err
+}
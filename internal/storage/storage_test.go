@@ -312,3 +312,167 @@ func TestListBackups(t *testing.T) {
 		t.Errorf("Expected 3 backups, got %d", len(backups))
 	}
 }
+
+func TestImportSource(t *testing.T) {
+	storage := setupStorageTest(t)
+
+	sourceFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(sourceFile, []byte(`{"version": 1}`), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	// 导入源文件
+	relPath, err := storage.ImportSource("project-id", "env-id", sourceFile)
+	if err != nil {
+		t.Fatalf("ImportSource() error = %v", err)
+	}
+	if filepath.IsAbs(relPath) {
+		t.Errorf("Expected relative path, got %s", relPath)
+	}
+
+	fileConfig := &internal.FileConfig{
+		ID:         "file-id",
+		SourcePath: relPath,
+		Managed:    true,
+		Version:    1,
+	}
+
+	resolved := storage.ResolveSourcePath(fileConfig)
+	if data, err := os.ReadFile(resolved); err != nil || string(data) != `{"version": 1}` {
+		t.Errorf("Imported source content mismatch: %q, %v", string(data), err)
+	}
+
+	// 同名文件不应被覆盖
+	secondPath, err := storage.ImportSource("project-id", "env-id", sourceFile)
+	if err != nil {
+		t.Fatalf("ImportSource() second import error = %v", err)
+	}
+	if secondPath == relPath {
+		t.Error("Expected a different path for second import of the same file name")
+	}
+
+	// 保存历史版本
+	if err := storage.SaveSourceVersion("project-id", "env-id", fileConfig); err != nil {
+		t.Fatalf("SaveSourceVersion() error = %v", err)
+	}
+	historyFile := filepath.Join(storage.SourcesDir("project-id", "env-id"), ".history", "file-id", "v1.json")
+	if _, err := os.Stat(historyFile); err != nil {
+		t.Errorf("Expected history file %s: %v", historyFile, err)
+	}
+
+	// 非托管文件路径保持不变
+	unmanaged := &internal.FileConfig{SourcePath: sourceFile}
+	if storage.ResolveSourcePath(unmanaged) != sourceFile {
+		t.Errorf("Expected unmanaged path unchanged, got %s", storage.ResolveSourcePath(unmanaged))
+	}
+
+	// 删除源文件
+	if err := storage.DeleteSources("project-id", ""); err != nil {
+		t.Fatalf("DeleteSources() error = %v", err)
+	}
+	if _, err := os.Stat(resolved); !os.IsNotExist(err) {
+		t.Error("Expected managed sources to be deleted")
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.properties", "app.properties", true},
+		{"*.properties", "sub/app.properties", false},
+		{"**/*.properties", "app.properties", true},
+		{"**/*.properties", "a/b/app.properties", true},
+		{"a/**/x.yaml", "a/x.yaml", true},
+		{"a/**/x.yaml", "a/b/c/x.yaml", true},
+		{"a/**/x.yaml", "b/x.yaml", false},
+		{"**", "any/file", true},
+	}
+
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIsGlobPattern(t *testing.T) {
+	tempDir := t.TempDir()
+	literal := filepath.Join(tempDir, "config[dev].json")
+	if err := os.WriteFile(literal, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"conf/*.yaml", true},
+		{"conf/app?.yaml", true},
+		{"conf/app[12].yaml", true},
+		{"conf/**/x.yaml", true},
+		{"conf/app.yaml", false},
+		{"conf/config[dev.json", false},
+		{"conf/config]dev.json", false},
+		{"conf/config[].json", false},
+		{literal, false},
+		{filepath.Join(tempDir, "config[prod].json"), true},
+	}
+
+	for _, tt := range tests {
+		if got := IsGlobPattern(tt.path); got != tt.want {
+			t.Errorf("IsGlobPattern(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// 方括号字面路径按单个文件映射
+	storage := setupStorageTest(t)
+	mappings, err := storage.ExpandFileConfig(nil, &internal.FileConfig{SourcePath: literal, TargetPath: filepath.Join(tempDir, "app.json")})
+	if err != nil {
+		t.Fatalf("ExpandFileConfig() error = %v", err)
+	}
+	if len(mappings) != 1 || mappings[0].SourcePath != literal {
+		t.Errorf("Expected literal bracket path to map a single file, got %v", mappings)
+	}
+}
+
+func TestExpandFileConfig(t *testing.T) {
+	storage := setupStorageTest(t)
+
+	srcDir := filepath.Join(t.TempDir(), "conf", "dev")
+	for _, name := range []string{"app.properties", "db.properties", "readme.txt", filepath.Join("sub", "cache.properties")} {
+		path := filepath.Join(srcDir, name)
+		_ = os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	targetDir := filepath.Join(t.TempDir(), "app", "conf")
+
+	fileConfig := &internal.FileConfig{
+		SourcePath: filepath.Join(srcDir, "**", "*.properties"),
+		TargetPath: targetDir,
+	}
+	mappings, err := storage.ExpandFileConfig(nil, fileConfig)
+	if err != nil {
+		t.Fatalf("ExpandFileConfig() error = %v", err)
+	}
+	if len(mappings) != 3 {
+		t.Fatalf("Expected 3 mappings, got %d: %v", len(mappings), mappings)
+	}
+	if mappings[2].TargetPath != filepath.Join(targetDir, "sub", "cache.properties") {
+		t.Errorf("Unexpected target for nested file: %s", mappings[2].TargetPath)
+	}
+
+	// 无匹配文件时报错，允许为空时返回空列表
+	fileConfig.SourcePath = filepath.Join(srcDir, "*.xml")
+	if _, err := storage.ExpandFileConfig(nil, fileConfig); err == nil {
+		t.Error("Expected error for empty glob match")
+	}
+	fileConfig.AllowEmpty = true
+	if mappings, err := storage.ExpandFileConfig(nil, fileConfig); err != nil || len(mappings) != 0 {
+		t.Errorf("Expected empty result with AllowEmpty, got %v, err = %v", mappings, err)
+	}
+}
@@ -0,0 +1,97 @@
+package tui
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 模糊匹配得分
+const (
+	scoreMatch       = 16 // 每个匹配字符
+	scoreConsecutive = 8  // 与上一个匹配字符相邻
+	scoreBoundary    = 10 // 匹配位于单词开头（开头或 / - _ . 空格之后）
+	penaltyGap       = 1  // 两个匹配字符之间每跳过一个字符
+	maxGapPenalty    = 8  // 单个间隔的最大扣分
+)
+
+// fuzzyScore 计算 pattern 在 text 中的模糊匹配得分：pattern 的字符需按顺序出现在 text 中（不区分大小写）
+func fuzzyScore(pattern, text string) (int, bool) {
+	p := []rune(strings.ToLower(pattern))
+	t := []rune(strings.ToLower(text))
+	if len(p) == 0 {
+		return 0, true
+	}
+
+	score := 0
+	last := -1
+	pi := 0
+	for ti := 0; ti < len(t) && pi < len(p); ti++ {
+		if t[ti] != p[pi] {
+			continue
+		}
+
+		score += scoreMatch
+		if last >= 0 {
+			if ti == last+1 {
+				score += scoreConsecutive
+			} else {
+				score -= min((ti-last-1)*penaltyGap, maxGapPenalty)
+			}
+		}
+		if ti == 0 || isBoundary(t[ti-1]) {
+			score += scoreBoundary
+		}
+
+		last = ti
+		pi++
+	}
+
+	if pi < len(p) {
+		return 0, false
+	}
+	return score, true
+}
+
+// isBoundary 判断字符是否为单词分隔符
+func isBoundary(r rune) bool {
+	return r == '/' || r == '-' || r == '_' || r == '.' || r == ':' || unicode.IsSpace(r)
+}
+
+// filterItems 按查询条件过滤并排序条目，返回匹配条目的下标
+// 查询按空格拆分为多个关键字，每个关键字都需匹配；得分相同时保持原有顺序
+func filterItems(items []Item, query string) []int {
+	terms := strings.Fields(query)
+
+	type scored struct {
+		index int
+		score int
+	}
+	var matches []scored
+	for i, item := range items {
+		text := item.searchText()
+		total := 0
+		matched := true
+		for _, term := range terms {
+			score, ok := fuzzyScore(term, text)
+			if !ok {
+				matched = false
+				break
+			}
+			total += score
+		}
+		if matched {
+			matches = append(matches, scored{i, total})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].score > matches[b].score
+	})
+
+	indexes := make([]int, len(matches))
+	for i, m := range matches {
+		indexes[i] = m.index
+	}
+	return indexes
+}
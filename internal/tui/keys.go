@@ -0,0 +1,119 @@
+package tui
+
+import "unicode/utf8"
+
+// keyKind 按键类型
+type keyKind int
+
+const (
+	keyRune keyKind = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEscape
+	keyBackspace
+	keyTab
+	keyClearWord   // Ctrl-W 删除查询中的最后一个单词
+	keyPreviewUp   // Ctrl-U 向上滚动预览
+	keyPreviewDown // Ctrl-D 向下滚动预览
+	keyInterrupt   // Ctrl-C
+	keyUnknown
+)
+
+// keyEvent 一次按键
+type keyEvent struct {
+	kind keyKind
+	r    rune
+}
+
+// escapeSequences 常见终端的转义序列
+var escapeSequences = map[string]keyKind{
+	"\x1b[A":  keyUp,
+	"\x1b[B":  keyDown,
+	"\x1bOA":  keyUp,
+	"\x1bOB":  keyDown,
+	"\x1b[5~": keyPageUp,
+	"\x1b[6~": keyPageDown,
+	"\x1b[H":  keyHome,
+	"\x1b[F":  keyEnd,
+	"\x1bOH":  keyHome,
+	"\x1bOF":  keyEnd,
+	"\x1b[1~": keyHome,
+	"\x1b[4~": keyEnd,
+}
+
+// parseKeys 将终端原始输入解析为按键序列
+func parseKeys(data []byte) []keyEvent {
+	var events []keyEvent
+	for len(data) > 0 {
+		b := data[0]
+		switch {
+		case b == 0x1b:
+			// 单独的 ESC 为退出键，否则解析转义序列
+			if len(data) == 1 {
+				events = append(events, keyEvent{kind: keyEscape})
+				return events
+			}
+			n := escapeLength(data)
+			kind, ok := escapeSequences[string(data[:n])]
+			if !ok {
+				kind = keyUnknown
+			}
+			events = append(events, keyEvent{kind: kind})
+			data = data[n:]
+			continue
+		case b == '\r' || b == '\n':
+			events = append(events, keyEvent{kind: keyEnter})
+		case b == 0x7f || b == 0x08:
+			events = append(events, keyEvent{kind: keyBackspace})
+		case b == '\t':
+			events = append(events, keyEvent{kind: keyTab})
+		case b == 0x03:
+			events = append(events, keyEvent{kind: keyInterrupt})
+		case b == 0x10: // Ctrl-P
+			events = append(events, keyEvent{kind: keyUp})
+		case b == 0x0e: // Ctrl-N
+			events = append(events, keyEvent{kind: keyDown})
+		case b == 0x17:
+			events = append(events, keyEvent{kind: keyClearWord})
+		case b == 0x15:
+			events = append(events, keyEvent{kind: keyPreviewUp})
+		case b == 0x04:
+			events = append(events, keyEvent{kind: keyPreviewDown})
+		case b < 0x20:
+			events = append(events, keyEvent{kind: keyUnknown})
+		default:
+			r, size := utf8.DecodeRune(data)
+			events = append(events, keyEvent{kind: keyRune, r: r})
+			data = data[size:]
+			continue
+		}
+		data = data[1:]
+	}
+	return events
+}
+
+// escapeLength 返回以 ESC 开头的转义序列长度
+func escapeLength(data []byte) int {
+	if len(data) < 2 {
+		return len(data)
+	}
+	switch data[1] {
+	case '[':
+		// CSI：参数字节之后以 0x40-0x7e 结尾
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				return i + 1
+			}
+		}
+		return len(data)
+	case 'O':
+		return min(3, len(data))
+	}
+	// Alt+按键
+	return 2
+}
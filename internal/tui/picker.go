@@ -0,0 +1,397 @@
+package tui
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/zoyopei/envswitch/internal"
+
+	"golang.org/x/term"
+)
+
+// ErrNotTerminal 标准输入或输出不是终端
+var ErrNotTerminal = errors.New("interactive mode requires a terminal")
+
+// ANSI 控制序列
+const (
+	ansiEnterScreen = "\x1b[?1049h\x1b[?25l"
+	ansiLeaveScreen = "\x1b[?25h\x1b[?1049l"
+	ansiHome        = "\x1b[H"
+	ansiClearLine   = "\x1b[K"
+	ansiClearBelow  = "\x1b[J"
+	ansiReset       = "\x1b[0m"
+	ansiBold        = "\x1b[1m"
+	ansiDim         = "\x1b[2m"
+	ansiReverse     = "\x1b[7m"
+	ansiRed         = "\x1b[31m"
+	ansiGreen       = "\x1b[32m"
+	ansiYellow      = "\x1b[33m"
+	ansiCyan        = "\x1b[36m"
+)
+
+// Item 选择器中的一个环境
+type Item struct {
+	Project     *internal.Project
+	Environment *internal.Environment
+	Current     bool // 是否为当前激活的环境
+	Protected   bool // 是否为受保护的环境
+}
+
+// Title 条目标题：项目/环境
+func (i Item) Title() string {
+	return i.Project.Name + "/" + i.Environment.Name
+}
+
+// searchText 模糊匹配的文本：标题和标签
+func (i Item) searchText() string {
+	if len(i.Environment.Tags) == 0 {
+		return i.Title()
+	}
+	return i.Title() + " " + strings.Join(i.Environment.Tags, " ")
+}
+
+// action 按键处理结果
+type action int
+
+const (
+	actionNone action = iota
+	actionSelect
+	actionCancel
+)
+
+// Picker 全屏环境选择器：上方输入模糊查询，左侧为环境列表，右侧为所选环境的预览
+type Picker struct {
+	items   []Item
+	preview func(Item) []string
+
+	query         []rune
+	matches       []int // 匹配条目在 items 中的下标，按得分排序
+	cursor        int   // 当前选中的匹配位置
+	offset        int   // 列表滚动位置
+	listHeight    int   // 上次渲染时列表的可见行数
+	showPreview   bool
+	previewScroll int
+	previewCache  map[int][]string
+}
+
+// NewPicker 创建选择器，preview 返回所选环境的预览内容（以 +、-、@@ 开头的行按 diff 着色）
+func NewPicker(items []Item, preview func(Item) []string) *Picker {
+	p := &Picker{
+		items:        items,
+		preview:      preview,
+		listHeight:   10,
+		showPreview:  preview != nil,
+		previewCache: make(map[int][]string),
+	}
+	p.refilter()
+
+	// 默认选中当前激活的环境
+	for pos, index := range p.matches {
+		if items[index].Current {
+			p.cursor = pos
+			break
+		}
+	}
+	return p
+}
+
+// Run 在终端中运行选择器，返回选中的条目；用户取消时返回 nil
+func (p *Picker) Run(in, out *os.File) (*Item, error) {
+	inFd, outFd := int(in.Fd()), int(out.Fd())
+	if !term.IsTerminal(inFd) || !term.IsTerminal(outFd) {
+		return nil, ErrNotTerminal
+	}
+
+	state, err := term.MakeRaw(inFd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable raw mode: %w", err)
+	}
+	defer func() { _ = term.Restore(inFd, state) }()
+
+	_, _ = out.WriteString(ansiEnterScreen)
+	defer func() { _, _ = out.WriteString(ansiLeaveScreen) }()
+
+	buf := make([]byte, 256)
+	for {
+		width, height, err := term.GetSize(outFd)
+		if err != nil || width <= 0 || height <= 0 {
+			width, height = 80, 24
+		}
+		_, _ = out.WriteString(p.View(width, height))
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ev := range parseKeys(buf[:n]) {
+			switch p.handle(ev) {
+			case actionSelect:
+				item := p.items[p.matches[p.cursor]]
+				return &item, nil
+			case actionCancel:
+				return nil, nil
+			}
+		}
+	}
+}
+
+// Selected 返回当前选中的条目
+func (p *Picker) Selected() (Item, bool) {
+	if len(p.matches) == 0 {
+		return Item{}, false
+	}
+	return p.items[p.matches[p.cursor]], true
+}
+
+// refilter 查询变化后重新过滤，并回到第一个匹配项
+func (p *Picker) refilter() {
+	p.matches = filterItems(p.items, string(p.query))
+	p.cursor = 0
+	p.offset = 0
+	p.previewScroll = 0
+}
+
+// move 移动选中位置
+func (p *Picker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = max(0, min(p.cursor+delta, len(p.matches)-1))
+	p.previewScroll = 0
+}
+
+// handle 处理一次按键
+func (p *Picker) handle(ev keyEvent) action {
+	switch ev.kind {
+	case keyRune:
+		p.query = append(p.query, ev.r)
+		p.refilter()
+	case keyBackspace:
+		if len(p.query) > 0 {
+			p.query = p.query[:len(p.query)-1]
+			p.refilter()
+		}
+	case keyClearWord:
+		query := strings.TrimRight(string(p.query), " ")
+		if i := strings.LastIndex(query, " "); i >= 0 {
+			query = query[:i+1]
+		} else {
+			query = ""
+		}
+		p.query = []rune(query)
+		p.refilter()
+	case keyUp:
+		p.move(-1)
+	case keyDown:
+		p.move(1)
+	case keyPageUp:
+		p.move(-p.listHeight)
+	case keyPageDown:
+		p.move(p.listHeight)
+	case keyHome:
+		p.move(-len(p.matches))
+	case keyEnd:
+		p.move(len(p.matches))
+	case keyTab:
+		p.showPreview = !p.showPreview && p.preview != nil
+	case keyPreviewUp:
+		p.previewScroll = max(0, p.previewScroll-p.listHeight/2)
+	case keyPreviewDown:
+		p.previewScroll += p.listHeight / 2
+	case keyEnter:
+		if len(p.matches) > 0 {
+			return actionSelect
+		}
+	case keyEscape, keyInterrupt:
+		return actionCancel
+	}
+	return actionNone
+}
+
+// previewLines 返回条目的预览内容（缓存结果，避免每次按键都重新比较文件）
+func (p *Picker) previewLines(index int) []string {
+	if lines, ok := p.previewCache[index]; ok {
+		return lines
+	}
+	lines := p.preview(p.items[index])
+	p.previewCache[index] = lines
+	return lines
+}
+
+// View 渲染一帧画面
+func (p *Picker) View(width, height int) string {
+	var b strings.Builder
+	b.WriteString(ansiHome)
+
+	// 查询输入行
+	counter := fmt.Sprintf("%d/%d", len(p.matches), len(p.items))
+	prompt := "> " + string(p.query)
+	b.WriteString(ansiBold + fit(prompt, width-len(counter)-1) + ansiReset + " " + ansiDim + counter + ansiReset + ansiClearLine + "\r\n")
+
+	// 列表和预览区域，底部保留一行帮助信息
+	p.listHeight = max(1, height-2)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+p.listHeight {
+		p.offset = p.cursor - p.listHeight + 1
+	}
+
+	listWidth := width
+	previewWidth := 0
+	if p.showPreview && width >= 60 {
+		listWidth = max(30, width/2)
+		previewWidth = width - listWidth - 3
+	}
+
+	var preview []string
+	if previewWidth > 0 && len(p.matches) > 0 {
+		preview = p.previewLines(p.matches[p.cursor])
+		p.previewScroll = max(0, min(p.previewScroll, len(preview)-1))
+		preview = preview[p.previewScroll:]
+	}
+
+	titleWidth := 0
+	for _, index := range p.matches {
+		titleWidth = max(titleWidth, displayWidth(p.items[index].Title()))
+	}
+	titleWidth = min(titleWidth, listWidth/2)
+
+	for row := 0; row < p.listHeight; row++ {
+		pos := p.offset + row
+		if pos < len(p.matches) {
+			line := pad(fit(p.formatItem(p.items[p.matches[pos]], titleWidth), listWidth), listWidth)
+			if pos == p.cursor {
+				b.WriteString(ansiReverse + line + ansiReset)
+			} else {
+				b.WriteString(line)
+			}
+		} else if row == 0 && len(p.matches) == 0 {
+			b.WriteString(ansiDim + pad(fit("  no matching environments", listWidth), listWidth) + ansiReset)
+		} else {
+			b.WriteString(strings.Repeat(" ", listWidth))
+		}
+
+		if previewWidth > 0 {
+			b.WriteString(ansiDim + " │ " + ansiReset)
+			if row < len(preview) {
+				b.WriteString(colorize(preview[row], row == 0 && p.previewScroll == 0, previewWidth))
+			}
+		}
+		b.WriteString(ansiClearLine + "\r\n")
+	}
+
+	// 帮助信息
+	help := "↑/↓ move  enter switch  tab preview  ctrl-u/ctrl-d scroll preview  esc quit"
+	b.WriteString(ansiDim + fit(help, width) + ansiReset + ansiClearLine + ansiClearBelow)
+
+	return b.String()
+}
+
+// formatItem 格式化列表中的一行：当前标记、项目/环境、上次切换时间、受保护标记和标签
+func (p *Picker) formatItem(item Item, titleWidth int) string {
+	marker := "  "
+	if item.Current {
+		marker = "* "
+	}
+
+	lastSwitch := "never           "
+	if item.Environment.LastSwitchAt != nil {
+		lastSwitch = item.Environment.LastSwitchAt.Format("2006-01-02 15:04")
+	}
+
+	line := marker + pad(fit(item.Title(), titleWidth), titleWidth) + "  " + lastSwitch
+	if item.Protected {
+		line += "  [protected]"
+	}
+	if len(item.Environment.Tags) > 0 {
+		line += "  #" + strings.Join(item.Environment.Tags, " #")
+	}
+	return line
+}
+
+// colorize 为预览行着色：标题加粗，diff 的增删行和块头分别着色
+func colorize(line string, title bool, width int) string {
+	text := fit(line, width)
+	switch {
+	case title:
+		return ansiBold + text + ansiReset
+	case strings.HasPrefix(line, "@@"):
+		return ansiCyan + text + ansiReset
+	case strings.HasPrefix(line, "+"):
+		return ansiGreen + text + ansiReset
+	case strings.HasPrefix(line, "-"):
+		return ansiRed + text + ansiReset
+	case strings.HasPrefix(line, "!"):
+		return ansiYellow + text + ansiReset
+	}
+	return text
+}
+
+// fit 按显示宽度截断字符串，并将制表符替换为空格
+func fit(s string, width int) string {
+	s = strings.ReplaceAll(s, "\t", "    ")
+	if width <= 0 {
+		return ""
+	}
+	if displayWidth(s) <= width {
+		return s
+	}
+
+	var b strings.Builder
+	used := 0
+	for _, r := range s {
+		w := runeWidth(r)
+		if used+w > width-1 {
+			break
+		}
+		b.WriteRune(r)
+		used += w
+	}
+	b.WriteString("…")
+	return b.String()
+}
+
+// pad 用空格补齐到指定显示宽度
+func pad(s string, width int) string {
+	if w := displayWidth(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// displayWidth 字符串在终端中的显示宽度
+func displayWidth(s string) int {
+	if utf8.RuneCountInString(s) == len(s) {
+		return len(s)
+	}
+	width := 0
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth 字符的显示宽度，中日韩等全角字符占两列
+func runeWidth(r rune) int {
+	switch {
+	case r < 0x1100:
+		return 1
+	case r <= 0x115f, // 韩文字母
+		r >= 0x2e80 && r <= 0xa4cf && r != 0x303f, // 中日韩部首、符号、汉字
+		r >= 0xac00 && r <= 0xd7a3,                // 韩文音节
+		r >= 0xf900 && r <= 0xfaff,                // 兼容汉字
+		r >= 0xfe30 && r <= 0xfe4f,                // 竖排符号
+		r >= 0xff00 && r <= 0xff60,                // 全角字符
+		r >= 0xffe0 && r <= 0xffe6,
+		r >= 0x1f300 && r <= 0x1f64f, // emoji
+		r >= 0x1f900 && r <= 0x1f9ff,
+		r >= 0x20000 && r <= 0x3fffd:
+		return 2
+	}
+	return 1
+}
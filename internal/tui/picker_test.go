@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zoyopei/envswitch/internal"
+)
+
+func testItems() []Item {
+	web := &internal.Project{Name: "web-app"}
+	api := &internal.Project{Name: "api"}
+	return []Item{
+		{Project: web, Environment: &internal.Environment{Name: "dev", Tags: []string{"local"}}},
+		{Project: web, Environment: &internal.Environment{Name: "production", Tags: []string{"remote", "critical"}}, Protected: true},
+		{Project: api, Environment: &internal.Environment{Name: "dev"}, Current: true},
+		{Project: api, Environment: &internal.Environment{Name: "staging"}},
+	}
+}
+
+func titles(items []Item, indexes []int) []string {
+	var result []string
+	for _, index := range indexes {
+		result = append(result, items[index].Title())
+	}
+	return result
+}
+
+func TestFuzzyScore(t *testing.T) {
+	if _, ok := fuzzyScore("wpd", "web-app/production"); !ok {
+		t.Error("Expected 'wpd' to match 'web-app/production'")
+	}
+	if _, ok := fuzzyScore("dpw", "web-app/production"); ok {
+		t.Error("Expected out-of-order pattern not to match")
+	}
+	if _, ok := fuzzyScore("PROD", "web-app/production"); !ok {
+		t.Error("Expected matching to be case-insensitive")
+	}
+
+	// 连续匹配和单词开头匹配得分更高
+	prefix, _ := fuzzyScore("prod", "web-app/production")
+	scattered, _ := fuzzyScore("prod", "api/preview-old-data")
+	if prefix <= scattered {
+		t.Errorf("Expected consecutive match to score higher: %d <= %d", prefix, scattered)
+	}
+}
+
+func TestFilterItems(t *testing.T) {
+	items := testItems()
+
+	if got := filterItems(items, ""); len(got) != len(items) {
+		t.Errorf("Expected empty query to match all items, got %v", titles(items, got))
+	}
+
+	got := titles(items, filterItems(items, "dev"))
+	if len(got) != 2 || got[0] != "web-app/dev" || got[1] != "api/dev" {
+		t.Errorf("Expected both dev environments in original order, got %v", got)
+	}
+
+	// 标签参与匹配，多个关键字都需匹配
+	got = titles(items, filterItems(items, "critical"))
+	if len(got) != 1 || got[0] != "web-app/production" {
+		t.Errorf("Expected tag match, got %v", got)
+	}
+	got = titles(items, filterItems(items, "api st"))
+	if len(got) != 1 || got[0] != "api/staging" {
+		t.Errorf("Expected 'api st' to match api/staging, got %v", got)
+	}
+	if got := filterItems(items, "nothing"); len(got) != 0 {
+		t.Errorf("Expected no matches, got %v", titles(items, got))
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	events := parseKeys([]byte("a\x1b[B\x1b[5~\r\x7f\x03é"))
+	expected := []keyKind{keyRune, keyDown, keyPageUp, keyEnter, keyBackspace, keyInterrupt, keyRune}
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %v", len(expected), len(events), events)
+	}
+	for i, kind := range expected {
+		if events[i].kind != kind {
+			t.Errorf("Event %d: expected kind %d, got %d", i, kind, events[i].kind)
+		}
+	}
+	if events[6].r != 'é' {
+		t.Errorf("Expected UTF-8 rune, got %q", events[6].r)
+	}
+
+	if events := parseKeys([]byte{0x1b}); len(events) != 1 || events[0].kind != keyEscape {
+		t.Errorf("Expected lone ESC to be parsed as escape, got %v", events)
+	}
+}
+
+func TestPickerNavigation(t *testing.T) {
+	items := testItems()
+	p := NewPicker(items, nil)
+
+	// 默认选中当前环境
+	if item, _ := p.Selected(); item.Title() != "api/dev" {
+		t.Errorf("Expected current environment to be preselected, got %s", item.Title())
+	}
+
+	for _, r := range "stag" {
+		p.handle(keyEvent{kind: keyRune, r: r})
+	}
+	if item, ok := p.Selected(); !ok || item.Title() != "api/staging" {
+		t.Errorf("Expected filtered selection api/staging, got %s", item.Title())
+	}
+	if action := p.handle(keyEvent{kind: keyEnter}); action != actionSelect {
+		t.Errorf("Expected enter to select, got %v", action)
+	}
+
+	p.handle(keyEvent{kind: keyClearWord})
+	p.handle(keyEvent{kind: keyDown})
+	p.handle(keyEvent{kind: keyDown})
+	if item, _ := p.Selected(); item.Title() != "api/dev" {
+		t.Errorf("Expected third item after clearing query, got %s", item.Title())
+	}
+	p.handle(keyEvent{kind: keyEnd})
+	p.handle(keyEvent{kind: keyDown})
+	if item, _ := p.Selected(); item.Title() != "api/staging" {
+		t.Errorf("Expected cursor to stop at last item, got %s", item.Title())
+	}
+
+	p.handle(keyEvent{kind: keyRune, r: 'x'})
+	p.handle(keyEvent{kind: keyRune, r: 'y'})
+	if action := p.handle(keyEvent{kind: keyEnter}); action != actionNone {
+		t.Errorf("Expected enter without matches to do nothing, got %v", action)
+	}
+	if action := p.handle(keyEvent{kind: keyEscape}); action != actionCancel {
+		t.Errorf("Expected escape to cancel, got %v", action)
+	}
+}
+
+func TestPickerView(t *testing.T) {
+	items := testItems()
+	previewed := 0
+	p := NewPicker(items, func(item Item) []string {
+		previewed++
+		return []string{item.Title(), "@@ -1,1 +1,1 @@", "-old", "+new"}
+	})
+
+	view := p.View(140, 10)
+	for _, expected := range []string{"4/4", "web-app/production", "[protected]  #remote #critical", "* api/dev", "+new"} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("Expected view to contain %q:\n%s", expected, view)
+		}
+	}
+
+	// 预览结果被缓存
+	p.View(120, 10)
+	if previewed != 1 {
+		t.Errorf("Expected preview to be computed once, got %d", previewed)
+	}
+
+	// Tab 隐藏预览
+	p.handle(keyEvent{kind: keyTab})
+	if view := p.View(120, 10); strings.Contains(view, "+new") {
+		t.Errorf("Expected preview to be hidden:\n%s", view)
+	}
+
+	if got := fit("环境名称很长", 7); displayWidth(got) > 7 || !strings.HasSuffix(got, "…") {
+		t.Errorf("Expected wide characters to be truncated by display width, got %q", got)
+	}
+}
@@ -1,47 +1,62 @@
 package web
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 
+	"github.com/zoyopei/envswitch/api"
 	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/file"
+	"github.com/zoyopei/envswitch/internal/project"
 
 	"github.com/gin-gonic/gin"
 )
 
+// respondError 返回错误响应
+func respondError(c *gin.Context, status int, message string) {
+	c.JSON(status, api.ErrorResponse{Error: message})
+}
+
+// errorStatus 根据错误类别选择状态码：不存在返回 404，名称或路径冲突返回 409，其他返回 fallback
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, internal.ErrAlreadyExists):
+		return http.StatusConflict
+	}
+	return fallback
+}
+
 // 项目相关API
 
 func (s *Server) listProjectsAPI(c *gin.Context) {
 	projects, err := s.projectManager.ListProjects()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"projects": projects,
+	c.JSON(http.StatusOK, api.ProjectList{
+		Projects: visibleProjects(c, projects),
 	})
 }
 
 func (s *Server) createProjectAPI(c *gin.Context) {
-	var request struct {
-		Name        string `json:"name" binding:"required"`
-		Description string `json:"description"`
-	}
-
+	var request api.CreateProjectRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	project, err := s.projectManager.CreateProject(request.Name, request.Description)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -53,9 +68,7 @@ func (s *Server) getProjectAPI(c *gin.Context) {
 
 	project, err := s.projectManager.GetProject(projectID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Project not found",
-		})
+		respondError(c, http.StatusNotFound, "Project not found")
 		return
 	}
 
@@ -65,15 +78,9 @@ func (s *Server) getProjectAPI(c *gin.Context) {
 func (s *Server) updateProjectAPI(c *gin.Context) {
 	projectID := c.Param("id")
 
-	var request struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-	}
-
+	var request api.UpdateProjectRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -81,15 +88,19 @@ func (s *Server) updateProjectAPI(c *gin.Context) {
 	if request.Name != "" {
 		updates["name"] = request.Name
 	}
-	if request.Description != "" {
-		updates["description"] = request.Description
+	if request.Description != nil {
+		updates["description"] = *request.Description
+	}
+	if request.Root != nil {
+		updates["root"] = *request.Root
+	}
+	if request.PathVariables != nil {
+		updates["path_variables"] = request.PathVariables
 	}
 
 	project, err := s.projectManager.UpdateProject(projectID, updates)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -99,50 +110,130 @@ func (s *Server) updateProjectAPI(c *gin.Context) {
 func (s *Server) deleteProjectAPI(c *gin.Context) {
 	projectID := c.Param("id")
 
-	err := s.projectManager.DeleteProject(projectID)
+	project, err := s.projectManager.GetProject(projectID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	// 删除项目会删除其中的全部环境，每个受保护的环境都需要确认
+	var envs []*internal.Environment
+	for i := range project.Environments {
+		envs = append(envs, &project.Environments[i])
+	}
+	if !s.requireConfirmation(c, "delete", envs...) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Project deleted successfully",
+	err = s.projectManager.DeleteProject(project.ID)
+	if err != nil {
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, api.MessageResponse{
+		Message: "Project deleted successfully",
 	})
 }
 
+func (s *Server) exportProjectAPI(c *gin.Context) {
+	projectID := c.Param("id")
+
+	project, err := s.projectManager.GetProject(projectID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := s.projectManager.ExportProject(project.ID, &buf); err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", project.Name+".tar.gz"))
+	c.Data(http.StatusOK, "application/gzip", buf.Bytes())
+}
+
+func (s *Server) importProjectAPI(c *gin.Context) {
+	fileHeader, err := c.FormFile("bundle")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "bundle file is required")
+		return
+	}
+
+	opts := project.ImportOptions{
+		Name:        c.PostForm("name"),
+		RemapTarget: make(map[string]string),
+	}
+	for _, remap := range c.PostFormArray("remap_target") {
+		parts := strings.SplitN(remap, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("invalid remap_target '%s', expected old=new", remap))
+			return
+		}
+		opts.RemapTarget[parts[0]] = parts[1]
+	}
+
+	bundle, err := fileHeader.Open()
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	defer func() { _ = bundle.Close() }()
+
+	imported, err := s.projectManager.ImportProject(bundle, opts)
+	if errors.Is(err, project.ErrBundleTooLarge) {
+		respondError(c, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	}
+	if err != nil {
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusCreated, imported)
+}
+
 // 环境相关API
 
+// findEnvironment 按ID查找环境及其所属项目，未找到时返回 nil
+func (s *Server) findEnvironment(envID string) (*internal.Project, *internal.Environment, error) {
+	projects, err := s.projectManager.ListProjects()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for i := range projects {
+		for j := range projects[i].Environments {
+			if projects[i].Environments[j].ID == envID {
+				return &projects[i], &projects[i].Environments[j], nil
+			}
+		}
+	}
+	return nil, nil, nil
+}
+
 func (s *Server) listEnvironmentsAPI(c *gin.Context) {
 	projectID := c.Param("id")
 
 	environments, err := s.projectManager.ListEnvironments(projectID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusNotFound, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"environments": environments,
+	c.JSON(http.StatusOK, api.EnvironmentList{
+		Environments: environments,
 	})
 }
 
 func (s *Server) createEnvironmentAPI(c *gin.Context) {
 	projectID := c.Param("id")
 
-	var request struct {
-		Name        string   `json:"name" binding:"required"`
-		Description string   `json:"description"`
-		Tags        []string `json:"tags"`
-	}
-
+	var request api.CreateEnvironmentRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -150,14 +241,15 @@ func (s *Server) createEnvironmentAPI(c *gin.Context) {
 		Name:        request.Name,
 		Description: request.Description,
 		Tags:        request.Tags,
+		Variables:   request.Variables,
+		DotEnvPath:  request.DotEnvPath,
+		Protected:   request.Protected,
 		Files:       []internal.FileConfig{},
 	}
 
 	err := s.projectManager.AddEnvironment(projectID, env)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -165,73 +257,36 @@ func (s *Server) createEnvironmentAPI(c *gin.Context) {
 }
 
 func (s *Server) getEnvironmentAPI(c *gin.Context) {
-	envID := c.Param("id")
-
-	// 查找环境（需要遍历所有项目）
-	projects, err := s.projectManager.ListProjects()
+	_, env, err := s.findEnvironment(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	for _, project := range projects {
-		for _, env := range project.Environments {
-			if env.ID == envID {
-				c.JSON(http.StatusOK, env)
-				return
-			}
-		}
+	if env == nil {
+		respondError(c, http.StatusNotFound, "Environment not found")
+		return
 	}
 
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "Environment not found",
-	})
+	c.JSON(http.StatusOK, env)
 }
 
 func (s *Server) updateEnvironmentAPI(c *gin.Context) {
 	envID := c.Param("id")
 
-	var request struct {
-		Name        string   `json:"name"`
-		Description string   `json:"description"`
-		Tags        []string `json:"tags"`
-	}
-
+	var request api.UpdateEnvironmentRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// 找到环境所属的项目
-	projects, err := s.projectManager.ListProjects()
+	project, target, err := s.findEnvironment(envID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	var projectID string
-	for _, project := range projects {
-		for _, env := range project.Environments {
-			if env.ID == envID {
-				projectID = project.ID
-				break
-			}
-		}
-		if projectID != "" {
-			break
-		}
-	}
-
-	if projectID == "" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Environment not found",
-		})
+	if project == nil {
+		respondError(c, http.StatusNotFound, "Environment not found")
 		return
 	}
 
@@ -239,18 +294,30 @@ func (s *Server) updateEnvironmentAPI(c *gin.Context) {
 	if request.Name != "" {
 		updates["name"] = request.Name
 	}
-	if request.Description != "" {
-		updates["description"] = request.Description
+	if request.Description != nil {
+		updates["description"] = *request.Description
 	}
 	if request.Tags != nil {
 		updates["tags"] = request.Tags
 	}
+	if request.Variables != nil {
+		updates["variables"] = request.Variables
+	}
+	if request.DotEnvPath != nil {
+		updates["dotenv_path"] = *request.DotEnvPath
+	}
+	if request.Protected != nil {
+		updates["protected"] = *request.Protected
+	}
+
+	// 取消受保护环境的保护（清除标记或移除受保护标签）需要确认
+	if config.RemovesProtection(target, updates) && !s.requireConfirmation(c, "unprotect", target) {
+		return
+	}
 
-	env, err := s.projectManager.UpdateEnvironment(projectID, envID, updates)
+	env, err := s.projectManager.UpdateEnvironment(project.ID, envID, updates)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
@@ -261,114 +328,304 @@ func (s *Server) deleteEnvironmentAPI(c *gin.Context) {
 	envID := c.Param("id")
 
 	// 找到环境所属的项目
-	projects, err := s.projectManager.ListProjects()
+	project, target, err := s.findEnvironment(envID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if project == nil {
+		respondError(c, http.StatusNotFound, "Environment not found")
 		return
 	}
 
-	var projectID string
-	for _, project := range projects {
-		for _, env := range project.Environments {
-			if env.ID == envID {
-				projectID = project.ID
-				break
-			}
-		}
-		if projectID != "" {
-			break
+	if !s.requireConfirmation(c, "delete", target) {
+		return
+	}
+
+	err = s.projectManager.RemoveEnvironment(project.ID, envID)
+	if err != nil {
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, api.MessageResponse{
+		Message: "Environment deleted successfully",
+	})
+}
+
+func (s *Server) cloneEnvironmentAPI(c *gin.Context) {
+	envID := c.Param("id")
+
+	var request api.CloneEnvironmentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 找到环境所属的项目
+	project, _, err := s.findEnvironment(envID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if project == nil {
+		respondError(c, http.StatusNotFound, "Environment not found")
+		return
+	}
+
+	// 克隆到其他项目时，令牌也必须能访问目标项目
+	if request.ProjectID != "" {
+		if target, err := s.projectManager.GetProject(request.ProjectID); err == nil && !canAccessProject(c, target) {
+			respondError(c, http.StatusForbidden, "Access to target project denied")
+			return
 		}
 	}
 
-	if projectID == "" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Environment not found",
-		})
+	env, err := s.projectManager.CloneEnvironment(project.ID, envID, request.ProjectID, request.Name, request.CopySources)
+	if err != nil {
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
-	err = s.projectManager.RemoveEnvironment(projectID, envID)
+	c.JSON(http.StatusCreated, env)
+}
+
+// environmentPlanAPI 返回环境文件在本机解析后的路径，远程命令行据此输出与本地相同的信息
+func (s *Server) environmentPlanAPI(c *gin.Context) {
+	project, env, err := s.findEnvironment(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if env == nil {
+		respondError(c, http.StatusNotFound, "Environment not found")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Environment deleted successfully",
-	})
+	plan := api.EnvironmentPlan{
+		Files:      []api.PlannedFile{},
+		SourcesDir: s.projectManager.GetStorage().SourcesDir(project.ID, env.ID),
+	}
+	for i := range env.Files {
+		fileConfig := &env.Files[i]
+		planned := api.PlannedFile{FileID: fileConfig.ID}
+
+		planned.SourcePath, planned.TargetPath, err = s.fileManager.ResolvePaths(project, fileConfig)
+		if err != nil {
+			planned.ResolveError = err.Error()
+		}
+		planned.Mappings, err = s.fileManager.ExpandFileConfig(project, fileConfig)
+		if err != nil {
+			planned.ExpandError = err.Error()
+		}
+		plan.Files = append(plan.Files, planned)
+	}
+
+	plan.DotEnvPath, err = s.fileManager.ResolveDotEnvPath(project, env)
+	if err != nil {
+		plan.DotEnvError = err.Error()
+	}
+
+	if c.Query("preview") == "true" {
+		plan.Preview, err = s.fileManager.PreviewEnvironment(project, env)
+		if err != nil {
+			plan.PreviewError = err.Error()
+		}
+	}
+
+	c.JSON(http.StatusOK, plan)
 }
 
 // 文件配置相关API
 
-func (s *Server) addFileConfigAPI(c *gin.Context) {
-	envID := c.Param("id")
+// findFile 按ID查找文件配置及其所属的项目和环境，未找到时返回 nil
+func (s *Server) findFile(fileID string) (*internal.Project, *internal.Environment, *internal.FileConfig, error) {
+	projects, err := s.projectManager.ListProjects()
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-	var request struct {
-		SourcePath  string `json:"source_path" binding:"required"`
-		TargetPath  string `json:"target_path" binding:"required"`
-		Description string `json:"description"`
+	for i := range projects {
+		for j := range projects[i].Environments {
+			env := &projects[i].Environments[j]
+			for k := range env.Files {
+				if env.Files[k].ID == fileID {
+					return &projects[i], env, &env.Files[k], nil
+				}
+			}
+		}
 	}
+	return nil, nil, nil, nil
+}
+
+func (s *Server) addFileConfigAPI(c *gin.Context) {
+	envID := c.Param("id")
 
+	var request api.AddFileRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	// 找到环境所属的项目
-	projects, err := s.projectManager.ListProjects()
+	project, _, err := s.findEnvironment(envID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if project == nil {
+		respondError(c, http.StatusNotFound, "Environment not found")
 		return
 	}
 
-	var projectID string
-	for _, project := range projects {
-		for _, env := range project.Environments {
-			if env.ID == envID {
-				projectID = project.ID
-				break
-			}
-		}
-		if projectID != "" {
-			break
-		}
+	fileConfig, err := s.fileManager.CreateFileConfig(project.ID, envID, &internal.FileConfig{
+		SourcePath:  request.SourcePath,
+		TargetPath:  request.TargetPath,
+		Description: request.Description,
+		AllowEmpty:  request.AllowEmpty,
+	}, request.Import)
+	if err != nil {
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
+		return
 	}
 
-	if projectID == "" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Environment not found",
-		})
+	c.JSON(http.StatusCreated, api.FileResponse{
+		Message:    "File configuration added successfully",
+		FileConfig: *fileConfig,
+	})
+}
+
+func (s *Server) updateFileConfigAPI(c *gin.Context) {
+	fileID := c.Param("id")
+
+	var request api.UpdateFileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// 找到文件配置所属的项目和环境
+	project, target, _, err := s.findFile(fileID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if project == nil {
+		respondError(c, http.StatusNotFound, "File configuration not found")
+		return
+	}
+
+	if !s.requireConfirmation(c, "update-file", target) {
 		return
 	}
 
-	err = s.fileManager.AddFileConfig(projectID, envID, request.SourcePath, request.TargetPath, request.Description)
+	updates := make(map[string]interface{})
+	if request.SourcePath != nil {
+		updates["source_path"] = *request.SourcePath
+	}
+	if request.TargetPath != nil {
+		updates["target_path"] = *request.TargetPath
+	}
+	if request.Description != nil {
+		updates["description"] = *request.Description
+	}
+	if request.Managed != nil {
+		updates["managed"] = *request.Managed
+	}
+	if request.AllowEmpty != nil {
+		updates["allow_empty"] = *request.AllowEmpty
+	}
+
+	fileConfig, err := s.fileManager.UpdateFileConfig(project.ID, target.ID, fileID, updates)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message": "File configuration added successfully",
+	c.JSON(http.StatusOK, api.FileResponse{
+		Message:    "File configuration updated successfully",
+		FileConfig: *fileConfig,
 	})
 }
 
-func (s *Server) updateFileConfigAPI(c *gin.Context) {
-	_ = c.Param("id") // fileID，暂时未使用
+// contentStatus 文件内容接口的状态码：超过大小上限返回 413，其他同 errorStatus
+func contentStatus(err error, fallback int) int {
+	if errors.Is(err, file.ErrContentTooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return errorStatus(err, fallback)
+}
+
+func (s *Server) getFileContentAPI(c *gin.Context) {
+	s.readFileContent(c, s.fileManager.ReadSourceContent)
+}
+
+func (s *Server) getFileTargetAPI(c *gin.Context) {
+	s.readFileContent(c, s.fileManager.ReadTargetContent)
+}
+
+// readFileContent 查找文件配置并返回 read 读取的源文件或目标文件内容
+func (s *Server) readFileContent(c *gin.Context, read func(*internal.Project, *internal.FileConfig) (*file.FileContent, error)) {
+	project, _, fileConfig, err := s.findFile(c.Param("id"))
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if project == nil {
+		respondError(c, http.StatusNotFound, "File configuration not found")
+		return
+	}
+
+	content, err := read(project, fileConfig)
+	if err != nil {
+		respondError(c, contentStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, content)
+}
+
+func (s *Server) updateFileContentAPI(c *gin.Context) {
+	fileID := c.Param("id")
+
+	// JSON 编码后的内容最多约为原文的 6 倍，超出时直接拒绝
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 6*file.MaxContentSize+1024)
+
+	var request api.UpdateFileContentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			respondError(c, http.StatusRequestEntityTooLarge, file.ErrContentTooLarge.Error())
+			return
+		}
+		respondError(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	// 这里需要实现文件配置更新逻辑
-	// 为简化，现在返回未实现
-	c.JSON(http.StatusNotImplemented, gin.H{
-		"error": "File config update not implemented yet",
+	// 找到文件配置所属的项目和环境
+	project, target, _, err := s.findFile(fileID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if project == nil {
+		respondError(c, http.StatusNotFound, "File configuration not found")
+		return
+	}
+
+	if !s.requireConfirmation(c, "update-file", target) {
+		return
+	}
+
+	fileConfig, err := s.fileManager.WriteSourceContent(project.ID, target.ID, fileID, []byte(request.Content))
+	if err != nil {
+		respondError(c, contentStatus(err, http.StatusBadRequest), err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, api.FileResponse{
+		Message:    "File content saved successfully",
+		FileConfig: *fileConfig,
 	})
 }
 
@@ -376,102 +633,88 @@ func (s *Server) deleteFileConfigAPI(c *gin.Context) {
 	fileID := c.Param("id")
 
 	// 找到文件配置所属的项目和环境
-	projects, err := s.projectManager.ListProjects()
+	project, target, _, err := s.findFile(fileID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-
-	var projectID, envID string
-	for _, project := range projects {
-		for _, env := range project.Environments {
-			for _, file := range env.Files {
-				if file.ID == fileID {
-					projectID = project.ID
-					envID = env.ID
-					break
-				}
-			}
-			if projectID != "" {
-				break
-			}
-		}
-		if projectID != "" {
-			break
-		}
+	if project == nil {
+		respondError(c, http.StatusNotFound, "File configuration not found")
+		return
 	}
 
-	if projectID == "" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "File configuration not found",
-		})
+	if !s.requireConfirmation(c, "remove-file", target) {
 		return
 	}
 
-	err = s.fileManager.RemoveFileConfig(projectID, envID, fileID)
+	err = s.fileManager.RemoveFileConfig(project.ID, target.ID, fileID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "File configuration deleted successfully",
+	c.JSON(http.StatusOK, api.MessageResponse{
+		Message: "File configuration deleted successfully",
 	})
 }
 
 // 切换相关API
 
 func (s *Server) switchEnvironmentAPI(c *gin.Context) {
-	var request struct {
-		ProjectID     string `json:"project_id" binding:"required"`
-		EnvironmentID string `json:"environment_id" binding:"required"`
-	}
-
+	var request api.SwitchRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err := s.fileManager.SwitchEnvironment(request.ProjectID, request.EnvironmentID)
+	// 项目和环境也可以使用名称
+	proj, err := s.projectManager.GetProject(request.ProjectID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusNotFound, err.Error())
+		return
+	}
+	env, err := s.projectManager.GetEnvironment(proj.ID, request.EnvironmentID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Environment switched successfully",
-	})
-}
+	if !s.requireConfirmation(c, "switch", env) {
+		return
+	}
 
-func (s *Server) getStatusAPI(c *gin.Context) {
-	state, err := s.fileManager.GetCurrentState()
+	err = s.fileManager.SwitchEnvironment(proj.ID, env.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, state)
+	result := api.SwitchResult{
+		Project:     proj.Name,
+		Environment: env.Name,
+		Files:       []internal.FileMapping{},
+	}
+	for _, fileConfig := range env.Files {
+		if mappings, err := s.fileManager.ExpandFileConfig(proj, &fileConfig); err == nil {
+			result.Files = append(result.Files, mappings...)
+		}
+	}
+	result.DotEnvPath, _ = s.fileManager.ResolveDotEnvPath(proj, env)
+	if state, err := s.fileManager.GetCurrentState(); err == nil {
+		result.BackupID = state.BackupID
+		result.SwitchedAt = state.LastSwitchAt
+	}
+
+	c.JSON(http.StatusOK, api.SwitchResponse{
+		Message:      "Environment switched successfully",
+		SwitchResult: result,
+	})
 }
 
 func (s *Server) rollbackAPI(c *gin.Context) {
-	var request struct {
-		BackupID string `json:"backup_id"`
-	}
-
+	var request api.RollbackRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -482,30 +725,50 @@ func (s *Server) rollbackAPI(c *gin.Context) {
 		// 使用当前状态中的备份ID
 		state, err := s.fileManager.GetCurrentState()
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": err.Error(),
-			})
+			respondError(c, http.StatusInternalServerError, err.Error())
 			return
 		}
 		backupID = state.BackupID
 	}
 
 	if backupID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "No backup ID provided and no backup available",
-		})
+		respondError(c, http.StatusBadRequest, "No backup ID provided and no backup available")
 		return
 	}
 
+	// 回滚前读取备份信息，用于返回结果
+	result := api.RollbackResult{
+		BackupID:      backupID,
+		RestoredFiles: []string{},
+	}
+	if backup, err := s.projectManager.GetStorage().LoadBackupInfo(backupID); err == nil {
+		for targetPath := range backup.Files {
+			result.RestoredFiles = append(result.RestoredFiles, targetPath)
+		}
+		sort.Strings(result.RestoredFiles)
+		if proj, err := s.projectManager.GetProject(backup.ProjectID); err == nil {
+			result.Project = proj.Name
+			for _, env := range proj.Environments {
+				if env.ID == backup.EnvID {
+					result.Environment = env.Name
+				}
+			}
+		}
+	}
+
 	err := s.fileManager.RollbackFromBackup(backupID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": err.Error(),
-		})
+		respondError(c, errorStatus(err, http.StatusBadRequest), err.Error())
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Rollback completed successfully",
+	c.JSON(http.StatusOK, api.RollbackResponse{
+		Message:        "Rollback completed successfully",
+		RollbackResult: result,
 	})
 }
+
+// openAPIHandler 返回 OpenAPI 文档
+func (s *Server) openAPIHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, api.Spec())
+}
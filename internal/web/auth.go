@@ -0,0 +1,411 @@
+package web
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zoyopei/envswitch/api"
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sessionCookie UI 登录会话的 Cookie 名称
+	sessionCookie = "envswitch_session"
+	// sessionTTL 登录会话的有效期
+	sessionTTL = 12 * time.Hour
+	// tokenContextKey 当前请求令牌在 gin.Context 中的键
+	tokenContextKey = "envswitch_token"
+)
+
+// session UI 登录会话，只记录令牌 ID，令牌吊销后会话随之失效
+type session struct {
+	tokenID   string
+	expiresAt time.Time
+}
+
+// sessionStore 内存中的登录会话，服务重启后需要重新登录
+type sessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]session
+}
+
+// newSessionStore 创建登录会话存储
+func newSessionStore() *sessionStore {
+	return &sessionStore{sessions: make(map[string]session)}
+}
+
+// create 为令牌创建新的登录会话
+func (s *sessionStore) create(tokenID string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(buf)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 顺便清理过期的会话
+	now := time.Now()
+	for sid, sess := range s.sessions {
+		if now.After(sess.expiresAt) {
+			delete(s.sessions, sid)
+		}
+	}
+	s.sessions[id] = session{tokenID: tokenID, expiresAt: now.Add(sessionTTL)}
+
+	return id, nil
+}
+
+// lookup 返回会话对应的令牌 ID
+func (s *sessionStore) lookup(id string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sess, ok := s.sessions[id]
+	if !ok || time.Now().After(sess.expiresAt) {
+		return "", false
+	}
+	return sess.tokenID, true
+}
+
+// remove 删除会话
+func (s *sessionStore) remove(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
+
+// authenticate 识别请求使用的令牌：Authorization: Bearer 请求头或登录会话
+// 配置中没有任何令牌时不启用认证，enabled 为 false
+func (s *Server) authenticate(c *gin.Context) (token *internal.APIToken, enabled bool, err error) {
+	tokens, err := config.LoadTokens()
+	if err != nil {
+		return nil, true, err
+	}
+	if len(tokens) == 0 {
+		return nil, false, nil
+	}
+
+	if auth := c.GetHeader("Authorization"); auth != "" {
+		secret, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok {
+			return nil, true, nil
+		}
+		return config.FindToken(tokens, strings.TrimSpace(secret)), true, nil
+	}
+
+	if id, err := c.Cookie(sessionCookie); err == nil {
+		if tokenID, ok := s.sessions.lookup(id); ok {
+			for i := range tokens {
+				if tokens[i].ID == tokenID && !config.TokenExpired(&tokens[i]) {
+					return &tokens[i], true, nil
+				}
+			}
+		}
+	}
+
+	return nil, true, nil
+}
+
+// requireAuth 认证中间件，未登录的页面请求跳转到登录页，API 请求返回 401
+func (s *Server) requireAuth(c *gin.Context) {
+	token, enabled, err := s.authenticate(c)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, api.ErrorResponse{Error: err.Error()})
+		return
+	}
+	if !enabled {
+		c.Next()
+		return
+	}
+
+	if token == nil {
+		if isPageRequest(c) {
+			c.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+			c.Abort()
+			return
+		}
+		c.Header("WWW-Authenticate", `Bearer realm="envswitch"`)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, api.ErrorResponse{Error: "Authentication required"})
+		return
+	}
+
+	c.Set(tokenContextKey, token)
+	c.Next()
+}
+
+// currentToken 返回当前请求的令牌，未启用认证时返回 nil（不限制权限）
+func currentToken(c *gin.Context) *internal.APIToken {
+	if value, ok := c.Get(tokenContextKey); ok {
+		return value.(*internal.APIToken)
+	}
+	return nil
+}
+
+// canAccessProject 当前请求能否访问指定项目
+func canAccessProject(c *gin.Context, project *internal.Project) bool {
+	token := currentToken(c)
+	return token == nil || config.TokenAllowsProject(token, project)
+}
+
+// visibleProjects 过滤出当前请求可访问的项目
+func visibleProjects(c *gin.Context, projects []internal.Project) []internal.Project {
+	token := currentToken(c)
+	if token == nil || len(token.Projects) == 0 {
+		return projects
+	}
+
+	visible := make([]internal.Project, 0, len(projects))
+	for i := range projects {
+		if config.TokenAllowsProject(token, &projects[i]) {
+			visible = append(visible, projects[i])
+		}
+	}
+	return visible
+}
+
+// projectResolver 解析请求所操作的项目，找不到时返回 nil（由处理器返回 404）
+type projectResolver func(s *Server, c *gin.Context) *internal.Project
+
+// authorize 权限中间件：检查令牌角色，resolve 不为 nil 时检查令牌能否访问请求操作的项目
+// resolve 为 nil 的路由由处理器按令牌过滤结果
+func (s *Server) authorize(role string, resolve projectResolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := currentToken(c)
+		if token == nil {
+			c.Next()
+			return
+		}
+
+		if !config.RoleAllows(token.Role, role) {
+			s.forbidden(c, fmt.Sprintf("Token '%s' has role '%s', this action requires '%s'", token.Name, token.Role, role))
+			return
+		}
+		if resolve != nil {
+			if project := resolve(s, c); project != nil && !config.TokenAllowsProject(token, project) {
+				s.forbidden(c, fmt.Sprintf("Token '%s' is not allowed to access project '%s'", token.Name, project.Name))
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// authorizeGlobal 权限中间件：用于不属于单个项目的操作（如创建、导入项目），要求令牌不限项目
+func (s *Server) authorizeGlobal(role string) gin.HandlerFunc {
+	check := s.authorize(role, nil)
+	return func(c *gin.Context) {
+		if token := currentToken(c); token != nil && len(token.Projects) > 0 {
+			s.forbidden(c, fmt.Sprintf("Token '%s' is limited to projects %s", token.Name, strings.Join(token.Projects, ", ")))
+			return
+		}
+		check(c)
+	}
+}
+
+// forbidden 拒绝请求，页面请求显示错误页
+func (s *Server) forbidden(c *gin.Context, message string) {
+	if isPageRequest(c) {
+		c.HTML(http.StatusForbidden, "error.html", gin.H{
+			"error":  message,
+			"status": s.getStatusData(c),
+		})
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusForbidden, api.ErrorResponse{Error: message})
+}
+
+// isPageRequest 是否为页面请求（非 API、WebSocket 和指标）
+func isPageRequest(c *gin.Context) bool {
+	path := c.Request.URL.Path
+	return !strings.HasPrefix(path, "/api/") && path != "/ws" && path != "/metrics"
+}
+
+// projectParam 路径参数 :id 为项目 ID 或名称
+func projectParam(s *Server, c *gin.Context) *internal.Project {
+	project, err := s.projectManager.GetProject(c.Param("id"))
+	if err != nil {
+		return nil
+	}
+	return project
+}
+
+// environmentParam 路径参数 :id 为环境 ID
+func environmentParam(s *Server, c *gin.Context) *internal.Project {
+	projects, err := s.projectManager.ListProjects()
+	if err != nil {
+		return nil
+	}
+	for i := range projects {
+		for _, env := range projects[i].Environments {
+			if env.ID == c.Param("id") {
+				return &projects[i]
+			}
+		}
+	}
+	return nil
+}
+
+// fileParam 路径参数 :id 为文件配置 ID
+func fileParam(s *Server, c *gin.Context) *internal.Project {
+	projects, err := s.projectManager.ListProjects()
+	if err != nil {
+		return nil
+	}
+	for i := range projects {
+		for _, env := range projects[i].Environments {
+			for _, fileConfig := range env.Files {
+				if fileConfig.ID == c.Param("id") {
+					return &projects[i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// projectBody 请求体中的 project_id 字段
+func projectBody(s *Server, c *gin.Context) *internal.Project {
+	project, err := s.projectManager.GetProject(bodyField(c, "project_id"))
+	if err != nil {
+		return nil
+	}
+	return project
+}
+
+// backupBody 请求体中 backup_id 对应备份所属的项目，未指定时使用当前状态中的备份
+func backupBody(s *Server, c *gin.Context) *internal.Project {
+	backupID := bodyField(c, "backup_id")
+	if backupID == "" {
+		state, err := s.fileManager.GetCurrentState()
+		if err != nil {
+			return nil
+		}
+		backupID = state.BackupID
+	}
+
+	backup, err := s.projectManager.GetStorage().LoadBackupInfo(backupID)
+	if err != nil {
+		return nil
+	}
+	project, err := s.projectManager.GetProject(backup.ProjectID)
+	if err != nil {
+		return nil
+	}
+	return project
+}
+
+// bodyField 读取 JSON 请求体中的字符串字段，并恢复请求体供处理器再次读取
+func bodyField(c *gin.Context, field string) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return ""
+	}
+	value, _ := body[field].(string)
+	return value
+}
+
+// checkOrigin 只接受同源的 WebSocket 连接，未携带 Origin 的非浏览器客户端不受限制
+func checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// loginPageHandler 登录页
+func (s *Server) loginPageHandler(c *gin.Context) {
+	if _, enabled, _ := s.authenticate(c); !enabled {
+		c.Redirect(http.StatusFound, "/")
+		return
+	}
+	c.HTML(http.StatusOK, "login.html", gin.H{
+		"title": "Login",
+		"next":  safeRedirect(c.Query("next")),
+	})
+}
+
+// loginHandler 使用 API 令牌登录，创建会话并写入 Cookie
+func (s *Server) loginHandler(c *gin.Context) {
+	next := safeRedirect(c.PostForm("next"))
+
+	tokens, err := config.LoadTokens()
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login.html", gin.H{
+			"title": "Login",
+			"next":  next,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	token := config.FindToken(tokens, strings.TrimSpace(c.PostForm("token")))
+	if token == nil {
+		c.HTML(http.StatusUnauthorized, "login.html", gin.H{
+			"title": "Login",
+			"next":  next,
+			"error": "令牌无效或已过期",
+		})
+		return
+	}
+
+	id, err := s.sessions.create(token.ID)
+	if err != nil {
+		c.HTML(http.StatusInternalServerError, "login.html", gin.H{
+			"title": "Login",
+			"next":  next,
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookie, id, int(sessionTTL.Seconds()), "/", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, next)
+}
+
+// logoutHandler 退出登录
+func (s *Server) logoutHandler(c *gin.Context) {
+	if id, err := c.Cookie(sessionCookie); err == nil {
+		s.sessions.remove(id)
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(sessionCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, "/login")
+}
+
+// safeRedirect 只允许跳转到本站路径，避免开放重定向
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
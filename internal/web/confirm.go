@@ -0,0 +1,145 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zoyopei/envswitch/api"
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// confirmTokenTTL 确认令牌的有效期
+const confirmTokenTTL = 5 * time.Minute
+
+// pendingConfirmation 已签发、尚未使用的确认令牌
+type pendingConfirmation struct {
+	action    string
+	envID     string
+	expiresAt time.Time
+}
+
+// confirmationStore 受保护环境操作的确认令牌，令牌绑定操作和环境，只能使用一次
+type confirmationStore struct {
+	mu      sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+// newConfirmationStore 创建确认令牌存储
+func newConfirmationStore() *confirmationStore {
+	return &confirmationStore{pending: make(map[string]pendingConfirmation)}
+}
+
+// issue 为指定操作和环境签发新的确认令牌
+func (s *confirmationStore) issue(action, envID string) (string, time.Time, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", time.Time{}, err
+	}
+	token := hex.EncodeToString(buf)
+	expiresAt := time.Now().Add(confirmTokenTTL)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 顺便清理过期的令牌
+	now := time.Now()
+	for t, p := range s.pending {
+		if now.After(p.expiresAt) {
+			delete(s.pending, t)
+		}
+	}
+	s.pending[token] = pendingConfirmation{action: action, envID: envID, expiresAt: expiresAt}
+
+	return token, expiresAt, nil
+}
+
+// consume 校验并作废令牌：envIDs 中每个环境都需要一个由同一操作签发且未过期的令牌，
+// 全部找到时作废这些令牌并返回 -1，否则不作废任何令牌，返回第一个缺少令牌的环境的下标
+func (s *confirmationStore) consume(tokens []string, action string, envIDs []string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	used := make(map[string]bool)
+	for i, envID := range envIDs {
+		found := ""
+		for _, token := range tokens {
+			p, ok := s.pending[token]
+			if ok && !used[token] && p.action == action && p.envID == envID && now.Before(p.expiresAt) {
+				found = token
+				break
+			}
+		}
+		if found == "" {
+			return i
+		}
+		used[found] = true
+	}
+
+	for token := range used {
+		delete(s.pending, token)
+	}
+	return -1
+}
+
+// confirmTokens 请求携带的确认令牌：X-Confirm-Token 请求头（多个令牌以逗号分隔）或 confirm_token 查询参数（可重复）
+func confirmTokens(c *gin.Context) []string {
+	var tokens []string
+	for _, header := range c.Request.Header.Values(api.ConfirmTokenHeader) {
+		for _, token := range strings.Split(header, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				tokens = append(tokens, token)
+			}
+		}
+	}
+	return append(tokens, c.QueryArray("confirm_token")...)
+}
+
+// requireConfirmation 受保护的环境需要有效的确认令牌才能继续操作，envs 中每个受保护的环境各需要一个令牌
+// （如删除项目时项目中的全部受保护环境）；缺少令牌或令牌无效时为第一个未确认的环境返回 428，
+// 并在响应中签发新的令牌，客户端携带已获得的全部令牌重试；返回 false 时调用方应直接返回
+func (s *Server) requireConfirmation(c *gin.Context, action string, envs ...*internal.Environment) bool {
+	var protected []*internal.Environment
+	var envIDs []string
+	for _, env := range envs {
+		if config.ProtectionReason(env) != "" {
+			protected = append(protected, env)
+			envIDs = append(envIDs, env.ID)
+		}
+	}
+	if len(protected) == 0 {
+		return true
+	}
+
+	missing := s.confirmations.consume(confirmTokens(c), action, envIDs)
+	if missing < 0 {
+		return true
+	}
+	env := protected[missing]
+
+	newToken, expiresAt, err := s.confirmations.issue(action, env.ID)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return false
+	}
+
+	reason := config.ProtectionReason(env)
+	c.JSON(http.StatusPreconditionRequired, api.ConfirmationRequired{
+		Error:        fmt.Sprintf("Environment '%s' is protected (%s), confirmation required", env.Name, reason),
+		Protected:    true,
+		Action:       action,
+		Environment:  env.Name,
+		Reason:       reason,
+		ConfirmToken: newToken,
+		ExpiresAt:    expiresAt,
+	})
+	return false
+}
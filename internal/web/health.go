@@ -0,0 +1,116 @@
+package web
+
+import (
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/zoyopei/envswitch/api"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsContentType Prometheus 文本格式的 Content-Type
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// instrument 记录每个请求的耗时，route 使用路由模板，未匹配的请求记为 unmatched
+func instrument(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(), c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+}
+
+// healthzHandler 存活检查，进程能处理请求即返回 200
+func (s *Server) healthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, api.Health{Status: "ok"})
+}
+
+// readyzHandler 就绪检查，数据目录可读写时返回 200，否则返回 503
+func (s *Server) readyzHandler(c *gin.Context) {
+	if err := checkDataDir(config.GetDataDir()); err != nil {
+		c.JSON(http.StatusServiceUnavailable, api.Health{
+			Status: "unavailable",
+			Checks: map[string]string{"data_dir": err.Error()},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, api.Health{Status: "ok"})
+}
+
+// checkDataDir 检查数据目录存在、可读，并能创建和删除文件
+func checkDataDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if _, err := os.ReadDir(dir); err != nil {
+		return err
+	}
+
+	probe, err := os.CreateTemp(dir, ".readyz-*")
+	if err != nil {
+		return err
+	}
+	name := probe.Name()
+	_ = probe.Close()
+	return os.Remove(name)
+}
+
+// metricsHandler 以 Prometheus 文本格式输出指标，备份占用和 WebSocket 连接数在请求时计算
+func (s *Server) metricsHandler(c *gin.Context) {
+	registry := metrics.NewRegistry()
+	registry.MustRegister(metrics.Default)
+
+	backupBytes, backupCount := backupUsage(config.GetBackupDir())
+	registry.MustRegister(
+		metrics.NewGaugeFunc("envswitch_backup_store_bytes",
+			"Total size of the backup store in bytes.",
+			func() float64 { return float64(backupBytes) }),
+		metrics.NewGaugeFunc("envswitch_backups",
+			"Number of backups in the backup store.",
+			func() float64 { return float64(backupCount) }),
+		metrics.NewGaugeFunc("envswitch_websocket_clients",
+			"Number of connected WebSocket clients.",
+			func() float64 { return float64(s.websocketClients.Load()) }),
+	)
+
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", metricsContentType)
+	_ = registry.WriteText(c.Writer)
+}
+
+// backupUsage 统计备份目录下文件的总大小和备份数量（每个备份一个子目录），目录不存在时为 0
+func backupUsage(dir string) (int64, int) {
+	var size int64
+	count := 0
+	_ = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if filepath.Dir(path) == filepath.Clean(dir) {
+				count++
+			}
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, count
+}
@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zoyopei/envswitch/api"
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+func setupHealthTest(t *testing.T) (*gin.Engine, *internal.Config) {
+	tempDir := t.TempDir()
+
+	originalConfig := config.GetConfig()
+	t.Cleanup(func() {
+		_ = config.SaveConfig(originalConfig)
+	})
+
+	testConfig := &internal.Config{
+		DataDir:   filepath.Join(tempDir, "data"),
+		BackupDir: filepath.Join(tempDir, "backups"),
+		WebPort:   8080,
+	}
+	if err := config.SaveConfig(testConfig); err != nil {
+		t.Fatalf("Failed to save test config: %v", err)
+	}
+	for _, dir := range []string{testConfig.DataDir, filepath.Join(testConfig.BackupDir, "backup-1")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(testConfig.BackupDir, "backup-1", "config.json"), []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	return NewServer().SetupRoutes(), testConfig
+}
+
+func get(router *gin.Engine, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestHealthEndpoints(t *testing.T) {
+	router, testConfig := setupHealthTest(t)
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		w := get(router, path)
+		var health api.Health
+		if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &health) != nil || health.Status != "ok" {
+			t.Errorf("GET %s = %d %s, expected ok", path, w.Code, w.Body.String())
+		}
+	}
+
+	// 数据目录不可用时未就绪，存活检查不受影响
+	if err := os.RemoveAll(testConfig.DataDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(testConfig.DataDir, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	w := get(router, "/readyz")
+	var health api.Health
+	if w.Code != http.StatusServiceUnavailable || json.Unmarshal(w.Body.Bytes(), &health) != nil || health.Checks["data_dir"] == "" {
+		t.Errorf("GET /readyz = %d %s, expected data_dir failure", w.Code, w.Body.String())
+	}
+	if w := get(router, "/healthz"); w.Code != http.StatusOK {
+		t.Errorf("GET /healthz = %d, expected 200", w.Code)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	router, _ := setupHealthTest(t)
+
+	get(router, "/healthz")
+	get(router, "/no-such-page")
+
+	w := get(router, "/metrics")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /metrics = %d %s", w.Code, w.Body.String())
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Unexpected Content-Type %q", w.Header().Get("Content-Type"))
+	}
+
+	body := w.Body.String()
+	for _, expected := range []string{
+		"# TYPE envswitch_switches_total counter",
+		"# TYPE envswitch_switch_duration_seconds histogram",
+		"# TYPE envswitch_switch_failures_total counter",
+		"# TYPE envswitch_rollbacks_total counter",
+		`envswitch_http_request_duration_seconds_count{method="GET",route="/healthz",status="200"}`,
+		`envswitch_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"}`,
+		"envswitch_backup_store_bytes 10\n",
+		"envswitch_backups 1\n",
+		"envswitch_websocket_clients 0\n",
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", expected, body)
+		}
+	}
+}
+
+func TestMetricsRequiresAuth(t *testing.T) {
+	router, _ := setupHealthTest(t)
+
+	if _, _, err := config.CreateToken("ci", config.RoleViewer, nil, 0); err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	_, limited, err := config.CreateToken("limited", config.RoleAdmin, []string{"web"}, 0)
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+
+	if w := get(router, "/metrics"); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /metrics without token = %d, expected 401", w.Code)
+	}
+	if w := get(router, "/healthz"); w.Code != http.StatusOK {
+		t.Errorf("GET /healthz without token = %d, expected 200", w.Code)
+	}
+	if w := get(router, "/readyz"); w.Code != http.StatusOK {
+		t.Errorf("GET /readyz without token = %d, expected 200", w.Code)
+	}
+
+	// 指标包含全部项目，限定项目的令牌无权查看
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	req.Header.Set("Authorization", "Bearer "+limited)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("GET /metrics with project token = %d, expected 403", w.Code)
+	}
+}
@@ -0,0 +1,386 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
+	"github.com/zoyopei/envswitch/internal/events"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// stateWatchInterval 检查状态文件的间隔，用于发现其他进程的切换
+	stateWatchInterval = 2 * time.Second
+	// eventHistorySize 保留的最近事件数，用于 SSE 断线续传
+	eventHistorySize = 256
+	// subscriptionBuffer 每个订阅的待发送事件缓冲区，缓冲区满时断开订阅
+	subscriptionBuffer = 32
+	// wsPingInterval WebSocket 心跳间隔
+	wsPingInterval = 30 * time.Second
+	// wsWriteTimeout WebSocket 写超时
+	wsWriteTimeout = 10 * time.Second
+)
+
+// subscription 一个实时事件订阅（WebSocket 或 SSE 连接），projects 为空时接收全部项目的事件
+type subscription struct {
+	token    *internal.APIToken
+	events   chan events.Event
+	mu       sync.Mutex
+	projects map[string]bool
+}
+
+// newSubscription 创建订阅，projects 为项目 ID 或名称
+func newSubscription(token *internal.APIToken, projects []string) *subscription {
+	sub := &subscription{
+		token:    token,
+		events:   make(chan events.Event, subscriptionBuffer),
+		projects: make(map[string]bool),
+	}
+	sub.subscribe(projects, true)
+	return sub
+}
+
+// subscribe 增加或移除订阅的项目，返回当前订阅列表
+func (s *subscription) subscribe(projects []string, add bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, project := range projects {
+		if add {
+			s.projects[project] = true
+		} else {
+			delete(s.projects, project)
+		}
+	}
+
+	topics := make([]string, 0, len(s.projects))
+	for project := range s.projects {
+		topics = append(topics, project)
+	}
+	return topics
+}
+
+// wants 判断订阅是否应收到事件：令牌必须能访问事件所属项目，且项目在订阅列表中
+// 与项目无关的事件发送给全部订阅；当前状态是全局的，状态类事件不受订阅列表限制
+func (s *subscription) wants(event *events.Event) bool {
+	if event.ProjectID == "" {
+		return true
+	}
+
+	project := &internal.Project{ID: event.ProjectID, Name: event.ProjectName}
+	if s.token != nil && !config.TokenAllowsProject(s.token, project) {
+		return false
+	}
+
+	if isStatusEvent(event) {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.projects) == 0 || s.projects[event.ProjectID] || s.projects[event.ProjectName]
+}
+
+// isStatusEvent 是否为改变当前状态的事件
+func isStatusEvent(event *events.Event) bool {
+	switch event.Type {
+	case events.SwitchCompleted, events.Rollback, events.StatusChanged:
+		return true
+	}
+	return false
+}
+
+// hub 将事件总线上的事件分发给订阅，保留最近的事件供断线续传，
+// 并维护状态版本号供长轮询使用
+type hub struct {
+	once          sync.Once
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+	history       []events.Event
+	rev           uint64
+	revChanged    chan struct{}
+	loadState     func() (*internal.AppState, error)
+	lastState     string
+}
+
+// newHub 创建事件分发中心，loadState 用于检测其他进程对状态的修改
+func newHub(loadState func() (*internal.AppState, error)) *hub {
+	return &hub{
+		subscriptions: make(map[*subscription]struct{}),
+		rev:           1,
+		revChanged:    make(chan struct{}),
+		loadState:     loadState,
+	}
+}
+
+// start 首次使用时开始订阅事件总线
+func (h *hub) start() {
+	h.once.Do(func() {
+		h.lastState = h.stateKey()
+		ch, _ := events.Subscribe()
+		go h.run(ch)
+	})
+}
+
+// run 分发事件，并定期检查状态文件
+func (h *hub) run(ch <-chan events.Event) {
+	ticker := time.NewTicker(stateWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			// 状态类事件发生时同步状态快照，避免重复通知
+			if isStatusEvent(&event) {
+				h.lastState = h.stateKey()
+			}
+			h.broadcast(event)
+		case <-ticker.C:
+			key := h.stateKey()
+			if key == h.lastState {
+				continue
+			}
+			h.lastState = key
+			h.publishStatusChanged()
+		}
+	}
+}
+
+// stateKey 当前状态的摘要，状态变化时摘要随之变化
+func (h *hub) stateKey() string {
+	state, err := h.loadState()
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s", state.CurrentProject, state.CurrentEnvironment, state.BackupID)
+}
+
+// publishStatusChanged 发布 status.changed 事件
+func (h *hub) publishStatusChanged() {
+	state, err := h.loadState()
+	if err != nil {
+		return
+	}
+	events.Publish(events.Event{
+		Type:            events.StatusChanged,
+		ProjectID:       state.CurrentProject,
+		ProjectName:     state.ProjectName,
+		EnvironmentID:   state.CurrentEnvironment,
+		EnvironmentName: state.EnvironmentName,
+		BackupID:        state.BackupID,
+	})
+}
+
+// broadcast 记录事件并发送给需要的订阅，发送缓冲区已满的订阅会被断开
+func (h *hub) broadcast(event events.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.history = append(h.history, event)
+	if len(h.history) > eventHistorySize {
+		h.history = h.history[len(h.history)-eventHistorySize:]
+	}
+
+	if isStatusEvent(&event) {
+		h.rev++
+		close(h.revChanged)
+		h.revChanged = make(chan struct{})
+	}
+
+	for sub := range h.subscriptions {
+		if !sub.wants(&event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(h.subscriptions, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// register 注册订阅，返回序号大于 lastID 的历史事件（lastID 为 0 时不返回）
+// 注册和读取历史在同一把锁内完成，之后的事件都会进入订阅通道，不会遗漏或重复
+func (h *hub) register(sub *subscription, lastID uint64) []events.Event {
+	h.start()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.subscriptions[sub] = struct{}{}
+	if lastID == 0 {
+		return nil
+	}
+
+	var backlog []events.Event
+	for _, event := range h.history {
+		if event.ID > lastID && sub.wants(&event) {
+			backlog = append(backlog, event)
+		}
+	}
+	return backlog
+}
+
+// unregister 注销订阅
+func (h *hub) unregister(sub *subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscriptions[sub]; ok {
+		delete(h.subscriptions, sub)
+		close(sub.events)
+	}
+}
+
+// currentRev 当前状态版本号
+func (h *hub) currentRev() uint64 {
+	h.start()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.rev
+}
+
+// waitRev 等待状态版本号不再等于 rev，超时或 done 关闭时返回当前版本号
+func (h *hub) waitRev(rev uint64, timeout time.Duration, done <-chan struct{}) uint64 {
+	h.start()
+
+	h.mu.Lock()
+	current, changed := h.rev, h.revChanged
+	h.mu.Unlock()
+
+	if current != rev {
+		return current
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-changed:
+	case <-timer.C:
+	case <-done:
+	}
+	return h.currentRev()
+}
+
+// wsMessage 客户端发送的消息
+// action 为 subscribe、unsubscribe 或 ping，projects 为项目 ID 或名称
+type wsMessage struct {
+	Action   string   `json:"action"`
+	Projects []string `json:"projects"`
+}
+
+// wsClient 一个 WebSocket 连接
+type wsClient struct {
+	conn    *websocket.Conn
+	sub     *subscription
+	replies chan []byte
+}
+
+// websocketHandler 推送实时事件，可通过 ?project= 或 subscribe 消息按项目过滤
+func (s *Server) websocketHandler(c *gin.Context) {
+	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+
+	client := &wsClient{
+		conn:    conn,
+		sub:     newSubscription(currentToken(c), c.QueryArray("project")),
+		replies: make(chan []byte, subscriptionBuffer),
+	}
+
+	s.websocketClients.Add(1)
+	defer s.websocketClients.Add(-1)
+
+	s.hub.register(client.sub, 0)
+	go client.writePump()
+	client.readPump(s.hub)
+}
+
+// readPump 处理客户端的订阅消息，连接断开时注销
+func (c *wsClient) readPump(h *hub) {
+	defer func() {
+		h.unregister(c.sub)
+		_ = c.conn.Close()
+	}()
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var message wsMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			c.reply(gin.H{"type": "error", "error": "invalid message: " + err.Error()})
+			continue
+		}
+
+		switch message.Action {
+		case "subscribe", "unsubscribe":
+			topics := c.sub.subscribe(message.Projects, message.Action == "subscribe")
+			c.reply(gin.H{"type": "subscribed", "projects": topics})
+		case "ping":
+			c.reply(gin.H{"type": "pong"})
+		default:
+			c.reply(gin.H{"type": "error", "error": fmt.Sprintf("unknown action '%s'", message.Action)})
+		}
+	}
+}
+
+// reply 向客户端发送响应消息，缓冲区已满时丢弃
+func (c *wsClient) reply(message gin.H) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	select {
+	case c.replies <- data:
+	default:
+	}
+}
+
+// writePump 将事件和响应消息写入连接，并定期发送心跳
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(wsPingInterval)
+	defer func() {
+		ticker.Stop()
+		_ = c.conn.Close()
+	}()
+
+	for {
+		var data []byte
+		select {
+		case event, ok := <-c.sub.events:
+			if !ok {
+				_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+				_ = c.conn.WriteMessage(websocket.CloseMessage, nil)
+				return
+			}
+			data, _ = json.Marshal(event)
+		case data = <-c.replies:
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			continue
+		}
+
+		_ = c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+		if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			return
+		}
+	}
+}
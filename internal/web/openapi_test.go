@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/zoyopei/envswitch/api"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOpenAPIMatchesRoutes OpenAPI 文档中的接口必须与注册的路由一致
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewServer().SetupRoutes()
+
+	param := regexp.MustCompile(`:(\w+)`)
+	routes := make(map[string]bool)
+	legacy := make(map[string]bool)
+	for _, route := range router.Routes() {
+		path := param.ReplaceAllString(route.Path, "{$1}")
+		switch {
+		case strings.HasPrefix(path, api.BasePath+"/"):
+			routes[route.Method+" "+strings.TrimPrefix(path, api.BasePath)] = true
+		case strings.HasPrefix(path, "/api/"):
+			legacy[route.Method+" "+strings.TrimPrefix(path, "/api")] = true
+		}
+	}
+
+	documented := make(map[string]bool)
+	for _, op := range api.Operations {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	for key := range routes {
+		if !documented[key] {
+			t.Errorf("Route %s is not documented in api.Operations", key)
+		}
+	}
+	for key := range documented {
+		if !routes[key] {
+			t.Errorf("Operation %s has no registered route", key)
+		}
+	}
+
+	// /api 兼容别名注册相同的路由（OpenAPI 文档只在 v1 下提供）
+	for key := range routes {
+		if key != "GET /openapi.json" && !legacy[key] {
+			t.Errorf("Route %s is missing from the legacy /api prefix", key)
+		}
+	}
+	if len(legacy) != len(routes)-1 {
+		keys := make([]string, 0, len(legacy))
+		for key := range legacy {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		t.Errorf("Unexpected legacy routes: %v", keys)
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := NewServer().SetupRoutes()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, api.BasePath+"/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var spec struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Invalid OpenAPI JSON: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected OpenAPI 3 document, got %q", spec.OpenAPI)
+	}
+	if _, ok := spec.Paths[api.BasePath+"/switch"]["post"]; !ok {
+		t.Errorf("Expected POST %s/switch in spec paths", api.BasePath)
+	}
+}
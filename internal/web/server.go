@@ -5,8 +5,12 @@ import (
 	"html/template"
 	"io/fs"
 	"net/http"
+	"path/filepath"
+	"sync/atomic"
 
+	"github.com/zoyopei/envswitch/api"
 	"github.com/zoyopei/envswitch/internal"
+	"github.com/zoyopei/envswitch/internal/config"
 	"github.com/zoyopei/envswitch/internal/file"
 	"github.com/zoyopei/envswitch/internal/project"
 
@@ -24,88 +28,149 @@ type Server struct {
 	projectManager *project.Manager
 	fileManager    *file.Manager
 	upgrader       websocket.Upgrader
+	confirmations  *confirmationStore
+	sessions       *sessionStore
+	hub            *hub
+	debug          bool
+
+	// websocketClients 当前连接的 WebSocket 客户端数量
+	websocketClients atomic.Int64
 }
 
 // NewServer 创建新的Web服务器实例
 func NewServer() *Server {
+	fileManager := file.NewManager()
 	return &Server{
 		projectManager: project.NewManager(),
-		fileManager:    file.NewManager(),
+		fileManager:    fileManager,
+		confirmations:  newConfirmationStore(),
+		sessions:       newSessionStore(),
+		hub:            newHub(fileManager.GetCurrentState),
 		upgrader: websocket.Upgrader{
-			CheckOrigin: func(_ *http.Request) bool {
-				return true // 在生产环境中应该有更严格的检查
-			},
+			CheckOrigin: checkOrigin,
 		},
 	}
 }
 
+// SetDebug 设置是否以 Gin 的 debug 模式运行，默认使用 release 模式
+func (s *Server) SetDebug(debug bool) {
+	s.debug = debug
+}
+
 // SetupRoutes 设置路由
 func (s *Server) SetupRoutes() *gin.Engine {
-	// 在生产环境中设置为release模式
-	// gin.SetMode(gin.ReleaseMode)
+	// 未开启调试时使用 release 模式；测试设置的 test 模式保持不变
+	if s.debug {
+		gin.SetMode(gin.DebugMode)
+	} else if gin.Mode() == gin.DebugMode {
+		gin.SetMode(gin.ReleaseMode)
+	}
 
 	r := gin.Default()
+	r.Use(instrument)
 
 	// 使用嵌入的静态文件系统，需要去掉前缀
 	staticFiles, _ := fs.Sub(staticFS, "static")
 	r.StaticFS("/static", http.FS(staticFiles))
 
 	// 使用嵌入的模板文件系统
-	tmpl := template.Must(template.New("").ParseFS(templateFS, "templates/*"))
+	tmpl := template.Must(template.New("").Funcs(template.FuncMap{
+		"protectionReason": config.ProtectionReason,
+		"isGlob": func(fileConfig internal.FileConfig) bool {
+			return s.fileManager.IsGlobFileConfig(&fileConfig)
+		},
+	}).ParseFS(templateFS, "templates/*"))
 	r.SetHTMLTemplate(tmpl)
 
-	// 页面路由
-	r.GET("/", s.indexHandler)
-	r.GET("/projects", s.projectsPageHandler)
-	r.GET("/projects/:id", s.projectDetailPageHandler)
-	r.GET("/environments/:id", s.environmentDetailPageHandler)
+	// 健康检查无需认证，指标需要全局只读权限
+	r.GET("/healthz", s.healthzHandler)
+	r.GET("/readyz", s.readyzHandler)
+	r.GET("/metrics", s.requireAuth, s.authorizeGlobal(config.RoleViewer), s.metricsHandler)
 
-	// API路由
-	api := r.Group("/api")
+	// 登录（配置了 API 令牌时启用认证）
+	r.GET("/login", s.loginPageHandler)
+	r.POST("/login", s.loginHandler)
+	r.GET("/logout", s.logoutHandler)
+
+	// 页面路由
+	pages := r.Group("", s.requireAuth)
 	{
-		// 项目相关API
-		projects := api.Group("/projects")
-		{
-			projects.GET("", s.listProjectsAPI)
-			projects.POST("", s.createProjectAPI)
-			projects.GET("/:id", s.getProjectAPI)
-			projects.PUT("/:id", s.updateProjectAPI)
-			projects.DELETE("/:id", s.deleteProjectAPI)
-
-			// 项目下的环境
-			projects.GET("/:id/environments", s.listEnvironmentsAPI)
-			projects.POST("/:id/environments", s.createEnvironmentAPI)
-		}
+		pages.GET("/", s.indexHandler)
+		pages.GET("/projects", s.projectsPageHandler)
+		pages.GET("/projects/:id", s.authorize(config.RoleViewer, projectParam), s.projectDetailPageHandler)
+		pages.GET("/environments/:id", s.authorize(config.RoleViewer, environmentParam), s.environmentDetailPageHandler)
+		pages.GET("/files/:id/edit", s.authorize(config.RoleViewer, fileParam), s.fileEditorPageHandler)
+	}
 
-		// 环境相关API
-		environments := api.Group("/environments")
-		{
-			environments.GET("/:id", s.getEnvironmentAPI)
-			environments.PUT("/:id", s.updateEnvironmentAPI)
-			environments.DELETE("/:id", s.deleteEnvironmentAPI)
+	// API路由：正式版本位于 /api/v1，/api 作为兼容别名保留；OpenAPI 文档无需认证
+	r.GET(api.BasePath+"/openapi.json", s.openAPIHandler)
+	s.registerAPI(r.Group(api.BasePath, s.requireAuth))
+	s.registerAPI(r.Group("/api", s.requireAuth))
 
-			// 环境下的文件配置
-			environments.POST("/:id/files", s.addFileConfigAPI)
-		}
+	// WebSocket
+	r.GET("/ws", s.requireAuth, s.authorize(config.RoleViewer, nil), s.websocketHandler)
+
+	return r
+}
 
-		// 文件配置相关API
-		api.PUT("/files/:id", s.updateFileConfigAPI)
-		api.DELETE("/files/:id", s.deleteFileConfigAPI)
+// registerAPI 在 group 下注册 REST API 路由，路由与 api.Operations 一一对应
+func (s *Server) registerAPI(group *gin.RouterGroup) {
+	viewer := config.RoleViewer
+	operator := config.RoleOperator
+	admin := config.RoleAdmin
 
-		// 切换相关API
-		api.POST("/switch", s.switchEnvironmentAPI)
-		api.GET("/status", s.getStatusAPI)
-		api.POST("/rollback", s.rollbackAPI)
+	// 项目相关API
+	projects := group.Group("/projects")
+	{
+		projects.GET("", s.authorize(viewer, nil), s.listProjectsAPI)
+		projects.POST("", s.authorizeGlobal(admin), s.createProjectAPI)
+		projects.POST("/import", s.authorizeGlobal(admin), s.importProjectAPI)
+		projects.GET("/:id", s.authorize(viewer, projectParam), s.getProjectAPI)
+		projects.PUT("/:id", s.authorize(admin, projectParam), s.updateProjectAPI)
+		projects.DELETE("/:id", s.authorize(admin, projectParam), s.deleteProjectAPI)
+		projects.GET("/:id/export", s.authorize(viewer, projectParam), s.exportProjectAPI)
+
+		// 项目下的环境
+		projects.GET("/:id/environments", s.authorize(viewer, projectParam), s.listEnvironmentsAPI)
+		projects.POST("/:id/environments", s.authorize(admin, projectParam), s.createEnvironmentAPI)
 	}
 
-	// WebSocket
-	r.GET("/ws", s.websocketHandler)
+	// 环境相关API
+	environments := group.Group("/environments")
+	{
+		environments.GET("/:id", s.authorize(viewer, environmentParam), s.getEnvironmentAPI)
+		environments.PUT("/:id", s.authorize(admin, environmentParam), s.updateEnvironmentAPI)
+		environments.DELETE("/:id", s.authorize(admin, environmentParam), s.deleteEnvironmentAPI)
+		environments.GET("/:id/plan", s.authorize(viewer, environmentParam), s.environmentPlanAPI)
+		environments.POST("/:id/clone", s.authorize(admin, environmentParam), s.cloneEnvironmentAPI)
+
+		// 环境下的文件配置
+		environments.POST("/:id/files", s.authorize(admin, environmentParam), s.addFileConfigAPI)
+	}
 
-	return r
+	// 文件配置相关API
+	group.PUT("/files/:id", s.authorize(admin, fileParam), s.updateFileConfigAPI)
+	group.DELETE("/files/:id", s.authorize(admin, fileParam), s.deleteFileConfigAPI)
+	group.GET("/files/:id/content", s.authorize(viewer, fileParam), s.getFileContentAPI)
+	group.PUT("/files/:id/content", s.authorize(admin, fileParam), s.updateFileContentAPI)
+	group.GET("/files/:id/target", s.authorize(viewer, fileParam), s.getFileTargetAPI)
+
+	// 切换相关API
+	group.POST("/switch", s.authorize(operator, projectBody), s.switchEnvironmentAPI)
+	group.GET("/status", s.authorize(viewer, nil), s.statusAPI)
+	group.POST("/rollback", s.authorize(operator, backupBody), s.rollbackAPI)
+
+	// 实时事件（SSE）
+	group.GET("/events", s.authorize(viewer, nil), s.eventsStreamAPI)
 }
 
-// 获取状态信息的辅助函数
-func (s *Server) getStatusData() gin.H {
+// 获取状态信息的辅助函数，user 为当前登录的令牌名称（未启用认证时为空）
+func (s *Server) getStatusData(c *gin.Context) gin.H {
+	user := ""
+	if token := currentToken(c); token != nil {
+		user = token.Name
+	}
+
 	state, err := s.fileManager.GetCurrentState()
 	if err != nil {
 		return gin.H{
@@ -113,6 +178,8 @@ func (s *Server) getStatusData() gin.H {
 			"current_environment": "",
 			"last_switch_at":     "",
 			"has_active_env":     false,
+			"profile":            config.GetActiveProfileName(),
+			"user":               user,
 		}
 	}
 	
@@ -143,12 +210,14 @@ func (s *Server) getStatusData() gin.H {
 		"current_environment": currentEnvironmentName,
 		"last_switch_at":     state.LastSwitchAt,
 		"has_active_env":     state.CurrentProject != "" && state.CurrentEnvironment != "",
+		"profile":            config.GetActiveProfileName(),
+		"user":               user,
 	}
 }
 
 // 页面处理器
 func (s *Server) indexHandler(c *gin.Context) {
-	status := s.getStatusData()
+	status := s.getStatusData(c)
 	c.HTML(http.StatusOK, "index.html", gin.H{
 		"title":  "envswitch - Environment Management",
 		"status": status,
@@ -158,7 +227,7 @@ func (s *Server) indexHandler(c *gin.Context) {
 func (s *Server) projectsPageHandler(c *gin.Context) {
 	projects, err := s.projectManager.ListProjects()
 	if err != nil {
-		status := s.getStatusData()
+		status := s.getStatusData(c)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error":  err.Error(),
 			"status": status,
@@ -166,10 +235,10 @@ func (s *Server) projectsPageHandler(c *gin.Context) {
 		return
 	}
 
-	status := s.getStatusData()
+	status := s.getStatusData(c)
 	c.HTML(http.StatusOK, "projects.html", gin.H{
 		"title":    "Projects",
-		"projects": projects,
+		"projects": visibleProjects(c, projects),
 		"status":   status,
 	})
 }
@@ -179,7 +248,7 @@ func (s *Server) projectDetailPageHandler(c *gin.Context) {
 
 	project, err := s.projectManager.GetProject(projectID)
 	if err != nil {
-		status := s.getStatusData()
+		status := s.getStatusData(c)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error":  "Project not found",
 			"status": status,
@@ -187,7 +256,7 @@ func (s *Server) projectDetailPageHandler(c *gin.Context) {
 		return
 	}
 
-	status := s.getStatusData()
+	status := s.getStatusData(c)
 	
 	// 获取当前激活的环境ID
 	currentEnvID := ""
@@ -212,7 +281,7 @@ func (s *Server) environmentDetailPageHandler(c *gin.Context) {
 	// 在实际应用中，可能需要更复杂的查询逻辑
 	projects, err := s.projectManager.ListProjects()
 	if err != nil {
-		status := s.getStatusData()
+		status := s.getStatusData(c)
 		c.HTML(http.StatusInternalServerError, "error.html", gin.H{
 			"error":  err.Error(),
 			"status": status,
@@ -237,7 +306,7 @@ func (s *Server) environmentDetailPageHandler(c *gin.Context) {
 	}
 
 	if targetEnv == nil {
-		status := s.getStatusData()
+		status := s.getStatusData(c)
 		c.HTML(http.StatusNotFound, "error.html", gin.H{
 			"error":  "Environment not found",
 			"status": status,
@@ -245,7 +314,7 @@ func (s *Server) environmentDetailPageHandler(c *gin.Context) {
 		return
 	}
 
-	status := s.getStatusData()
+	status := s.getStatusData(c)
 	
 	// 获取当前激活的环境ID
 	currentEnvID := ""
@@ -255,34 +324,52 @@ func (s *Server) environmentDetailPageHandler(c *gin.Context) {
 		}
 	}
 	
+	// 展开通配符文件配置，列出具体匹配的文件
+	fileMappings := make(map[string][]internal.FileMapping)
+	fileErrors := make(map[string]string)
+	for _, fileConfig := range targetEnv.Files {
+		mappings, err := s.fileManager.ExpandFileConfig(targetProject, &fileConfig)
+		if err != nil {
+			fileErrors[fileConfig.ID] = err.Error()
+			continue
+		}
+		if s.fileManager.IsGlobFileConfig(&fileConfig) {
+			fileMappings[fileConfig.ID] = mappings
+		}
+	}
+
 	c.HTML(http.StatusOK, "environment_detail.html", gin.H{
-		"title":        "Environment: " + targetEnv.Name,
-		"project":      targetProject,
-		"environment":  targetEnv,
-		"status":       status,
-		"current_env":  currentEnvID,
+		"title":         "Environment: " + targetEnv.Name,
+		"project":       targetProject,
+		"environment":   targetEnv,
+		"status":        status,
+		"current_env":   currentEnvID,
+		"file_mappings": fileMappings,
+		"file_errors":   fileErrors,
 	})
 }
 
-// WebSocket处理器
-func (s *Server) websocketHandler(c *gin.Context) {
-	conn, err := s.upgrader.Upgrade(c.Writer, c.Request, nil)
+// fileEditorPageHandler 源文件编辑页面，内容通过 /api/v1/files/:id/content 读取和保存
+func (s *Server) fileEditorPageHandler(c *gin.Context) {
+	targetProject, targetEnv, fileConfig, err := s.findFile(c.Param("id"))
+	if err == nil && targetProject == nil {
+		err = internal.NotFoundf("File configuration not found")
+	}
 	if err != nil {
+		c.HTML(errorStatus(err, http.StatusInternalServerError), "error.html", gin.H{
+			"error":  err.Error(),
+			"status": s.getStatusData(c),
+		})
 		return
 	}
-	defer func() { _ = conn.Close() }()
-
-	// WebSocket连接处理逻辑
-	for {
-		// 读取消息
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			break
-		}
 
-		// 回声消息（简单实现）
-		if err := conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			break
-		}
-	}
+	c.HTML(http.StatusOK, "file_editor.html", gin.H{
+		"title":       "Edit: " + filepath.Base(fileConfig.TargetPath),
+		"project":     targetProject,
+		"environment": targetEnv,
+		"file":        fileConfig,
+		"max_size":    file.MaxContentSize,
+		"status":      s.getStatusData(c),
+	})
 }
+
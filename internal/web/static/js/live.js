@@ -0,0 +1,146 @@
+// 实时事件
+// 通过 /ws 接收切换、回滚和项目变更事件，无需刷新页面即可更新当前状态；
+// 项目详情和环境详情页只订阅所在项目的变更事件（状态类事件总会收到）
+
+(function () {
+    const projectID = document.body.dataset.projectId || '';
+    const statusEvents = ['switch.completed', 'rollback', 'status.changed'];
+    let retryDelay = 1000;
+
+    // connect 建立 WebSocket 连接，断开后按指数退避重连
+    function connect() {
+        const protocol = location.protocol === 'https:' ? 'wss:' : 'ws:';
+        const query = projectID ? '?project=' + encodeURIComponent(projectID) : '';
+        const socket = new WebSocket(protocol + '//' + location.host + '/ws' + query);
+
+        socket.onopen = () => {
+            // 重连期间可能错过事件，连接后同步一次状态
+            if (retryDelay > 1000) {
+                refreshStatus();
+            }
+            retryDelay = 1000;
+        };
+        socket.onmessage = message => {
+            const event = JSON.parse(message.data);
+            if (event.type === 'subscribed' || event.type === 'pong' || event.type === 'error') {
+                return;
+            }
+            handleEvent(event);
+            document.dispatchEvent(new CustomEvent('envswitch:event', { detail: event }));
+        };
+        socket.onclose = () => {
+            setTimeout(connect, retryDelay);
+            retryDelay = Math.min(retryDelay * 2, 30000);
+        };
+    }
+
+    // handleEvent 根据事件类型更新页面
+    function handleEvent(event) {
+        const target = [event.project_name, event.environment_name].filter(Boolean).join('/');
+
+        if (statusEvents.includes(event.type)) {
+            refreshStatus();
+        }
+
+        switch (event.type) {
+        case 'switch.started':
+            showToast('正在切换到 ' + target);
+            break;
+        case 'switch.completed':
+            showToast('已切换到 ' + target);
+            break;
+        case 'switch.failed':
+            showToast('切换到 ' + target + ' 失败: ' + event.error, 'error');
+            break;
+        case 'rollback':
+            showToast('已从备份回滚');
+            break;
+        default:
+            if (isContentEvent(event)) {
+                showToast(describeChange(event), 'info', true);
+            }
+        }
+    }
+
+    // isContentEvent 项目、环境或文件配置的变更是否影响当前页面
+    function isContentEvent(event) {
+        if (!/^(project|environment|file)\./.test(event.type)) {
+            return false;
+        }
+        if (projectID) {
+            return event.project_id === projectID;
+        }
+        // 项目列表页只关心项目和环境数量的变化
+        return location.pathname === '/projects' && !event.type.startsWith('file.');
+    }
+
+    // describeChange 变更事件的说明文字
+    function describeChange(event) {
+        const actions = { created: '已创建', updated: '已更新', deleted: '已删除', added: '已添加', removed: '已移除' };
+        const [kind, action] = event.type.split('.');
+        const names = { project: '项目 ' + event.project_name, environment: '环境 ' + event.environment_name, file: '环境 ' + event.environment_name + ' 的文件配置' };
+        return names[kind] + ' ' + (actions[action] || action);
+    }
+
+    // refreshStatus 重新获取当前状态，更新导航栏和环境的激活标记
+    function refreshStatus() {
+        fetch('/api/status')
+            .then(response => response.json())
+            .then(state => {
+                const active = Boolean(state.current_project && state.current_environment);
+                const nav = document.getElementById('current-env');
+                if (nav) {
+                    nav.style.display = active ? '' : 'none';
+                    nav.querySelector('.current-project').textContent = state.project_name || state.current_project || '';
+                    nav.querySelector('.current-env-name').textContent = state.environment_name || state.current_environment || '';
+                }
+
+                document.querySelectorAll('[data-env-id]').forEach(element => {
+                    const badge = element.querySelector('.status-active, .status-inactive');
+                    if (!badge) {
+                        return;
+                    }
+                    const isActive = element.dataset.envId === state.current_environment;
+                    badge.className = isActive ? 'status-active' : 'status-inactive';
+                    badge.textContent = isActive ? (element.dataset.activeText || '激活') : '未激活';
+                });
+
+                document.dispatchEvent(new CustomEvent('envswitch:status', { detail: state }));
+            })
+            .catch(() => {});
+    }
+
+    // showToast 在页面右下角显示通知，reload 为 true 时附带刷新链接并保持显示
+    function showToast(text, kind, reload) {
+        let container = document.getElementById('live-toasts');
+        if (!container) {
+            container = document.createElement('div');
+            container.id = 'live-toasts';
+            container.className = 'live-toasts';
+            document.body.appendChild(container);
+        }
+
+        const toast = document.createElement('div');
+        toast.className = 'live-toast live-toast-' + (kind || 'info');
+        toast.textContent = text;
+        if (reload) {
+            const link = document.createElement('a');
+            link.href = '#';
+            link.textContent = '刷新';
+            link.onclick = e => {
+                e.preventDefault();
+                location.reload();
+            };
+            toast.appendChild(link);
+        }
+        container.appendChild(toast);
+
+        if (!reload) {
+            setTimeout(() => toast.remove(), 5000);
+        }
+    }
+
+    if ('WebSocket' in window) {
+        connect();
+    }
+})();
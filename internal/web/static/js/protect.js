@@ -0,0 +1,64 @@
+// 受保护环境的确认
+// 切换、删除受保护环境（或删除包含受保护环境的项目）、修改、移除其文件配置时，服务端返回 428 并签发确认令牌；
+// 弹出确认框要求输入环境名称，确认后携带令牌重新发送请求
+
+// protectedFetch 发送请求并解析 JSON，遇到受保护环境时先完成确认再重试
+// 涉及多个受保护环境（如删除项目）时依次确认，重试时携带已获得的全部确认令牌
+function protectedFetch(url, options, tokens) {
+    tokens = tokens || [];
+    const headers = Object.assign({}, options.headers);
+    if (tokens.length > 0) {
+        headers['X-Confirm-Token'] = tokens.join(',');
+    }
+    return fetch(url, Object.assign({}, options, { headers: headers })).then(response => {
+        if (response.status !== 428) {
+            return response.json();
+        }
+        return response.json().then(data => confirmProtected(data).then(confirmed => {
+            if (!confirmed) {
+                return { error: '操作已取消' };
+            }
+            return protectedFetch(url, options, tokens.concat(data.confirm_token));
+        }));
+    });
+}
+
+// confirmProtected 显示确认框，输入的名称与环境名称一致并确认时返回 true
+function confirmProtected(data) {
+    return new Promise(resolve => {
+        const modal = document.getElementById('confirm-modal');
+        const input = document.getElementById('confirm-input');
+        const submit = document.getElementById('confirm-submit');
+        const cancel = document.getElementById('confirm-cancel');
+
+        document.getElementById('confirm-reason').textContent = data.error;
+        document.getElementById('confirm-env-name').textContent = data.environment;
+        input.value = '';
+        submit.disabled = true;
+        modal.style.display = 'flex';
+        input.focus();
+
+        function close(confirmed) {
+            modal.style.display = 'none';
+            input.oninput = null;
+            input.onkeydown = null;
+            submit.onclick = null;
+            cancel.onclick = null;
+            resolve(confirmed);
+        }
+
+        input.oninput = () => {
+            submit.disabled = input.value !== data.environment;
+        };
+        input.onkeydown = e => {
+            if (e.key === 'Enter' && !submit.disabled) {
+                e.preventDefault();
+                close(true);
+            } else if (e.key === 'Escape') {
+                close(false);
+            }
+        };
+        submit.onclick = () => close(true);
+        cancel.onclick = () => close(false);
+    });
+}
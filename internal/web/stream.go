@@ -0,0 +1,131 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/zoyopei/envswitch/api"
+	"github.com/zoyopei/envswitch/internal/events"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// sseKeepAliveInterval SSE 保活注释的发送间隔，防止代理断开空闲连接
+	sseKeepAliveInterval = 15 * time.Second
+	// sseRetry 建议客户端断线后的重连间隔（毫秒）
+	sseRetry = 3000
+	// defaultWaitTimeout 长轮询的默认等待时间
+	defaultWaitTimeout = 30 * time.Second
+	// maxWaitTimeout 长轮询的最长等待时间
+	maxWaitTimeout = 2 * time.Minute
+)
+
+// eventsStreamAPI 以 Server-Sent Events 推送实时事件，事件内容与 WebSocket 相同
+// 可通过 ?project= 按项目过滤；Last-Event-ID 请求头（或 last_event_id 参数）用于断线续传，
+// 只能补发仍在缓冲区中的事件
+func (s *Server) eventsStreamAPI(c *gin.Context) {
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	var lastID uint64
+	if lastEventID != "" {
+		id, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid Last-Event-ID '%s'", lastEventID))
+			return
+		}
+		lastID = id
+	}
+
+	sub := newSubscription(currentToken(c), c.QueryArray("project"))
+	backlog := s.hub.register(sub, lastID)
+	defer s.hub.unregister(sub)
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	_, _ = fmt.Fprintf(c.Writer, "retry: %d\n\n", sseRetry)
+	for i := range backlog {
+		if err := writeSSEEvent(c.Writer, &backlog[i]); err != nil {
+			return
+		}
+	}
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			if err := writeSSEEvent(c.Writer, &event); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		case <-c.Request.Context().Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
+
+// writeSSEEvent 写入一条 SSE 事件，id 为事件序号，event 为事件类型，data 为事件 JSON
+func writeSSEEvent(w gin.ResponseWriter, event *events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// statusAPI 获取当前状态和状态版本号
+// ?wait=<rev> 时进行长轮询：版本号等于 rev 时等待状态变化，超时（?timeout=秒，默认 30）后返回未变化的状态
+func (s *Server) statusAPI(c *gin.Context) {
+	var rev uint64
+	if wait := c.Query("wait"); wait != "" {
+		waitRev, err := strconv.ParseUint(wait, 10, 64)
+		if err != nil {
+			respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid wait revision '%s'", wait))
+			return
+		}
+
+		timeout := defaultWaitTimeout
+		if value := c.Query("timeout"); value != "" {
+			seconds, err := strconv.Atoi(value)
+			if err != nil || seconds <= 0 {
+				respondError(c, http.StatusBadRequest, fmt.Sprintf("Invalid timeout '%s'", value))
+				return
+			}
+			timeout = min(time.Duration(seconds)*time.Second, maxWaitTimeout)
+		}
+
+		rev = s.hub.waitRev(waitRev, timeout, c.Request.Context().Done())
+	} else {
+		rev = s.hub.currentRev()
+	}
+
+	state, err := s.fileManager.GetCurrentState()
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, api.Status{AppState: *state, Rev: rev})
+}
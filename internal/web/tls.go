@@ -0,0 +1,116 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// 自签名证书的有效期，到期前 selfSignedRenewBefore 重新生成
+const (
+	selfSignedValidity    = 365 * 24 * time.Hour
+	selfSignedRenewBefore = 30 * 24 * time.Hour
+)
+
+// EnsureSelfSignedCert 返回 dir 下的自签名证书和私钥文件，不存在、即将过期或不包含 hosts 时重新生成
+// 证书保存在数据目录中，重启后继续使用，浏览器只需信任一次
+func EnsureSelfSignedCert(dir string, hosts []string) (string, string, error) {
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+
+	if selfSignedCertValid(certFile, keyFile, hosts) {
+		return certFile, keyFile, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate private key: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate serial number: %w", err)
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"envswitch"}, CommonName: "envswitch self-signed"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if host != "" {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create certificate: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to encode private key: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", "", fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return "", "", fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return "", "", fmt.Errorf("failed to write certificate: %w", err)
+	}
+	return certFile, keyFile, nil
+}
+
+// selfSignedCertValid 判断已有的证书能否继续使用
+func selfSignedCertValid(certFile, keyFile string, hosts []string) bool {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return false
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil || time.Now().Add(selfSignedRenewBefore).After(cert.NotAfter) {
+		return false
+	}
+	for _, host := range hosts {
+		if host != "" && cert.VerifyHostname(host) != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// SelfSignedHosts 自签名证书包含的主机名：本机地址、主机名和监听地址
+func SelfSignedHosts(bind string) []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" {
+		hosts = append(hosts, hostname)
+	}
+	if bind == "" || bind == "0.0.0.0" || bind == "::" {
+		return hosts
+	}
+	for _, host := range hosts {
+		if host == bind {
+			return hosts
+		}
+	}
+	return append(hosts, bind)
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureSelfSignedCert(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+	hosts := SelfSignedHosts("192.168.1.10")
+
+	certFile, keyFile, err := EnsureSelfSignedCert(dir, hosts)
+	if err != nil {
+		t.Fatalf("EnsureSelfSignedCert() error = %v", err)
+	}
+
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Failed to load generated certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, host := range []string{"localhost", "127.0.0.1", "192.168.1.10"} {
+		if err := cert.VerifyHostname(host); err != nil {
+			t.Errorf("Expected certificate to be valid for %s: %v", host, err)
+		}
+	}
+	if info, err := os.Stat(keyFile); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected private key with mode 0600, got %v, %v", info, err)
+	}
+
+	// 已有的证书继续使用
+	data, _ := os.ReadFile(certFile)
+	if _, _, err := EnsureSelfSignedCert(dir, hosts); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(certFile); string(again) != string(data) {
+		t.Error("Expected existing certificate to be reused")
+	}
+
+	// 监听地址变化时重新生成
+	if _, _, err := EnsureSelfSignedCert(dir, SelfSignedHosts("10.0.0.5")); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := os.ReadFile(certFile); string(again) == string(data) {
+		t.Error("Expected certificate to be regenerated for a new host")
+	}
+}
@@ -17,7 +17,6 @@ func main() {
 
 	// 执行命令
 	if err := cmd.Execute(); err != nil {
-		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(cmd.ExitCode(err))
 	}
 }
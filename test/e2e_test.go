@@ -1,14 +1,22 @@
 package test
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/gorilla/websocket"
 )
 
 func TestCLIEndToEnd(t *testing.T) {
@@ -179,6 +187,156 @@ func TestCLIEndToEnd(t *testing.T) {
 		}
 	})
 
+	// 测试动态补全
+	t.Run("Completion", func(t *testing.T) {
+		cmd := exec.Command(binary, "__complete", "switch", "test-project", "")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to complete environments: %v\nOutput: %s", err, string(output))
+		}
+		if !strings.Contains(string(output), "dev") {
+			t.Errorf("Expected environment 'dev' in completions, got: %s", string(output))
+		}
+
+		cmd = exec.Command(binary, "__complete", "env", "show", "")
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to complete projects: %v\nOutput: %s", err, string(output))
+		}
+		if !strings.Contains(string(output), "test-project") {
+			t.Errorf("Expected project 'test-project' in completions, got: %s", string(output))
+		}
+
+		cmd = exec.Command(binary, "completion", "bash")
+		output, err = cmd.CombinedOutput()
+		if err != nil || !strings.Contains(string(output), "bash completion") {
+			t.Errorf("Failed to generate bash completion: %v\nOutput: %s", err, string(output))
+		}
+	})
+
+	// 测试提示符片段和 shell 集成脚本
+	t.Run("ShellPrompt", func(t *testing.T) {
+		cmd := exec.Command(binary, "prompt", "--format", "[{project}/{env}]")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to run prompt: %v\nOutput: %s", err, string(output))
+		}
+		if string(output) != "[test-project/dev]" {
+			t.Errorf("Expected prompt segment [test-project/dev], got: %q", string(output))
+		}
+
+		cmd = exec.Command(binary, "init-shell", "bash", "--auto-switch")
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to run init-shell: %v\nOutput: %s", err, string(output))
+		}
+		if !strings.Contains(string(output), "__envswitch_ps1") || !strings.Contains(string(output), "auto-switch") {
+			t.Errorf("Expected prompt function and cd hook in script, got: %s", string(output))
+		}
+	})
+
+	// 测试结构化输出和退出码
+	t.Run("StructuredOutput", func(t *testing.T) {
+		cmd := exec.Command(binary, "project", "list", "-o", "json")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("Failed to list projects as JSON: %v\nOutput: %s", err, string(output))
+		}
+		var projects []struct {
+			Name         string `json:"name"`
+			Environments []struct {
+				Name string `json:"name"`
+			} `json:"environments"`
+		}
+		if err := json.Unmarshal(output, &projects); err != nil {
+			t.Fatalf("Invalid JSON output: %v\nOutput: %s", err, string(output))
+		}
+		if len(projects) != 1 || projects[0].Name != "test-project" || len(projects[0].Environments) != 1 {
+			t.Errorf("Unexpected project list: %s", string(output))
+		}
+
+		cmd = exec.Command(binary, "env", "show", "test-project", "dev", "-o", "yaml")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("Failed to show environment as YAML: %v\nOutput: %s", err, string(output))
+		}
+		if !strings.Contains(string(output), "name: dev") || !strings.Contains(string(output), "target_path:") {
+			t.Errorf("Expected YAML environment details, got: %s", string(output))
+		}
+
+		cmd = exec.Command(binary, "status", "-o", "json")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("Failed to get status as JSON: %v\nOutput: %s", err, string(output))
+		}
+		var status struct {
+			Active      bool   `json:"active"`
+			Environment string `json:"environment"`
+			BackupID    string `json:"backup_id"`
+		}
+		if err := json.Unmarshal(output, &status); err != nil {
+			t.Fatalf("Invalid JSON status: %v\nOutput: %s", err, string(output))
+		}
+		if !status.Active || status.Environment != "dev" || status.BackupID == "" {
+			t.Errorf("Unexpected status: %s", string(output))
+		}
+
+		cmd = exec.Command(binary, "switch", "test-project", "dev", "--dry-run", "-o", "json")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("Failed to dry-run switch: %v\nOutput: %s", err, string(output))
+		}
+		var result struct {
+			Environment string `json:"environment"`
+			DryRun      bool   `json:"dry_run"`
+			Files       []struct {
+				TargetPath string `json:"target_path"`
+			} `json:"files"`
+		}
+		if err := json.Unmarshal(output, &result); err != nil {
+			t.Fatalf("Invalid JSON switch result: %v\nOutput: %s", err, string(output))
+		}
+		if result.Environment != "dev" || !result.DryRun || len(result.Files) != 1 {
+			t.Errorf("Unexpected switch result: %s", string(output))
+		}
+
+		// 不同类别的错误返回不同的退出码
+		exitCodes := []struct {
+			args []string
+			code int
+		}{
+			{[]string{"env", "show", "missing-project", "dev", "-o", "json"}, 3},
+			{[]string{"project", "create", "test-project"}, 4},
+			{[]string{"project", "list", "-o", "xml"}, 2},
+			{[]string{"project", "list", "-o", "projects.json"}, 2},
+		}
+		for _, tc := range exitCodes {
+			cmd = exec.Command(binary, tc.args...)
+			output, err = cmd.CombinedOutput()
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok || exitErr.ExitCode() != tc.code {
+				t.Errorf("Expected exit code %d for %v, got %v\nOutput: %s", tc.code, tc.args, err, string(output))
+			}
+		}
+
+		// 旧版本的 -o <文件> 仍然可用于 export 和 dump
+		bundle := filepath.Join(tempDir, "legacy.tar.gz")
+		manifestFile := filepath.Join(tempDir, "legacy.yaml")
+		for _, args := range [][]string{
+			{"project", "export", "test-project", "-o", bundle},
+			{"project", "dump", "test-project", "-o", manifestFile},
+		} {
+			if output, err := exec.Command(binary, args...).CombinedOutput(); err != nil {
+				t.Fatalf("Failed to run %v: %v\nOutput: %s", args, err, string(output))
+			}
+		}
+		for _, path := range []string{bundle, manifestFile} {
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("Expected %s to be written: %v", path, err)
+			}
+		}
+	})
+
 	// 9. 测试回滚
 	t.Run("Rollback", func(t *testing.T) {
 		cmd := exec.Command(binary, "rollback", "--force")
@@ -192,7 +350,180 @@ func TestCLIEndToEnd(t *testing.T) {
 		}
 	})
 
+	// 测试受保护环境
+	t.Run("ProtectedEnvironment", func(t *testing.T) {
+		sourceFile := filepath.Join(tempDir, "configs", "prod.json")
+		if err := os.WriteFile(sourceFile, []byte(`{"env": "production"}`), 0644); err != nil {
+			t.Fatalf("Failed to create source file: %v", err)
+		}
+
+		steps := [][]string{
+			{"env", "create", "test-project", "prod", "--protected"},
+			{"env", "add-file", "test-project", "prod", sourceFile, filepath.Join(tempDir, "app", "config.json")},
+		}
+		for _, args := range steps {
+			cmd := exec.Command(binary, args...)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("Failed to run %v: %v\nOutput: %s", args, err, string(output))
+			}
+		}
+
+		// 非交互环境下未提供 --confirm 时拒绝操作，--force 不能跳过确认
+		rejected := []struct {
+			args []string
+			code int
+		}{
+			{[]string{"switch", "test-project", "prod"}, 6},
+			{[]string{"switch", "test-project", "prod", "--confirm", "dev"}, 2},
+			{[]string{"env", "delete", "test-project", "prod", "--force"}, 6},
+			{[]string{"env", "update", "test-project", "prod", "--protected=false"}, 6},
+		}
+		for _, tc := range rejected {
+			cmd := exec.Command(binary, tc.args...)
+			output, err := cmd.CombinedOutput()
+			exitErr, ok := err.(*exec.ExitError)
+			if !ok || exitErr.ExitCode() != tc.code {
+				t.Errorf("Expected exit code %d for %v, got %v\nOutput: %s", tc.code, tc.args, err, string(output))
+			}
+		}
+
+		cmd := exec.Command(binary, "switch", "test-project", "prod", "--confirm", "prod")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to switch with confirmation: %v\nOutput: %s", err, string(output))
+		}
+		if !strings.Contains(string(output), "Successfully switched") {
+			t.Errorf("Expected success message, got: %s", string(output))
+		}
+
+		cmd = exec.Command(binary, "env", "delete", "test-project", "prod", "--confirm=prod")
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to delete protected environment with confirmation: %v\nOutput: %s", err, string(output))
+		}
+
+		// 删除项目需要确认其中的每个受保护环境
+		for _, args := range [][]string{
+			{"project", "create", "guarded"},
+			{"env", "create", "guarded", "prod", "--protected"},
+			{"env", "create", "guarded", "staging", "--protected"},
+		} {
+			if output, err := exec.Command(binary, args...).CombinedOutput(); err != nil {
+				t.Fatalf("Failed to run %v: %v\nOutput: %s", args, err, string(output))
+			}
+		}
+		for _, args := range [][]string{
+			{"project", "delete", "guarded", "--force"},
+			{"project", "delete", "guarded", "--force", "--confirm", "prod"},
+		} {
+			cmd := exec.Command(binary, args...)
+			output, err := cmd.CombinedOutput()
+			if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 6 {
+				t.Errorf("Expected exit code 6 for %v, got %v\nOutput: %s", args, err, string(output))
+			}
+		}
+		// 取消保护需要确认，之后删除项目只需确认仍受保护的环境
+		output, err = exec.Command(binary, "env", "update", "guarded", "staging", "--protected=false", "--confirm", "staging").CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to remove protection with confirmation: %v\nOutput: %s", err, string(output))
+		}
+		output, err = exec.Command(binary, "project", "delete", "guarded", "--force", "--confirm", "prod").CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to delete project with confirmation: %v\nOutput: %s", err, string(output))
+		}
+
+		// apply 取消受保护环境的保护同样需要确认
+		manifestFile := filepath.Join(tempDir, "guarded.yaml")
+		writeManifest := func(content string) {
+			if err := os.WriteFile(manifestFile, []byte("project: guarded-apply\nenvironments:\n"+content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		writeManifest("  - name: prod\n    protected: true\n")
+		if output, err := exec.Command(binary, "apply", "-f", manifestFile).CombinedOutput(); err != nil {
+			t.Fatalf("Failed to apply manifest: %v\nOutput: %s", err, string(output))
+		}
+		writeManifest("  - name: prod\n    protected: false\n")
+		output, err = exec.Command(binary, "apply", "-f", manifestFile).CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 6 {
+			t.Errorf("Expected exit code 6 when apply removes protection, got %v\nOutput: %s", err, string(output))
+		}
+		output, err = exec.Command(binary, "apply", "-f", manifestFile, "--confirm", "prod").CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to apply manifest with confirmation: %v\nOutput: %s", err, string(output))
+		}
+
+		// prune 删除受保护环境中的文件映射需要确认
+		writeManifest("  - name: live\n    protected: true\n    files:\n      - source: " + sourceFile + "\n        target: app.json\n")
+		if output, err := exec.Command(binary, "apply", "-f", manifestFile).CombinedOutput(); err != nil {
+			t.Fatalf("Failed to apply manifest: %v\nOutput: %s", err, string(output))
+		}
+		writeManifest("  - name: live\n    protected: true\n")
+		output, err = exec.Command(binary, "apply", "-f", manifestFile, "--prune").CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 6 {
+			t.Errorf("Expected exit code 6 when apply prunes a file from a protected environment, got %v\nOutput: %s", err, string(output))
+		}
+		output, err = exec.Command(binary, "apply", "-f", manifestFile, "--prune", "--confirm", "live").CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to prune with confirmation: %v\nOutput: %s", err, string(output))
+		}
+	})
+
 	// 10. 测试环境删除
+	t.Run("ServerDaemon", func(t *testing.T) {
+		output, err := exec.Command(binary, "server", "--daemon", "--port", "8082").CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to start server in the background: %v, output: %s", err, output)
+		}
+		defer func() { _ = exec.Command(binary, "server", "stop").Run() }()
+
+		resp, err := http.Get("http://localhost:8082/api/v1/status")
+		if err != nil {
+			t.Fatalf("Failed to connect to background server: %v", err)
+		}
+		_ = resp.Body.Close()
+
+		output, err = exec.Command(binary, "server", "status", "-o", "json").Output()
+		if err != nil {
+			t.Fatalf("server status failed: %v", err)
+		}
+		var status struct {
+			Running bool   `json:"running"`
+			PID     int    `json:"pid"`
+			LogFile string `json:"log_file"`
+		}
+		if err := json.Unmarshal(output, &status); err != nil || !status.Running || status.PID == 0 {
+			t.Fatalf("Unexpected server status %s: %v", output, err)
+		}
+		if _, err := os.Stat(status.LogFile); err != nil {
+			t.Errorf("Expected log file %s: %v", status.LogFile, err)
+		}
+
+		// 已在运行时不能重复启动
+		cmd := exec.Command(binary, "server", "--daemon", "--port", "8082")
+		if err := cmd.Run(); cmd.ProcessState.ExitCode() != 4 {
+			t.Errorf("Expected exit code 4 for a second daemon, got %v", err)
+		}
+
+		// restart 未指定标志时沿用启动时的端口
+		if output, err := exec.Command(binary, "server", "restart").CombinedOutput(); err != nil {
+			t.Fatalf("server restart failed: %v, output: %s", err, output)
+		}
+		resp, err = http.Get("http://localhost:8082/api/v1/status")
+		if err != nil {
+			t.Fatalf("Expected restarted server to keep port 8082: %v", err)
+		}
+		_ = resp.Body.Close()
+
+		if output, err := exec.Command(binary, "server", "stop").CombinedOutput(); err != nil {
+			t.Fatalf("server stop failed: %v, output: %s", err, output)
+		}
+		cmd = exec.Command(binary, "server", "status")
+		if err := cmd.Run(); cmd.ProcessState.ExitCode() != 3 {
+			t.Errorf("Expected exit code 3 after stop, got %v", err)
+		}
+	})
+
 	t.Run("DeleteEnvironment", func(t *testing.T) {
 		cmd := exec.Command(binary, "env", "delete", "test-project", "dev", "--force")
 		output, err := cmd.CombinedOutput()
@@ -277,6 +608,340 @@ func TestWebServerEndToEnd(t *testing.T) {
 			t.Errorf("Expected status code 200, got %d", resp.StatusCode)
 		}
 	})
+
+	// 测试 WebSocket 实时事件和按项目订阅
+	t.Run("WebSocketEvents", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial("ws://localhost:8081/ws?project=e2e-ws-watched", nil)
+		if err != nil {
+			t.Fatalf("Failed to connect WebSocket: %v", err)
+		}
+		defer func() { _ = conn.Close() }()
+
+		readMessage := func() map[string]interface{} {
+			_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			var message map[string]interface{}
+			if err := conn.ReadJSON(&message); err != nil {
+				t.Fatalf("Failed to read WebSocket message: %v", err)
+			}
+			return message
+		}
+
+		if err := conn.WriteJSON(map[string]string{"action": "ping"}); err != nil {
+			t.Fatalf("Failed to send ping: %v", err)
+		}
+		if message := readMessage(); message["type"] != "pong" {
+			t.Fatalf("Expected pong, got %v", message)
+		}
+
+		createProject := func(name string) string {
+			resp, err := http.Post("http://localhost:8081/api/projects", "application/json", strings.NewReader(`{"name":"`+name+`"}`))
+			if err != nil {
+				t.Fatalf("Failed to create project: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+			var project struct {
+				ID string `json:"id"`
+			}
+			_ = json.NewDecoder(resp.Body).Decode(&project)
+			if resp.StatusCode != http.StatusCreated {
+				t.Fatalf("Expected 201 creating project, got %d", resp.StatusCode)
+			}
+			return project.ID
+		}
+		deleteProject := func(id string) {
+			req, _ := http.NewRequest("DELETE", "http://localhost:8081/api/projects/"+id, nil)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				_ = resp.Body.Close()
+			}
+		}
+
+		// 未订阅项目的事件不会收到，第一条消息应为订阅项目的创建事件
+		otherID := createProject("e2e-ws-other")
+		defer deleteProject(otherID)
+		watchedID := createProject("e2e-ws-watched")
+		defer deleteProject(watchedID)
+
+		message := readMessage()
+		if message["type"] != "project.created" || message["project_id"] != watchedID || message["project_name"] != "e2e-ws-watched" {
+			t.Errorf("Expected project.created for watched project, got %v", message)
+		}
+	})
+
+	// 测试 SSE 事件流、断线续传和状态长轮询
+	t.Run("EventStreamAndLongPoll", func(t *testing.T) {
+		resp, err := http.Get("http://localhost:8081/api/v1/events?project=e2e-sse")
+		if err != nil {
+			t.Fatalf("Failed to open event stream: %v", err)
+		}
+		defer func() { _ = resp.Body.Close() }()
+		if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+			t.Fatalf("Expected text/event-stream, got %s", ct)
+		}
+
+		createResp, err := http.Post("http://localhost:8081/api/projects", "application/json", strings.NewReader(`{"name":"e2e-sse"}`))
+		if err != nil {
+			t.Fatalf("Failed to create project: %v", err)
+		}
+		var project struct {
+			ID string `json:"id"`
+		}
+		_ = json.NewDecoder(createResp.Body).Decode(&project)
+		_ = createResp.Body.Close()
+		defer func() {
+			req, _ := http.NewRequest("DELETE", "http://localhost:8081/api/projects/"+project.ID, nil)
+			if resp, err := http.DefaultClient.Do(req); err == nil {
+				_ = resp.Body.Close()
+			}
+		}()
+
+		// 读取第一条事件（跳过 retry 和保活行）
+		lines := make(chan string)
+		go func() {
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+			close(lines)
+		}()
+		var eventID, eventType, data string
+		for data == "" {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatal("Event stream closed unexpectedly")
+				}
+				switch {
+				case strings.HasPrefix(line, "id: "):
+					eventID = strings.TrimPrefix(line, "id: ")
+				case strings.HasPrefix(line, "event: "):
+					eventType = strings.TrimPrefix(line, "event: ")
+				case strings.HasPrefix(line, "data: "):
+					data = strings.TrimPrefix(line, "data: ")
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for SSE event")
+			}
+		}
+		if eventType != "project.created" || !strings.Contains(data, `"project_name":"e2e-sse"`) || eventID == "" {
+			t.Fatalf("Unexpected SSE event: id=%s event=%s data=%s", eventID, eventType, data)
+		}
+
+		// 从上一个事件之前续传，应补发同一事件
+		previous, _ := strconv.ParseUint(eventID, 10, 64)
+		req, _ := http.NewRequest("GET", "http://localhost:8081/api/v1/events?project=e2e-sse", nil)
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(previous-1, 10))
+		client := &http.Client{Timeout: time.Second}
+		resumed, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to resume event stream: %v", err)
+		}
+		buf := make([]byte, 4096)
+		n, _ := io.ReadAtLeast(resumed.Body, buf, len("retry: 3000\n\nid: "+eventID))
+		_ = resumed.Body.Close()
+		if !strings.Contains(string(buf[:n]), "id: "+eventID+"\n") {
+			t.Errorf("Expected resumed stream to replay event %s, got %q", eventID, buf[:n])
+		}
+
+		// 长轮询：版本号未变化时等待到超时
+		var status struct {
+			Rev uint64 `json:"rev"`
+		}
+		statusResp, err := http.Get("http://localhost:8081/api/v1/status")
+		if err != nil {
+			t.Fatalf("Failed to get status: %v", err)
+		}
+		_ = json.NewDecoder(statusResp.Body).Decode(&status)
+		_ = statusResp.Body.Close()
+		if status.Rev == 0 {
+			t.Fatal("Expected status revision")
+		}
+
+		start := time.Now()
+		waitResp, err := http.Get(fmt.Sprintf("http://localhost:8081/api/v1/status?wait=%d&timeout=1", status.Rev))
+		if err != nil {
+			t.Fatalf("Failed to long poll status: %v", err)
+		}
+		_ = waitResp.Body.Close()
+		if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+			t.Errorf("Expected long poll to wait for timeout, returned after %v", elapsed)
+		}
+
+		start = time.Now()
+		staleResp, err := http.Get(fmt.Sprintf("http://localhost:8081/api/v1/status?wait=%d", status.Rev-1))
+		if err != nil {
+			t.Fatalf("Failed to long poll status: %v", err)
+		}
+		_ = staleResp.Body.Close()
+		if time.Since(start) > 500*time.Millisecond {
+			t.Error("Expected long poll with stale revision to return immediately")
+		}
+	})
+
+	// 测试远程模式：命令通过 REST API 操作服务端数据，输出与本地模式一致
+	t.Run("RemoteMode", func(t *testing.T) {
+		remoteHome := t.TempDir()
+		remote := func(args ...string) ([]byte, error) {
+			cmd := exec.Command(binary, args...)
+			cmd.Env = append(os.Environ(), "HOME="+remoteHome, "USERPROFILE="+remoteHome, "ENVSWITCH_REMOTE=http://localhost:8081")
+			return cmd.CombinedOutput()
+		}
+
+		source := filepath.Join(tempDir, "remote-dev.json")
+		target := filepath.Join(tempDir, "remote-target.json")
+		if err := os.WriteFile(source, []byte(`{"env":"remote-dev"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(target, []byte(`{"env":"original"}`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, args := range [][]string{
+			{"project", "create", "e2e-remote", "--description", "Remote project"},
+			{"env", "create", "e2e-remote", "dev"},
+			{"env", "add-file", "e2e-remote", "dev", source, target},
+			{"switch", "e2e-remote", "dev"},
+		} {
+			if output, err := remote(args...); err != nil {
+				t.Fatalf("Remote %v failed: %v\nOutput: %s", args, err, output)
+			}
+		}
+		defer func() { _, _ = remote("project", "delete", "e2e-remote", "--force") }()
+
+		if data, _ := os.ReadFile(target); string(data) != `{"env":"remote-dev"}` {
+			t.Errorf("Expected target to be switched by the server, got %s", data)
+		}
+
+		for _, args := range [][]string{
+			{"env", "list", "e2e-remote"},
+			{"env", "show", "e2e-remote", "dev"},
+		} {
+			local, err := exec.Command(binary, args...).CombinedOutput()
+			if err != nil {
+				t.Fatalf("Local %v failed: %v\nOutput: %s", args, err, local)
+			}
+			output, err := remote(args...)
+			if err != nil {
+				t.Fatalf("Remote %v failed: %v\nOutput: %s", args, err, output)
+			}
+			if string(output) != string(local) {
+				t.Errorf("Remote %v output differs from local:\n%s\nlocal:\n%s", args, output, local)
+			}
+		}
+
+		// 状态来自服务端，当前目录检测到的项目和档案仍来自本地配置
+		output, err := remote("status", "-o", "json")
+		if err != nil {
+			t.Fatalf("Remote status failed: %v\nOutput: %s", err, output)
+		}
+		var status struct {
+			Project     string `json:"project"`
+			Environment string `json:"environment"`
+		}
+		if err := json.Unmarshal(output, &status); err != nil || status.Project != "e2e-remote" || status.Environment != "dev" {
+			t.Errorf("Unexpected remote status: %v\n%s", err, output)
+		}
+
+		output, err = remote("project", "show", "e2e-missing")
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+			t.Errorf("Expected exit code 3 for missing remote project, got %v\nOutput: %s", err, output)
+		}
+
+		if output, err := remote("rollback", "--force"); err != nil {
+			t.Fatalf("Remote rollback failed: %v\nOutput: %s", err, output)
+		}
+		if data, _ := os.ReadFile(target); string(data) != `{"env":"original"}` {
+			t.Errorf("Expected target to be restored by the server, got %s", data)
+		}
+	})
+
+	// 测试令牌认证和角色权限
+	t.Run("Authentication", func(t *testing.T) {
+		output, err := exec.Command(binary, "token", "create", "e2e-viewer", "--role", "viewer", "-o", "json").Output()
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		defer func() { _ = exec.Command(binary, "token", "revoke", "e2e-viewer").Run() }()
+
+		var created struct {
+			Token string `json:"token"`
+			Role  string `json:"role"`
+		}
+		if err := json.Unmarshal(output, &created); err != nil {
+			t.Fatalf("Failed to parse token output: %v\n%s", err, output)
+		}
+		if !strings.HasPrefix(created.Token, "esw_") || created.Role != "viewer" {
+			t.Fatalf("Unexpected token output: %s", output)
+		}
+
+		output, err = exec.Command(binary, "token", "create", "e2e-admin", "--role", "admin", "-o", "json").Output()
+		if err != nil {
+			t.Fatalf("Failed to create token: %v", err)
+		}
+		defer func() { _ = exec.Command(binary, "token", "revoke", "e2e-admin").Run() }()
+
+		var admin struct {
+			Token string `json:"token"`
+		}
+		if err := json.Unmarshal(output, &admin); err != nil {
+			t.Fatalf("Failed to parse token output: %v\n%s", err, output)
+		}
+
+		request := func(method, path, token string) int {
+			req, _ := http.NewRequest(method, "http://localhost:8081"+path, strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			resp, err := http.DefaultTransport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("Request %s %s failed: %v", method, path, err)
+			}
+			_ = resp.Body.Close()
+			return resp.StatusCode
+		}
+
+		if code := request("GET", "/api/projects", ""); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 without token, got %d", code)
+		}
+		if code := request("GET", "/api/projects", "esw_invalid"); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 with invalid token, got %d", code)
+		}
+		if code := request("GET", "/api/projects", created.Token); code != http.StatusOK {
+			t.Errorf("Expected 200 with viewer token, got %d", code)
+		}
+		if code := request("POST", "/api/switch", created.Token); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for viewer switch, got %d", code)
+		}
+		if code := request("POST", "/api/projects", created.Token); code != http.StatusForbidden {
+			t.Errorf("Expected 403 for viewer project create, got %d", code)
+		}
+		if code := request("POST", "/api/projects", admin.Token); code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for admin project create with empty body, got %d", code)
+		}
+		if code := request("GET", "/", ""); code != http.StatusFound {
+			t.Errorf("Expected redirect to login page, got %d", code)
+		}
+
+		// 跨站 WebSocket 连接必须被拒绝
+		header := http.Header{}
+		header.Set("Authorization", "Bearer "+created.Token)
+		header.Set("Origin", "http://evil.example.com")
+		conn, resp, err := websocket.DefaultDialer.Dial("ws://localhost:8081/ws", header)
+		if err == nil {
+			_ = conn.Close()
+			t.Error("Expected cross-origin WebSocket connection to be rejected")
+		} else if resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected 403 for cross-origin WebSocket, got %v", err)
+		}
+
+		// 吊销后立即失效（仍有其他令牌时认证保持开启）
+		if err := exec.Command(binary, "token", "revoke", "e2e-viewer").Run(); err != nil {
+			t.Fatalf("Failed to revoke token: %v", err)
+		}
+		if code := request("GET", "/api/projects", created.Token); code != http.StatusUnauthorized {
+			t.Errorf("Expected 401 after revoke, got %d", code)
+		}
+	})
 }
 
 func TestFileOperations(t *testing.T) {
@@ -424,5 +1089,52 @@ func TestFileOperations(t *testing.T) {
 		if !strings.Contains(string(content), "development") {
 			t.Errorf("Expected development config after rollback, got: %s", string(content))
 		}
+
+		// 在私有覆盖目录中以生产环境运行命令，不影响实际文件
+		cmd = exec.Command(binary, "project", "update", "file-test", "--root", tempDir)
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to set project root: %v\nOutput: %s", err, string(output))
+		}
+
+		cmd = exec.Command(binary, "env", "update", "file-test", "prod", "--var", "APP_MODE=production")
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("Failed to set prod variables: %v\nOutput: %s", err, string(output))
+		}
+
+		cmd = exec.Command(binary, "exec", "file-test", "prod", "--overlay", "--",
+			"sh", "-c", `cat app/config.json; echo " mode=$APP_MODE"; exit 3`)
+		output, err = cmd.CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 3 {
+			t.Fatalf("Expected exit code 3 from exec, got %v\nOutput: %s", err, string(output))
+		}
+		if !strings.Contains(string(output), "production") || !strings.Contains(string(output), "mode=production") {
+			t.Errorf("Expected production overlay and variables, got: %s", string(output))
+		}
+
+		content, err = os.ReadFile(targetConfig)
+		if err != nil {
+			t.Fatalf("Failed to read target config after exec: %v", err)
+		}
+		if !strings.Contains(string(content), "development") {
+			t.Errorf("Expected exec to leave the development config in place, got: %s", string(content))
+		}
+
+		// 命令无法运行时以 127 退出，错误写入 stderr
+		var stdout, stderr bytes.Buffer
+		cmd = exec.Command(binary, "exec", "file-test", "dev", "-o", "json", "--", "envswitch-missing-command")
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err = cmd.Run()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 127 {
+			t.Fatalf("Expected exit code 127 for a missing command, got %v\nOutput: %s%s", err, stdout.String(), stderr.String())
+		}
+		var result struct {
+			Error    string `json:"error"`
+			ExitCode int    `json:"exit_code"`
+		}
+		if stdout.Len() != 0 || json.Unmarshal(stderr.Bytes(), &result) != nil || result.ExitCode != 127 {
+			t.Errorf("Expected JSON error with exit code 127 on stderr only, got stdout %q, stderr %q", stdout.String(), stderr.String())
+		}
 	})
 }